@@ -0,0 +1,569 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	texttemplate "text/template"
+	"time"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+	"scholacantorum.org/orders/stripe"
+)
+
+// ProductCancelled returns true if the product is a ticket to a cancelled
+// event:  either the event to which it is dedicated is cancelled, or all of
+// the events at which it is valid are.
+func ProductCancelled(product *model.Product) bool {
+	var live bool
+
+	for _, pe := range product.Events {
+		if pe.Priority == 0 && !pe.Event.Cancelled.IsZero() {
+			return true
+		}
+		if pe.Event.Cancelled.IsZero() {
+			live = true
+		}
+	}
+	return len(product.Events) != 0 && !live
+}
+
+// A cancelledLine is an order line with tickets dedicated to a cancelled
+// event.
+type cancelledLine struct {
+	line  *model.OrderLine
+	units int // number of units of the line's product that are cancelled
+}
+
+// cancelledLines returns the lines of the order that have unused tickets
+// dedicated to the specified event, with the number of units of each line's
+// product that those tickets make up.
+func cancelledLines(order *model.Order, eventID model.EventID) (lines []cancelledLine) {
+	for _, ol := range order.Lines {
+		var count int
+
+		if ol.Product.TicketCount == 0 {
+			continue
+		}
+		for _, t := range ol.Tickets {
+			if t.Used.IsZero() && t.Event != nil && t.Event.ID == eventID {
+				count++
+			}
+		}
+		if units := count / ol.Product.TicketCount; units != 0 {
+			lines = append(lines, cancelledLine{ol, units})
+		}
+	}
+	return lines
+}
+
+// CancelledTickets returns the number of unused tickets on the order that are
+// dedicated to the specified event, and their value in cents.
+func CancelledTickets(order *model.Order, eventID model.EventID) (tickets, amount int) {
+	for _, cl := range cancelledLines(order, eventID) {
+		tickets += cl.units * cl.line.Product.TicketCount
+		amount += cl.units * cl.line.Price
+	}
+	return tickets, amount
+}
+
+// ResolveCancellation carries out the chosen outcome for an order's tickets
+// to a cancelled event, and records it in the CancelledOrder.  username is
+// the office staff member making the choice, or empty if the customer is
+// making it.  For a refund, the value of the tickets is refunded to the card
+// the order was paid with; if it wasn't paid by card, the refund is recorded
+// but must be paid by the office (and customers can't choose it themselves).
+// For a donation, the tickets are removed and their value is transferred to a
+// new donation order, dated now, which is returned.  For an exchange, the
+// tickets are replaced with equivalent tickets to the rescheduled event.
+//
+// If the outcome can't be carried out, ResolveCancellation returns the reason,
+// and the transaction is left open for the caller to roll back.  Otherwise,
+// the order and CancelledOrder are saved and the transaction is committed
+// before any card refund is issued through Stripe, so that the database isn't
+// locked while we're talking to Stripe and a concurrent request to resolve
+// the same order sees that it has been resolved already.  The refund is then
+// recorded, and the confirmation notice to the customer is queued, in a new
+// transaction, which is committed before returning.  If the refund fails,
+// ResolveCancellation returns the Stripe error; the failure is recorded in the
+// CancelledOrder (see RetryCancellationRefund), and no notice is sent.
+func ResolveCancellation(
+	tx db.Tx, ec *model.EventCancellation, co *model.CancelledOrder, order *model.Order,
+	outcome model.CancellationOutcome, username string,
+) (donationOrder *model.Order, reason string, refundErr error) {
+	var (
+		lines    []cancelledLine
+		amount   int
+		now      = time.Now()
+		paid     *model.Payment
+		refund   *model.Payment
+		donation *model.Product
+		resched  *model.Event
+		newprods []*model.Product
+		tickets  int
+	)
+	if co.Outcome != model.CancelPending {
+		return nil, "outcome already chosen", nil
+	}
+	if lines = cancelledLines(order, ec.Event); len(lines) == 0 {
+		return nil, "order has no tickets to the cancelled event", nil
+	}
+	for _, cl := range lines {
+		amount += cl.units * cl.line.Price
+		tickets += cl.units * cl.line.Product.TicketCount
+	}
+	// Make sure the outcome can be carried out before changing anything.
+	switch outcome {
+	case model.CancelRefund:
+		if amount == 0 {
+			break
+		}
+		for _, p := range order.Payments {
+			if p.Amount >= amount {
+				paid = p
+				break
+			}
+		}
+		if paid == nil {
+			return nil, "no payment to refund", nil
+		}
+		if username == "" && (paid.Stripe == "" || (paid.Type != model.PaymentCard && paid.Type != model.PaymentCardPresent)) {
+			return nil, "please contact the Schola Cantorum office for a refund", nil
+		}
+	case model.CancelDonation:
+		if ec.Donation == "" {
+			return nil, "donation is not offered for this event", nil
+		}
+		if amount == 0 {
+			return nil, "tickets have no value to donate", nil
+		}
+		if donation = tx.FetchProduct(ec.Donation); donation == nil {
+			return nil, "donation product does not exist", nil
+		}
+	case model.CancelExchange:
+		if ec.Rescheduled == "" {
+			return nil, "exchange is not offered for this event", nil
+		}
+		if resched = tx.FetchEvent(ec.Rescheduled); resched == nil || !resched.Cancelled.IsZero() {
+			return nil, "rescheduled event is not available", nil
+		}
+		for _, cl := range lines {
+			var np = exchangeProduct(tx, resched, cl.line.Product)
+
+			if np == nil {
+				return nil, fmt.Sprintf("no ticket to the rescheduled event matches %s", cl.line.Product.ID), nil
+			}
+			newprods = append(newprods, np)
+		}
+		if resched.Capacity != 0 && tx.FetchTicketCount(resched)+tickets > resched.Capacity {
+			return nil, "not enough seats left in the rescheduled event", nil
+		}
+	default:
+		return nil, "invalid outcome", nil
+	}
+	// Carry it out.
+	switch outcome {
+	case model.CancelRefund:
+		removeCancelledTickets(tx, order, ec.Event, lines)
+		if paid != nil {
+			// The Stripe refund ID is filled in once the refund has
+			// been issued, below.
+			refund = &model.Payment{
+				Type:    paid.Type,
+				Subtype: paid.Subtype,
+				Method:  paid.Method,
+				Created: now,
+				Amount:  -amount,
+			}
+			order.Payments = append(order.Payments, refund)
+		}
+	case model.CancelDonation:
+		removeCancelledTickets(tx, order, ec.Event, lines)
+		donationOrder = cancellationDonationOrder(tx, order, ec.Event, donation, amount, now, username)
+	case model.CancelExchange:
+		for i, cl := range lines {
+			exchangeTickets(order, ec.Event, cl, newprods[i], resched)
+		}
+	}
+	tx.SaveOrder(order)
+	co.Outcome = outcome
+	co.Resolved = now
+	co.ResolvedBy = username
+	if refund != nil && paid.Stripe != "" {
+		co.RefundError = model.RefundInProgress
+	}
+	tx.SaveCancelledOrder(co)
+	Commit(tx)
+	// Issue the refund, if it's to a card, now that the order is marked as
+	// resolved.
+	if co.RefundError != "" {
+		return donationOrder, "", issueCancellationRefund(ec, co, order, paid, refund)
+	}
+	tx = db.Begin()
+	EmitCancellationNotice(tx, order, tx.FetchEvent(ec.Event), ec, co)
+	Commit(tx)
+	return donationOrder, "", nil
+}
+
+// RetryCancellationRefund retries a card refund for an order's tickets to a
+// cancelled event that failed when the outcome was chosen.  If there is no
+// failed refund to retry, it returns the reason, and the transaction is left
+// open for the caller to roll back.  Otherwise, it commits the transaction
+// and retries the refund as ResolveCancellation does, returning the Stripe
+// error if it fails again.
+func RetryCancellationRefund(
+	tx db.Tx, ec *model.EventCancellation, co *model.CancelledOrder, order *model.Order,
+) (reason string, refundErr error) {
+	var paid, refund *model.Payment
+
+	if co.Outcome != model.CancelRefund || co.RefundError == "" {
+		return "no failed refund to retry", nil
+	}
+	if co.RefundError == model.RefundInProgress {
+		return "refund is already in progress", nil
+	}
+	// Find the payment that was refunded, as ResolveCancellation did, and
+	// the refund recorded against it.
+	for _, p := range order.Payments {
+		if paid == nil && p.Amount >= co.Amount {
+			paid = p
+		}
+		if p.Amount == -co.Amount && p.Stripe == "" && (p.Type == model.PaymentCard || p.Type == model.PaymentCardPresent) {
+			refund = p
+		}
+	}
+	if paid == nil || paid.Stripe == "" || refund == nil {
+		return "refund payment not found", nil
+	}
+	co.RefundError = model.RefundInProgress
+	tx.SaveCancelledOrder(co)
+	Commit(tx)
+	return "", issueCancellationRefund(ec, co, order, paid, refund)
+}
+
+// issueCancellationRefund issues a card refund for an order's tickets to a
+// cancelled event through Stripe, and records the outcome in a new
+// transaction.  If the refund succeeds, its Stripe ID is saved in the refund
+// payment, and the confirmation notice to the customer is queued.  If it fails,
+// the error is saved in the CancelledOrder and returned.
+func issueCancellationRefund(
+	ec *model.EventCancellation, co *model.CancelledOrder, order *model.Order, paid, refund *model.Payment,
+) (err error) {
+	var tx db.Tx
+
+	refund.Stripe, err = stripe.RefundCharge(paid.Stripe, -refund.Amount)
+	tx = db.Begin()
+	if err != nil {
+		log.Printf("ERROR: can't refund charge %s for order %d: %s", paid.Stripe, order.ID, err)
+		co.RefundError = err.Error()
+		tx.SaveCancelledOrder(co)
+		Commit(tx)
+		return err
+	}
+	co.RefundError = ""
+	tx.SaveCancelledOrder(co)
+	tx.SaveOrder(order)
+	EmitCancellationNotice(tx, order, tx.FetchEvent(ec.Event), ec, co)
+	Commit(tx)
+	return nil
+}
+
+// cancellationDonationOrder creates and saves a donation order for the value
+// of an order's tickets to a cancelled event, and transfers that value to it
+// from the original order.  The donation order is dated at the time of the
+// donation, so that it is acknowledged in that year.
+func cancellationDonationOrder(
+	tx db.Tx, order *model.Order, eventID model.EventID, donation *model.Product, amount int, now time.Time, username string,
+) (donationOrder *model.Order) {
+	donationOrder = &model.Order{
+		Token:          NewToken(),
+		Valid:          true,
+		Source:         model.OrderFromPublic,
+		Name:           order.Name,
+		Email:          order.Email,
+		Address:        order.Address,
+		City:           order.City,
+		State:          order.State,
+		Zip:            order.Zip,
+		Phone:          order.Phone,
+		Member:         order.Member,
+		Created:        now,
+		ONote:          fmt.Sprintf("Donation of the value of tickets to cancelled event %s on order #%d.", eventID, order.ID),
+		MarketingOptIn: order.MarketingOptIn,
+		CustomerID:     order.CustomerID,
+		Lines:          []*model.OrderLine{{Product: donation, Quantity: 1, Price: amount, FMV: donation.FMV}},
+		Payments: []*model.Payment{{
+			Type:    model.PaymentOther,
+			Method:  fmt.Sprintf("transfer from order #%d", order.ID),
+			Created: now,
+			Amount:  amount,
+		}},
+	}
+	if username != "" {
+		donationOrder.Source = model.OrderFromOffice
+	}
+	tx.SaveOrder(donationOrder)
+	order.Payments = append(order.Payments, &model.Payment{
+		Type:    model.PaymentOther,
+		Method:  fmt.Sprintf("transfer to donation order #%d", donationOrder.ID),
+		Created: now,
+		Amount:  -amount,
+	})
+	return donationOrder
+}
+
+// removeCancelledTickets removes the cancelled tickets from the order, along
+// with the units of product they make up.
+func removeCancelledTickets(tx db.Tx, order *model.Order, eventID model.EventID, lines []cancelledLine) {
+	for _, cl := range lines {
+		if cl.units == cl.line.Quantity {
+			tx.DeleteOrderLine(cl.line)
+			for i, ol := range order.Lines {
+				if ol == cl.line {
+					order.Lines = append(order.Lines[:i], order.Lines[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		cl.line.Quantity -= cl.units
+		cl.line.Tickets = dropCancelledTickets(cl.line.Tickets, cl.units*cl.line.Product.TicketCount, eventID)
+	}
+}
+
+// dropCancelledTickets removes count unused tickets dedicated to the specified
+// event from the list, and returns the remainder.
+func dropCancelledTickets(tickets []*model.Ticket, count int, eventID model.EventID) (kept []*model.Ticket) {
+	for _, t := range tickets {
+		if count != 0 && t.Used.IsZero() && t.Event != nil && t.Event.ID == eventID {
+			count--
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// exchangeTickets replaces the cancelled tickets on an order line with tickets
+// for the new product, dedicated to the rescheduled event.  The price paid
+// for them is unchanged.
+func exchangeTickets(order *model.Order, eventID model.EventID, cl cancelledLine, np *model.Product, resched *model.Event) {
+	var nl *model.OrderLine
+
+	if cl.units == cl.line.Quantity {
+		// The whole line is exchanged, so we just change its product.
+		nl = cl.line
+		nl.Product = np
+		nl.Tickets = dropCancelledTickets(nl.Tickets, cl.units*np.TicketCount, eventID)
+	} else {
+		cl.line.Quantity -= cl.units
+		cl.line.Tickets = dropCancelledTickets(cl.line.Tickets, cl.units*cl.line.Product.TicketCount, eventID)
+		nl = &model.OrderLine{Product: np, Quantity: cl.units, Price: cl.line.Price}
+		order.Lines = append(order.Lines, nl)
+	}
+	for i := 0; i < cl.units*np.TicketCount; i++ {
+		nl.Tickets = append(nl.Tickets, &model.Ticket{Event: resched})
+	}
+}
+
+// exchangeProduct returns the ticket product dedicated to the rescheduled
+// event that is equivalent to the specified product, or nil if there isn't
+// one.  It must have the same number of tickets per unit, and preferably the
+// same ticket class.
+func exchangeProduct(tx db.Tx, resched *model.Event, product *model.Product) (match *model.Product) {
+	for _, p := range tx.FetchProductsByEvent(resched) {
+		if p.Type != model.ProdTicket || p.TicketCount != product.TicketCount {
+			continue
+		}
+		for _, pe := range p.Events {
+			if pe.Event.ID == resched.ID && pe.Priority == 0 {
+				if p.TicketClass == product.TicketClass {
+					return p
+				}
+				if match == nil {
+					match = p
+				}
+			}
+		}
+	}
+	return match
+}
+
+// cancellationData is the data passed to the cancellation notice templates.
+type cancellationData struct {
+	Order           *model.Order
+	Event           *model.Event
+	When            string
+	Rescheduled     *model.Event
+	RescheduledWhen string
+	Cancellation    *model.EventCancellation
+	CancelledOrder  *model.CancelledOrder
+	Amount          string
+	RefundMethod    string
+	ChoiceURL       string
+	TicketURL       string
+}
+
+// EmitCancellationNotice queues an email to the customer who placed an order
+// about its tickets to a cancelled event.  If no outcome has been chosen yet,
+// the email tells the customer of the cancellation and invites them to choose
+// one; otherwise, it confirms the outcome.  The notice is delivered once the
+// transaction is committed and SendQueuedEmail is called.  It returns the
+// queued message, or nil if there is no notice to send.  Errors are logged.
+func EmitCancellationNotice(
+	tx db.Tx, order *model.Order, event *model.Event, ec *model.EventCancellation, co *model.CancelledOrder,
+) *model.Email {
+	var (
+		buf      bytes.Buffer
+		out      bytes.Buffer
+		data     cancellationData
+		subject  string
+		htmlBody string
+		textBody string
+		bodyType string
+		body     []byte
+		qr       []byte
+		emailTo  []string
+		email    *model.Email
+		err      error
+	)
+	if order.Email == "" {
+		return nil
+	}
+	data.Order = order
+	data.Event = event
+	data.When = event.Start.Format("Monday, January 2, 2006 at 3:04pm")
+	data.Cancellation = ec
+	data.CancelledOrder = co
+	data.Amount = fmt.Sprintf("$%.2f", float64(co.Amount)/100.0)
+	data.TicketURL = config.Get("ordersURL") + "/ticket/" + order.Token
+	data.ChoiceURL = data.TicketURL + "/cancellation"
+	if ec.Rescheduled != "" {
+		if data.Rescheduled = tx.FetchEvent(ec.Rescheduled); data.Rescheduled != nil {
+			data.RescheduledWhen = data.Rescheduled.Start.Format("Monday, January 2, 2006 at 3:04pm")
+		}
+	}
+	if co.Outcome == model.CancelRefund {
+		for _, p := range order.Payments {
+			if p.Amount == -co.Amount {
+				data.RefundMethod = p.Method
+			}
+		}
+	}
+	if co.Outcome == model.CancelPending {
+		subject = fmt.Sprintf("Cancelled: %s, %s", event.Name, event.Start.Format("January 2"))
+	} else {
+		subject = fmt.Sprintf("Your tickets to %s", event.Name)
+	}
+	if err = cancellationHTMLTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render cancellation notice for order %d, event %s: %s", order.ID, event.ID, err)
+		return nil
+	}
+	htmlBody = out.String()
+	out.Reset()
+	if err = cancellationTextTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render cancellation notice for order %d, event %s: %s", order.ID, event.ID, err)
+		return nil
+	}
+	textBody = out.String()
+	if co.Outcome == model.CancelExchange {
+		if qr, err = OrderQRCode(order); err != nil {
+			log.Printf("ERROR: can't create QR code for order %d: %s", order.ID, err)
+			return nil
+		}
+	}
+	bodyType, body = htmlEmailBody(htmlBody, textBody, qr)
+
+	fmt.Fprint(&buf, "From: Schola Cantorum <admin@scholacantorum.org>\r\n")
+	fmt.Fprintf(&buf, "To: %s <%s>\r\n", order.Name, order.Email)
+	fmt.Fprint(&buf, "Bcc: admin@scholacantorum.org\r\n")
+	fmt.Fprint(&buf, "Reply-To: info@scholacantorum.org\r\n")
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", bodyType)
+	buf.Write(body)
+
+	emailTo = []string{"admin@scholacantorum.org"}
+	if config.Get("mode") != "development" {
+		emailTo = append(emailTo, order.Email)
+	}
+	email = &model.Email{
+		Order:   order.ID,
+		From:    "admin@scholacantorum.org",
+		To:      emailTo,
+		Subject: subject,
+		Message: buf.Bytes(),
+	}
+	QueueEmail(tx, email)
+	return email
+}
+
+var cancellationHTMLTemplate = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+{{- if eq .CancelledOrder.Outcome "exchange" }}<div style="float:right"><a href="{{ .TicketURL }}">
+<img src="cid:ORDER_QRCODE" alt="[Ticket Barcode]" style="border-width:0"></a></div>{{ end }}
+<p>Dear {{ or .Order.Name "Schola Cantorum Patron" }},</p>
+{{- if eq .CancelledOrder.Outcome "" }}
+<p>We regret to tell you that <b>{{ .Event.Name }}</b>, scheduled for {{ .When }}, has been cancelled.
+{{- if .Rescheduled }}  It has been rescheduled for {{ .RescheduledWhen }}{{ if .Rescheduled.Venue }}, at {{ .Rescheduled.Venue }}{{ end }}.{{ end }}</p>
+{{- if .Cancellation.Message }}<p>{{ .Cancellation.Message }}</p>{{ end }}
+<p>Your order #{{ .Order.ID }} includes {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} to this event.  You may:</p>
+<ul>
+{{- if .Rescheduled }}<li>exchange {{ if eq .CancelledOrder.Tickets 1 }}it{{ else }}them{{ end }} for tickets to the rescheduled performance,</li>{{ end }}
+{{- if and .Cancellation.Donation .CancelledOrder.Amount }}<li>donate {{ .Amount }}, the value of your tickets, to Schola Cantorum (tax deductible), or</li>{{ end }}
+<li>receive a refund of {{ .Amount }}.</li>
+</ul>
+<p><a href="{{ .ChoiceURL }}">Please let us know your choice.</a></p>
+{{- else if eq .CancelledOrder.Outcome "refund" }}
+<p>As requested, the {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} on your order #{{ .Order.ID }} to the cancelled <b>{{ .Event.Name }}</b> on {{ .When }} {{ if eq .CancelledOrder.Tickets 1 }}has{{ else }}have{{ end }} been cancelled,
+{{- if .CancelledOrder.Amount }} and {{ .Amount }} has been refunded{{ if .RefundMethod }} to your {{ .RefundMethod }}{{ end }}{{ end }}.</p>
+{{- else if eq .CancelledOrder.Outcome "donation" }}
+<p>Thank you for donating {{ .Amount }}, the value of the {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} on your order #{{ .Order.ID }} to the cancelled <b>{{ .Event.Name }}</b> on {{ .When }}.  Your generosity is deeply appreciated.</p>
+<p>No goods or services were provided in exchange for your donation.</p>
+{{- else if eq .CancelledOrder.Outcome "exchange" }}
+<p>As requested, the {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} on your order #{{ .Order.ID }} to the cancelled <b>{{ .Event.Name }}</b> on {{ .When }} {{ if eq .CancelledOrder.Tickets 1 }}has{{ else }}have{{ end }} been exchanged for tickets to
+{{- if .Rescheduled }} <b>{{ .Rescheduled.Name }}</b> on {{ .RescheduledWhen }}{{ if .Rescheduled.Venue }}, at {{ .Rescheduled.Venue }}{{ end }}{{ else }} the rescheduled performance{{ end }}.
+Please show this email (or <a href="{{ .TicketURL }}">your tickets</a>) at the door.</p>
+{{- end }}
+<p>Sincerely yours,<br>Schola Cantorum</p>
+<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>
+Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>
+Phone: (650) 254-1700</p></div></body></html>
+`))
+
+var cancellationTextTemplate = texttemplate.Must(texttemplate.New("").Parse(`Dear {{ or .Order.Name "Schola Cantorum Patron" }},
+{{ if eq .CancelledOrder.Outcome "" }}
+We regret to tell you that {{ .Event.Name }}, scheduled for {{ .When }}, has been cancelled.
+{{- if .Rescheduled }}  It has been rescheduled for {{ .RescheduledWhen }}{{ if .Rescheduled.Venue }}, at {{ .Rescheduled.Venue }}{{ end }}.{{ end }}
+{{ if .Cancellation.Message }}
+{{ .Cancellation.Message }}
+{{ end }}
+Your order #{{ .Order.ID }} includes {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} to this event.  You may:
+{{ if .Rescheduled }}  - exchange {{ if eq .CancelledOrder.Tickets 1 }}it{{ else }}them{{ end }} for tickets to the rescheduled performance,
+{{ end }}{{ if and .Cancellation.Donation .CancelledOrder.Amount }}  - donate {{ .Amount }}, the value of your tickets, to Schola Cantorum (tax deductible), or
+{{ end }}  - receive a refund of {{ .Amount }}.
+
+Please let us know your choice at {{ .ChoiceURL }}
+{{ else if eq .CancelledOrder.Outcome "refund" }}
+As requested, the {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} on your order #{{ .Order.ID }} to the cancelled {{ .Event.Name }} on {{ .When }} {{ if eq .CancelledOrder.Tickets 1 }}has{{ else }}have{{ end }} been cancelled
+{{- if .CancelledOrder.Amount }}, and {{ .Amount }} has been refunded{{ if .RefundMethod }} to your {{ .RefundMethod }}{{ end }}{{ end }}.
+{{ else if eq .CancelledOrder.Outcome "donation" }}
+Thank you for donating {{ .Amount }}, the value of the {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} on your order #{{ .Order.ID }} to the cancelled {{ .Event.Name }} on {{ .When }}.  Your generosity is deeply appreciated.
+
+No goods or services were provided in exchange for your donation.
+{{ else if eq .CancelledOrder.Outcome "exchange" }}
+As requested, the {{ .CancelledOrder.Tickets }} ticket{{ if ne .CancelledOrder.Tickets 1 }}s{{ end }} on your order #{{ .Order.ID }} to the cancelled {{ .Event.Name }} on {{ .When }} {{ if eq .CancelledOrder.Tickets 1 }}has{{ else }}have{{ end }} been exchanged for tickets to
+{{- if .Rescheduled }} {{ .Rescheduled.Name }} on {{ .RescheduledWhen }}{{ if .Rescheduled.Venue }}, at {{ .Rescheduled.Venue }}{{ end }}{{ else }} the rescheduled performance{{ end }}.
+Please show this email (or your tickets, at {{ .TicketURL }}) at the door.
+{{ end }}
+Sincerely yours,
+Schola Cantorum
+
+Web: https://scholacantorum.org
+Email: info@scholacantorum.org
+Phone: (650) 254-1700
+`))
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// customerMatcher finds the customer that placed an order, based on the
+// details of the orders already linked to customers.
+type customerMatcher struct {
+	byEmail    map[string]model.CustomerID
+	byCard     map[string]model.CustomerID
+	byNameAddr map[string]model.CustomerID
+}
+
+// MatchCustomers links each valid order that isn't yet linked to a customer
+// to the customer that placed it, creating a new customer if necessary.  An
+// order is recognized as coming from an existing customer if it has the same
+// email address as one of the customer's orders; or if it was paid with the
+// same card (or with a card last used with one of the customer's email
+// addresses); or if it has the same name and address, after normalization.
+// Orders with no name, email address, or card are left unlinked.  The contact
+// details of each matched customer are updated from the order.  MatchCustomers
+// returns the number of orders linked and the number of customers created.
+func MatchCustomers(tx db.Tx) (linked, created int) {
+	var cm = customerMatcher{
+		byEmail:    make(map[string]model.CustomerID),
+		byCard:     make(map[string]model.CustomerID),
+		byNameAddr: make(map[string]model.CustomerID),
+	}
+	for _, ck := range tx.FetchCustomerKeys() {
+		cm.add(ck.Customer, ck.Name, ck.Email, ck.Address, ck.Zip, ck.Card)
+	}
+	for _, oid := range tx.FetchUnlinkedOrders() {
+		var (
+			order = tx.FetchOrder(oid)
+			cards []string
+			cust  *model.Customer
+		)
+		for _, p := range order.Payments {
+			if p.Card != "" {
+				cards = append(cards, p.Card)
+			}
+		}
+		if order.Name == "" && order.Email == "" && len(cards) == 0 {
+			continue
+		}
+		if cid := cm.match(tx, order, cards); cid != 0 {
+			cust = tx.FetchCustomer(cid)
+		}
+		if cust == nil {
+			cust = &model.Customer{Created: time.Now()}
+			created++
+		}
+		updateCustomer(cust, order)
+		tx.SaveCustomer(cust)
+		order.CustomerID = cust.ID
+		tx.SaveOrder(order)
+		linked++
+		if len(cards) == 0 {
+			cards = []string{""}
+		}
+		for _, card := range cards {
+			cm.add(cust.ID, order.Name, order.Email, order.Address, order.Zip, card)
+		}
+	}
+	return linked, created
+}
+
+// add records the identifying details of an order linked to a customer.
+func (cm *customerMatcher) add(cid model.CustomerID, name, email, address, zip, card string) {
+	if email != "" {
+		cm.byEmail[strings.ToLower(email)] = cid
+	}
+	if card != "" {
+		cm.byCard[card] = cid
+	}
+	if key := nameAddressKey(name, address, zip); key != "" {
+		cm.byNameAddr[key] = cid
+	}
+}
+
+// match returns the customer who placed the order, or zero if it isn't from a
+// known customer.
+func (cm *customerMatcher) match(tx db.Tx, order *model.Order, cards []string) model.CustomerID {
+	if order.Email != "" {
+		if cid := cm.byEmail[strings.ToLower(order.Email)]; cid != 0 {
+			return cid
+		}
+	}
+	for _, card := range cards {
+		if cid := cm.byCard[card]; cid != 0 {
+			return cid
+		}
+		if _, email := tx.FetchCard(card); email != "" {
+			if cid := cm.byEmail[strings.ToLower(email)]; cid != 0 {
+				return cid
+			}
+		}
+	}
+	if key := nameAddressKey(order.Name, order.Address, order.Zip); key != "" {
+		return cm.byNameAddr[key]
+	}
+	return 0
+}
+
+// updateCustomer updates the customer's contact details with those on the
+// order, where the order has them.
+func updateCustomer(cust *model.Customer, order *model.Order) {
+	if order.Name != "" {
+		cust.Name = order.Name
+	}
+	if order.Email != "" {
+		cust.Email = order.Email
+	}
+	if order.Address != "" {
+		cust.Address, cust.City, cust.State, cust.Zip = order.Address, order.City, order.State, order.Zip
+	}
+	if order.Phone != "" {
+		cust.Phone = order.Phone
+	}
+}
+
+// nameAddressKey returns the normalized name and address used to match
+// customers, or an empty string if the name or address is missing.
+func nameAddressKey(name, address, zip string) string {
+	var household string
+
+	if name = normalizeWords(name, nil); name == "" {
+		return ""
+	}
+	if household = HouseholdKey(address, zip); household == "" {
+		return ""
+	}
+	return name + "|" + household
+}
+
+// HouseholdKey returns the normalized street address and 5-digit zip code that
+// identify a household, or an empty string if the address is missing.
+func HouseholdKey(address, zip string) string {
+	if address = normalizeWords(address, addressAbbreviations); address == "" {
+		return ""
+	}
+	if len(zip) > 5 {
+		zip = zip[:5]
+	}
+	return address + "|" + zip
+}
+
+// normalizeWords returns the words of s, in lower case, without punctuation,
+// with the abbreviations applied, separated by single spaces.
+func normalizeWords(s string, abbreviations map[string]string) string {
+	var words = strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if abbr := abbreviations[word]; abbr != "" {
+			words[i] = abbr
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// addressAbbreviations are the standard abbreviations of common words in
+// street addresses.
+var addressAbbreviations = map[string]string{
+	"apartment": "apt",
+	"avenue":    "ave",
+	"boulevard": "blvd",
+	"court":     "ct",
+	"drive":     "dr",
+	"east":      "e",
+	"highway":   "hwy",
+	"lane":      "ln",
+	"north":     "n",
+	"parkway":   "pkwy",
+	"place":     "pl",
+	"road":      "rd",
+	"south":     "s",
+	"street":    "st",
+	"suite":     "ste",
+	"terrace":   "ter",
+	"unit":      "apt",
+	"way":       "wy",
+	"west":      "w",
+}
@@ -0,0 +1,414 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// A DonorStatement is a year-end acknowledgement of a donor's contributions.
+type DonorStatement struct {
+	// Key identifies the donor:  their email address, in lower case, or if
+	// they have none, their name, address, and zip code.
+	Key         string
+	Name        string
+	Email       string
+	Address     string
+	City        string
+	State       string
+	Zip         string
+	Gifts       []*DonorGift
+	Total       int       // total of all gifts, in cents
+	Deductible  int       // total deductible amount, in cents
+	Sent        time.Time // zero if the statement hasn't been emailed
+	lastOrder   model.OrderID
+	latest      time.Time
+	addressAsOf time.Time
+}
+
+// A DonorGift is one line of a DonorStatement.
+type DonorGift struct {
+	Date        time.Time
+	Order       model.OrderID
+	Description string
+	Amount      int // in cents
+	Deductible  int // in cents
+	// Goods is true if goods or services (e.g., gala admission or an auction
+	// item) were provided in exchange for the gift, and GoodsValue is our
+	// good-faith estimate of their fair market value, in cents.
+	Goods      bool
+	GoodsValue int
+}
+
+// DonorStatements returns the donation acknowledgement statements for the
+// specified calendar year, sorted by donor name.  They include all donations,
+// gala registrations, and auction items on valid orders placed during the
+// year.  Covered processing fees aren't included, since they aren't gifts.
+// Orders without a customer name are omitted, since they can't be
+// acknowledged.
+func DonorStatements(tx db.Tx, year int) (list []*DonorStatement) {
+	var (
+		from  = time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+		to    = time.Date(year+1, 1, 1, 0, 0, 0, 0, time.Local)
+		byKey = make(map[string]*DonorStatement)
+	)
+	for _, oid := range tx.FetchDonationOrders(from, to) {
+		var (
+			order = tx.FetchOrder(oid)
+			key   string
+			ds    *DonorStatement
+		)
+		if order.Name == "" {
+			continue
+		}
+		if order.Email != "" {
+			key = strings.ToLower(order.Email)
+		} else {
+			key = strings.ToLower(strings.Join([]string{order.Name, order.Address, order.Zip}, "|"))
+		}
+		if ds = byKey[key]; ds == nil {
+			ds = &DonorStatement{Key: key}
+			byKey[key] = ds
+			list = append(list, ds)
+		}
+		// The name and address are taken from the donor's most recent
+		// order (with an address, in the latter case).
+		if !order.Created.Before(ds.latest) {
+			ds.Name, ds.Email, ds.lastOrder, ds.latest = order.Name, order.Email, order.ID, order.Created
+		}
+		if order.Address != "" && !order.Created.Before(ds.addressAsOf) {
+			ds.Address, ds.City, ds.State, ds.Zip = order.Address, order.City, order.State, order.Zip
+			ds.addressAsOf = order.Created
+		}
+		for _, ol := range order.Lines {
+			var gift = DonorGift{Date: order.Created, Order: order.ID, Description: ol.Product.Name, Amount: ol.Quantity * ol.Price}
+
+			switch ol.Product.Type {
+			case model.ProdDonation:
+				break
+			case model.ProdRegistration, model.ProdAuctionItem:
+				gift.Goods = true
+			default:
+				continue
+			}
+			gift.Deductible = ol.Deductible()
+			if gift.Goods {
+				gift.GoodsValue = ol.GoodsValue()
+			}
+			if gift.Amount == 0 {
+				continue
+			}
+			ds.Gifts = append(ds.Gifts, &gift)
+			ds.Total += gift.Amount
+			ds.Deductible += gift.Deductible
+		}
+	}
+	for _, ds := range list {
+		sort.SliceStable(ds.Gifts, func(i, j int) bool { return ds.Gifts[i].Date.Before(ds.Gifts[j].Date) })
+		if ds.Email != "" {
+			ds.Sent = tx.FetchDonorStatementSent(year, ds.Key)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// SendDonorStatements queues email statements for the specified year to each
+// donor with an email address who hasn't already been sent one, and records
+// that they were sent.  The statements are delivered once the transaction is
+// committed and SendQueuedEmail is called.  It returns the number of
+// statements queued.
+func SendDonorStatements(tx db.Tx, list []*DonorStatement, year int) (count int) {
+	for _, ds := range list {
+		var email *model.Email
+
+		if ds.Email == "" || !ds.Sent.IsZero() || len(ds.Gifts) == 0 {
+			continue
+		}
+		if email = EmitDonorStatement(tx, ds, year); email == nil {
+			continue
+		}
+		ds.Sent = email.Created
+		tx.SaveDonorStatementSent(year, ds.Key, ds.Sent, email.ID)
+		count++
+	}
+	return count
+}
+
+// donorStatementData is the data passed to the donor statement email
+// templates.
+type donorStatementData struct {
+	Statement *DonorStatement
+	Year      int
+}
+
+// EmitDonorStatement queues an email to a donor with their statement for the
+// specified year attached as a PDF.  The email is delivered once the
+// transaction is committed and SendQueuedEmail is called.  It returns the
+// queued message, or nil if there is an error (which is logged).
+func EmitDonorStatement(tx db.Tx, ds *DonorStatement, year int) *model.Email {
+	var (
+		buf      bytes.Buffer
+		out      bytes.Buffer
+		data     = donorStatementData{Statement: ds, Year: year}
+		subject  string
+		htmlBody string
+		textBody string
+		bodyType string
+		body     []byte
+		pdf      []byte
+		xw       *multipart.Writer
+		part     io.Writer
+		hdr      textproto.MIMEHeader
+		emailTo  []string
+		email    *model.Email
+		err      error
+	)
+	if pdf, err = DonorStatementPDF([]*DonorStatement{ds}, year); err != nil {
+		log.Printf("ERROR: can't create donor statement PDF for %s: %s", ds.Email, err)
+		return nil
+	}
+	if err = donorStatementHTMLTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render donor statement for %s: %s", ds.Email, err)
+		return nil
+	}
+	htmlBody = out.String()
+	out.Reset()
+	if err = donorStatementTextTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render donor statement for %s: %s", ds.Email, err)
+		return nil
+	}
+	textBody = out.String()
+	bodyType, body = htmlEmailBody(htmlBody, textBody, nil)
+	subject = fmt.Sprintf("Your %d donation acknowledgement from Schola Cantorum", year)
+
+	fmt.Fprint(&buf, "From: Schola Cantorum <admin@scholacantorum.org>\r\n")
+	fmt.Fprintf(&buf, "To: %s <%s>\r\n", mime.QEncoding.Encode("UTF-8", ds.Name), ds.Email)
+	fmt.Fprint(&buf, "Reply-To: info@scholacantorum.org\r\n")
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	xw = multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", xw.Boundary())
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", bodyType)
+	part, _ = xw.CreatePart(hdr)
+	part.Write(body)
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", "application/pdf")
+	hdr.Set("Content-Transfer-Encoding", "base64")
+	hdr.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="schola-donations-%d.pdf"`, year))
+	part, _ = xw.CreatePart(hdr)
+	writeBase64(part, pdf)
+	xw.Close()
+
+	if config.Get("mode") == "development" {
+		emailTo = []string{"admin@scholacantorum.org"}
+	} else {
+		emailTo = []string{ds.Email}
+	}
+	email = &model.Email{
+		Order:   ds.lastOrder,
+		From:    "admin@scholacantorum.org",
+		To:      emailTo,
+		Subject: subject,
+		Message: buf.Bytes(),
+	}
+	QueueEmail(tx, email)
+	return email
+}
+
+// DonorStatementPDF returns a printable PDF of the statements for the
+// specified year, each starting on a new page.
+func DonorStatementPDF(list []*DonorStatement, year int) (pdfbytes []byte, err error) {
+	var (
+		pdf  *gofpdf.Fpdf
+		logo []byte
+		buf  bytes.Buffer
+	)
+	if logo, err = base64.StdEncoding.DecodeString(string(bytes.ReplaceAll(mailLogo, []byte("\n"), nil))); err != nil {
+		return nil, err
+	}
+	pdf = gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetTitle(fmt.Sprintf("Schola Cantorum %d Donation Acknowledgement", year), true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: "GIF"}, bytes.NewReader(logo))
+	for _, ds := range list {
+		emitDonorStatementPage(pdf, ds, year)
+	}
+	if err = pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// emitDonorStatementPage adds a donor's statement to the PDF.
+func emitDonorStatementPage(pdf *gofpdf.Fpdf, ds *DonorStatement, year int) {
+	const (
+		lineHeight  = 6.0
+		dateWidth   = 24.0
+		orderWidth  = 18.0
+		amountWidth = 28.0
+		descWidth   = 216.0 - 40 - dateWidth - orderWidth - 3*amountWidth
+	)
+	var (
+		tr         = pdf.UnicodeTranslatorFromDescriptor("")
+		goods      bool
+		goodsValue int
+		taxID      = config.Get("taxID")
+		text       string
+	)
+	pdf.AddPage()
+	pdf.ImageOptions("logo", 20, 20, 120, 0, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.SetY(52)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, lineHeight, time.Now().Format("January 2, 2006"), "", 1, "L", false, 0, "")
+	pdf.Ln(lineHeight)
+	pdf.CellFormat(0, lineHeight, tr(ds.Name), "", 1, "L", false, 0, "")
+	if ds.Address != "" {
+		pdf.CellFormat(0, lineHeight, tr(ds.Address), "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, lineHeight, tr(fmt.Sprintf("%s, %s  %s", ds.City, ds.State, ds.Zip)), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(lineHeight)
+	pdf.MultiCell(0, lineHeight, tr(fmt.Sprintf("Dear %s,", ds.Name)), "", "L", false)
+	pdf.Ln(2)
+	pdf.MultiCell(0, lineHeight, tr(fmt.Sprintf(
+		"Thank you for your generous support of Schola Cantorum in %d.  This letter acknowledges "+
+			"the contributions we received from you during the year:", year)), "", "L", false)
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(dateWidth, lineHeight, "Date", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(orderWidth, lineHeight, "Order", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(descWidth, lineHeight, "Description", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(amountWidth, lineHeight, "Amount", "B", 0, "R", false, 0, "")
+	pdf.CellFormat(amountWidth, lineHeight, "Goods Value", "B", 0, "R", false, 0, "")
+	pdf.CellFormat(amountWidth, lineHeight, "Deductible", "B", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	for _, g := range ds.Gifts {
+		var (
+			desc  = g.Description
+			value string
+		)
+		if g.Goods {
+			desc += " *"
+			goods = true
+			goodsValue += g.GoodsValue
+			value = statementDollars(g.GoodsValue)
+		}
+		pdf.CellFormat(dateWidth, lineHeight, g.Date.Format("Jan 2, 2006"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(orderWidth, lineHeight, fmt.Sprintf("#%d", g.Order), "", 0, "L", false, 0, "")
+		pdf.CellFormat(descWidth, lineHeight, tr(desc), "", 0, "L", false, 0, "")
+		pdf.CellFormat(amountWidth, lineHeight, statementDollars(g.Amount), "", 0, "R", false, 0, "")
+		pdf.CellFormat(amountWidth, lineHeight, value, "", 0, "R", false, 0, "")
+		pdf.CellFormat(amountWidth, lineHeight, statementDollars(g.Deductible), "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(dateWidth+orderWidth+descWidth, lineHeight, "Total", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(amountWidth, lineHeight, statementDollars(ds.Total), "T", 0, "R", false, 0, "")
+	if goods {
+		pdf.CellFormat(amountWidth, lineHeight, statementDollars(goodsValue), "T", 0, "R", false, 0, "")
+	} else {
+		pdf.CellFormat(amountWidth, lineHeight, "", "T", 0, "R", false, 0, "")
+	}
+	pdf.CellFormat(amountWidth, lineHeight, statementDollars(ds.Deductible), "T", 1, "R", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "", 11)
+	if goods {
+		pdf.MultiCell(0, 5, tr(
+			"* Goods or services (such as admission to an event or an auction item) were provided in "+
+				"exchange for this payment.  Only the portion of the payment exceeding our good-faith estimate "+
+				"of the fair market value of the goods or services, shown as their goods value, is tax deductible.  No goods or services were provided in exchange "+
+				"for your other contributions."), "", "L", false)
+	} else {
+		pdf.MultiCell(0, 5, "No goods or services were provided in exchange for these contributions.", "", "L", false)
+	}
+	pdf.Ln(2)
+	text = "Schola Cantorum is a tax-exempt organization under section 501(c)(3) of the Internal Revenue Code"
+	if taxID != "" {
+		text += fmt.Sprintf("; our federal tax identification number is %s", taxID)
+	}
+	text += ".  Please keep this letter for your tax records."
+	pdf.MultiCell(0, 5, tr(text), "", "L", false)
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.MultiCell(0, lineHeight, "With gratitude,\nSchola Cantorum", "", "L", false)
+}
+
+// statementDollars formats an amount in cents for a donor statement.
+func statementDollars(cents int) string {
+	if cents < 0 {
+		return fmt.Sprintf("-$%d.%02d", -cents/100, -cents%100)
+	}
+	return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+}
+
+// WriteDonorStatementsCSV writes a mail-merge CSV file for the statements in
+// the list that can't be emailed, i.e., those for donors without email
+// addresses.
+func WriteDonorStatementsCSV(w io.Writer, list []*DonorStatement, year int) {
+	var cw = csv.NewWriter(w)
+
+	cw.Write([]string{"Name", "Address", "City", "State", "Zip", "Year", "Total", "Deductible", "Gifts"})
+	for _, ds := range list {
+		var gifts []string
+
+		if ds.Email != "" || len(ds.Gifts) == 0 {
+			continue
+		}
+		for _, g := range ds.Gifts {
+			var gift = fmt.Sprintf("%s %s %s", g.Date.Format("2006-01-02"), g.Description, statementDollars(g.Amount))
+
+			if g.Goods {
+				gift += fmt.Sprintf(" (goods valued at %s; %s deductible)", statementDollars(g.GoodsValue), statementDollars(g.Deductible))
+			}
+			gifts = append(gifts, gift)
+		}
+		cw.Write([]string{
+			ds.Name, ds.Address, ds.City, ds.State, ds.Zip, fmt.Sprint(year),
+			statementDollars(ds.Total), statementDollars(ds.Deductible), strings.Join(gifts, "; "),
+		})
+	}
+	cw.Flush()
+}
+
+var donorStatementHTMLTemplate = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+<p>Dear {{ .Statement.Name }},</p>
+<p>Thank you for your generous support of Schola Cantorum in {{ .Year }}.  Attached is an acknowledgement of the contributions we received from you during the year, for your tax records.</p>
+<p>Sincerely yours,<br>Schola Cantorum</p>
+<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>
+Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>
+Phone: (650) 254-1700</p>
+</div></body></html>
+`))
+
+var donorStatementTextTemplate = texttemplate.Must(texttemplate.New("").Parse(`Dear {{ .Statement.Name }},
+
+Thank you for your generous support of Schola Cantorum in {{ .Year }}.  Attached is an acknowledgement of the contributions we received from you during the year, for your tax records.
+
+Sincerely yours,
+Schola Cantorum
+
+Web: https://scholacantorum.org
+Email: info@scholacantorum.org
+Phone: (650) 254-1700
+`))
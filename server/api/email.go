@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os/exec"
+	"time"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// QueueEmail adds a message to the email outbox, for delivery as soon as the
+// transaction is committed and SendQueuedEmail is called.
+func QueueEmail(tx db.Tx, email *model.Email) {
+	email.Created = time.Now()
+	email.NextAttempt = email.Created
+	tx.SaveEmail(email)
+}
+
+// SendQueuedEmail starts a subprocess that delivers the messages waiting in
+// the email outbox.  It should be called after committing a transaction that
+// queued messages.
+func SendQueuedEmail() {
+	var (
+		cmd *exec.Cmd
+		err error
+	)
+	cmd = exec.Command(config.Get("bin") + "/send-email-outbox")
+	if err = cmd.Start(); err != nil {
+		// The messages will be sent on the next periodic run.
+		log.Printf("ERROR: can't start email delivery: %s", err)
+		return
+	}
+	// Note that we are intentionally not waiting for the subprocess to
+	// finish, for the same reason as in UpdateGoogleSheet.
+}
+
+// htmlEmailBody returns the body of an email message with the specified HTML
+// and plain text bodies, and its content type.  The HTML body goes in a
+// multipart/related with the Schola logo (cid:SCHOLA_LOGO) and, if qr is not
+// nil, the QR code (cid:ORDER_QRCODE).  If there is a plain text body, it and
+// the multipart/related are wrapped in a multipart/alternative.
+func htmlEmailBody(htmlBody, textBody string, qr []byte) (bodyType string, body []byte) {
+	var (
+		buf     bytes.Buffer
+		related bytes.Buffer
+		aw      *multipart.Writer
+		rw      *multipart.Writer
+		part    io.Writer
+		hdr     textproto.MIMEHeader
+		qp      io.WriteCloser
+	)
+	rw = multipart.NewWriter(&related)
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", "text/html; charset=UTF-8")
+	hdr.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, _ = rw.CreatePart(hdr)
+	qp = quotedprintable.NewWriter(part)
+	qp.Write([]byte(htmlBody))
+	qp.Close()
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", "image/gif")
+	hdr.Set("Content-Transfer-Encoding", "base64")
+	hdr.Set("Content-ID", "<SCHOLA_LOGO>")
+	part, _ = rw.CreatePart(hdr)
+	part.Write(mailLogo)
+	if qr != nil {
+		hdr = make(textproto.MIMEHeader)
+		hdr.Set("Content-Type", "image/png")
+		hdr.Set("Content-Transfer-Encoding", "base64")
+		hdr.Set("Content-ID", "<ORDER_QRCODE>")
+		part, _ = rw.CreatePart(hdr)
+		writeBase64(part, qr)
+	}
+	rw.Close()
+	if textBody == "" {
+		return "multipart/related; boundary=" + rw.Boundary(), related.Bytes()
+	}
+	aw = multipart.NewWriter(&buf)
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", "text/plain; charset=UTF-8")
+	hdr.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, _ = aw.CreatePart(hdr)
+	qp = quotedprintable.NewWriter(part)
+	qp.Write([]byte(textBody))
+	qp.Close()
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", "multipart/related; boundary="+rw.Boundary())
+	part, _ = aw.CreatePart(hdr)
+	part.Write(related.Bytes())
+	aw.Close()
+	return "multipart/alternative; boundary=" + aw.Boundary(), buf.Bytes()
+}
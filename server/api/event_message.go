@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"strings"
+	texttemplate "text/template"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// eventMessageData is the data passed to the event message templates.
+type eventMessageData struct {
+	Event      *model.Event
+	When       string
+	Body       string
+	Paragraphs [][]string
+}
+
+// EmitEventMessage queues a copy of an ad hoc message to the ticket holders
+// of an event, addressed to the specified recipient.  orderID is the order
+// through which the recipient holds tickets, or zero for a test copy.  The
+// message is delivered once the transaction is committed and SendQueuedEmail
+// is called.  It returns the queued message, or nil if there is an error
+// (which is logged).
+func EmitEventMessage(
+	tx db.Tx, em *model.EventMessage, event *model.Event, name, address string, orderID model.OrderID,
+) *model.Email {
+	var (
+		buf      bytes.Buffer
+		out      bytes.Buffer
+		data     eventMessageData
+		htmlBody string
+		textBody string
+		bodyType string
+		body     []byte
+		emailTo  []string
+		email    *model.Email
+		err      error
+	)
+	data.Event = event
+	data.When = event.Start.Format("Monday, January 2, 2006 at 3:04pm")
+	data.Body = strings.TrimSpace(strings.Replace(em.Body, "\r\n", "\n", -1))
+	for _, para := range strings.Split(data.Body, "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			data.Paragraphs = append(data.Paragraphs, strings.Split(para, "\n"))
+		}
+	}
+	if err = eventMessageHTMLTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render message for event %s: %s", event.ID, err)
+		return nil
+	}
+	htmlBody = out.String()
+	out.Reset()
+	if err = eventMessageTextTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render message for event %s: %s", event.ID, err)
+		return nil
+	}
+	textBody = out.String()
+	bodyType, body = htmlEmailBody(htmlBody, textBody, nil)
+
+	fmt.Fprint(&buf, "From: Schola Cantorum <admin@scholacantorum.org>\r\n")
+	if name != "" {
+		fmt.Fprintf(&buf, "To: %s <%s>\r\n", mime.QEncoding.Encode("UTF-8", name), address)
+	} else {
+		fmt.Fprintf(&buf, "To: %s\r\n", address)
+	}
+	fmt.Fprint(&buf, "Reply-To: info@scholacantorum.org\r\n")
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", em.Subject))
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", bodyType)
+	buf.Write(body)
+
+	if config.Get("mode") == "development" {
+		emailTo = []string{"admin@scholacantorum.org"}
+	} else {
+		emailTo = []string{address}
+	}
+	email = &model.Email{
+		Order:   orderID,
+		From:    "admin@scholacantorum.org",
+		To:      emailTo,
+		Subject: em.Subject,
+		Message: buf.Bytes(),
+	}
+	QueueEmail(tx, email)
+	return email
+}
+
+var eventMessageHTMLTemplate = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+{{- range .Paragraphs }}
+<p>{{ range $i, $line := . }}{{ if $i }}<br>{{ end }}{{ $line }}{{ end }}</p>
+{{- end }}
+<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>
+Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>
+Phone: (650) 254-1700</p>
+<p style="font-size:smaller;color:#666">You are receiving this message because you have tickets to {{ .Event.Name }} on {{ .When }}.</p>
+</div></body></html>
+`))
+
+var eventMessageTextTemplate = texttemplate.Must(texttemplate.New("").Parse(`{{ .Body }}
+
+Web: https://scholacantorum.org
+Email: info@scholacantorum.org
+Phone: (650) 254-1700
+
+You are receiving this message because you have tickets to {{ .Event.Name }} on {{ .When }}.
+`))
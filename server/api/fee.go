@@ -0,0 +1,72 @@
+package api
+
+import (
+	"math"
+	"strconv"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ProcessingFee returns the card processing fee, in cents, on an order whose
+// total (without the fee) is the specified number of cents.  It is computed so
+// that, after the processor takes its cut of the total including the fee, we
+// receive the original total.  The processor's rate is given by the "feeRate"
+// configuration variable, as a percentage, and its fixed per-charge amount by
+// the "feeFixed" configuration variable, in cents.  ProcessingFee returns zero
+// if customers can't cover the fee, because those variables or the
+// "feeProduct" variable aren't set.
+func ProcessingFee(total int) int {
+	var (
+		rate  float64
+		fixed int
+		bp    int
+		err   error
+	)
+	if total <= 0 || config.Get("feeProduct") == "" {
+		return 0
+	}
+	if rate, err = strconv.ParseFloat(config.Get("feeRate"), 64); err != nil || rate <= 0 || rate >= 100 {
+		return 0
+	}
+	if fixed, err = strconv.Atoi(config.Get("feeFixed")); err != nil || fixed < 0 {
+		return 0
+	}
+	// Work in basis points to avoid floating point rounding surprises.
+	bp = int(math.Round(rate * 100))
+	return ((total+fixed)*10000+(10000-bp)-1)/(10000-bp) - total
+}
+
+// addProcessingFee adds a line for the card processing fee to the order, if
+// the customer chose to cover it.  The order's CoverFee is the fee amount the
+// customer was shown; it must match the fee computed here.  It returns false
+// if the fee can't be added, or if the order already contains fee lines.
+func addProcessingFee(tx db.Tx, order *model.Order) bool {
+	var (
+		product *model.Product
+		total   int
+		fee     int
+	)
+	for _, ol := range order.Lines {
+		if ol.Product.Type == model.ProdFee {
+			return false
+		}
+		total += ol.Price * ol.Quantity
+	}
+	if order.CoverFee == 0 {
+		return true
+	}
+	if len(order.Payments) != 1 ||
+		(order.Payments[0].Type != model.PaymentCard && order.Payments[0].Type != model.PaymentCardPresent) {
+		return false
+	}
+	if product = tx.FetchProduct(model.ProductID(config.Get("feeProduct"))); product == nil || product.Type != model.ProdFee {
+		return false
+	}
+	if fee = ProcessingFee(total); fee == 0 || fee != order.CoverFee {
+		return false
+	}
+	order.Lines = append(order.Lines, &model.OrderLine{Product: product, Quantity: 1, Price: fee})
+	return true
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// A JournalEntry is one day's general ledger journal entry for the accounting
+// export.  Its lines are balanced: their amounts sum to zero.
+type JournalEntry struct {
+	Date  time.Time // midnight local time
+	Lines []JournalLine
+}
+
+// A JournalLine is one line of a JournalEntry.
+type JournalLine struct {
+	Account string
+	Amount  int // in cents; positive for debits, negative for credits
+}
+
+// BuildJournal returns the journal entries, one per day, for the days from
+// from (inclusive) to to (exclusive), using the general ledger account mappings
+// in the database.  Each payment (or refund) is debited to its payment account
+// on the day it was made, and credited to the revenue accounts of the order's
+// lines, in proportion to their amounts.  If the payment was made before the
+// day of the last event a line is for, its share is credited to deferred
+// revenue instead, and moved from deferred revenue to revenue on the day of
+// that event.  Amounts that don't match any mapping are posted to accounts
+// named "UNMAPPED ...", so that they are noticed.
+//
+// The entries are computed from the orders as they are now, so the entry for
+// a past day can change when orders do (e.g., when tickets are refunded).
+// Since the bookkeeper can't rewrite the books for periods already exported,
+// BuildJournal records the entries for each day as it is first exported (if
+// the day is over), and thereafter returns the recorded entry for that day.
+// The differences between the recorded and computed entries for all exported
+// days are posted, as adjustments, on the first day of the requested range
+// that hasn't been exported; if there is none, they wait for a later export.
+func BuildJournal(tx db.Tx, from, to time.Time) (entries []*JournalEntry) {
+	var (
+		mappings    = tx.FetchGLAccounts()
+		byDay       = make(map[time.Time]map[string]int)
+		exported    = tx.FetchExportedJournal()
+		adjustments = make(map[string]int)
+		today       = startOfDay(time.Now())
+		through     = to
+	)
+	// Compute the entries for every exported day, as well as for the
+	// requested range, so that all of the differences are found.
+	for day := range exported {
+		if !day.Before(through) {
+			through = day.AddDate(0, 0, 1)
+		}
+	}
+	post := func(day time.Time, account string, amount int) {
+		if !day.Before(through) || amount == 0 {
+			return
+		}
+		if byDay[day] == nil {
+			byDay[day] = make(map[string]int)
+		}
+		byDay[day][account] += amount
+	}
+	for _, oid := range tx.FetchPaidOrders(through) {
+		var (
+			order = tx.FetchOrder(oid)
+			total int
+		)
+		for _, ol := range order.Lines {
+			total += ol.Price * ol.Quantity
+		}
+		for _, p := range order.Payments {
+			var (
+				day       = startOfDay(p.Created)
+				remaining = p.Amount
+				ptype     = db.ReportPaymentType(string(p.Type), p.Subtype)
+			)
+			if p.Amount == 0 {
+				continue
+			}
+			post(day, glPaymentAccount(mappings, ptype), p.Amount)
+			for i, ol := range order.Lines {
+				var (
+					share int
+					recog time.Time
+				)
+				if total == 0 {
+					break
+				}
+				if i == len(order.Lines)-1 {
+					share = remaining
+				} else {
+					share = int(int64(p.Amount) * int64(ol.Price*ol.Quantity) / int64(total))
+				}
+				remaining -= share
+				if recog = recognitionDay(ol.Product); day.Before(recog) {
+					post(day, glLineAccount(mappings, model.GLDeferred, ol.Product), -share)
+					post(recog, glLineAccount(mappings, model.GLDeferred, ol.Product), share)
+					post(recog, glLineAccount(mappings, model.GLRevenue, ol.Product), -share)
+				} else {
+					post(day, glLineAccount(mappings, model.GLRevenue, ol.Product), -share)
+				}
+			}
+			if remaining != 0 {
+				// A payment on an order with no priced lines.
+				post(day, "UNMAPPED revenue", -remaining)
+			}
+		}
+	}
+	// Replace the computed entries for exported days with the recorded
+	// ones, accumulating the differences.  Because the adjustments posted
+	// by earlier exports are part of the recorded entries, differences
+	// already adjusted for cancel out.
+	for day, accounts := range exported {
+		for account, amount := range byDay[day] {
+			adjustments[account] += amount
+		}
+		for account, amount := range accounts {
+			adjustments[account] -= amount
+		}
+		byDay[day] = accounts
+	}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if _, ok := exported[day]; ok {
+			continue
+		}
+		for account, amount := range adjustments {
+			post(day, account, amount)
+		}
+		break
+	}
+	// Record the entries for the days in the range that are over and
+	// haven't been exported before.
+	for day := from; day.Before(to) && day.Before(today); day = day.AddDate(0, 0, 1) {
+		if _, ok := exported[day]; !ok {
+			tx.SaveExportedJournalDay(day, byDay[day])
+		}
+	}
+	for day, accounts := range byDay {
+		var je = JournalEntry{Date: day}
+
+		if day.Before(from) || !day.Before(to) {
+			continue
+		}
+		for account, amount := range accounts {
+			if amount != 0 {
+				je.Lines = append(je.Lines, JournalLine{Account: account, Amount: amount})
+			}
+		}
+		if len(je.Lines) == 0 {
+			continue
+		}
+		// Debits first, then credits, each in account order.
+		sort.Slice(je.Lines, func(i, j int) bool {
+			if (je.Lines[i].Amount > 0) != (je.Lines[j].Amount > 0) {
+				return je.Lines[i].Amount > 0
+			}
+			return je.Lines[i].Account < je.Lines[j].Account
+		})
+		entries = append(entries, &je)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return entries
+}
+
+// recognitionDay returns the day on which revenue from the sale of the
+// product is earned: the day of the last (uncancelled) event it is for.  It
+// returns the zero time for products that aren't for events, whose revenue is
+// earned when paid.
+func recognitionDay(p *model.Product) (day time.Time) {
+	for _, pe := range p.Events {
+		if pe.Event.Cancelled.IsZero() && pe.Event.Start.After(day) {
+			day = pe.Event.Start
+		}
+	}
+	if day.IsZero() {
+		return day
+	}
+	return startOfDay(day)
+}
+
+// startOfDay returns midnight local time at the start of the day containing
+// the specified time.
+func startOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// glLineAccount returns the account to which the revenue (or deferred
+// revenue) from an order line for the specified product is posted.
+func glLineAccount(mappings []*model.GLAccount, kind model.GLAccountKind, p *model.Product) string {
+	var (
+		account = fmt.Sprintf("UNMAPPED %s %s %s", kind, p.Type, p.Series)
+		best    = -1
+	)
+	for _, gla := range mappings {
+		var score int
+
+		if gla.Kind != kind {
+			continue
+		}
+		switch gla.ProductType {
+		case "":
+			break
+		case p.Type:
+			score += 2
+		default:
+			continue
+		}
+		switch gla.Series {
+		case "":
+			break
+		case p.Series:
+			score++
+		default:
+			continue
+		}
+		if score > best {
+			account, best = gla.Account, score
+		}
+	}
+	return strings.TrimSpace(account)
+}
+
+// glPaymentAccount returns the account to which payments of the specified
+// type (as shown in reports) are posted.
+func glPaymentAccount(mappings []*model.GLAccount, ptype string) string {
+	var (
+		account  = "UNMAPPED payment " + ptype
+		best     = -1
+		category = ptype
+	)
+	if idx := strings.IndexByte(ptype, ','); idx >= 0 {
+		category = ptype[:idx]
+	}
+	for _, gla := range mappings {
+		var score int
+
+		if gla.Kind != model.GLPayment {
+			continue
+		}
+		switch gla.PaymentType {
+		case "":
+			score = 0
+		case ptype:
+			score = 2
+		case category:
+			score = 1
+		default:
+			continue
+		}
+		if score > best {
+			account, best = gla.Account, score
+		}
+	}
+	return account
+}
@@ -21,6 +21,11 @@ var stateRE = regexp.MustCompile(`^[A-Z][A-Z]$`)
 var zipRE = regexp.MustCompile(`^\d{5}(?:-\d{4})?$`)
 var customerRE = regexp.MustCompile(`^cus_[A-Za-z0-9]+$`)
 
+// ValidEmail returns whether the string is a syntactically valid email address.
+func ValidEmail(email string) bool {
+	return emailRE.MatchString(email)
+}
+
 // GetOrderFromRequest reads the order details from the request body and returns
 // them.  If it returns nil, the order details were invalid; an appropriate
 // error response has been issued, and the error has been logged.
@@ -40,7 +45,9 @@ var customerRE = regexp.MustCompile(`^cus_[A-Za-z0-9]+$`)
 //     oNote:  order note from office
 //     inAccess:  flag whether order is in office Access database
 //     coupon:  coupon code used for order
+//     marketingOptIn:  flag whether customer consents to marketing email
 //     saveForReuse:  order method should be preserved for later charges
+//     coverFee:  processing fee (in cents) the customer chose to cover
 //     [line# begins at 1]
 //     line#.product:  product ID for line #
 //     line#.quantity:  quantity for line #
@@ -48,6 +55,11 @@ var customerRE = regexp.MustCompile(`^cus_[A-Za-z0-9]+$`)
 //     line#.guestName:  name of guest for line #
 //     line#.guestEmail:  email address of guest for line #
 //     line#.option:  product option for line #
+//     line#.fund:  fund designation for donation on line #
+//     line#.tribute:  tribute type ("honor" or "memory") for donation on line #
+//     line#.honoree:  person honored or remembered by donation on line #
+//     line#.notifyName:  name of person to notify of tribute on line #
+//     line#.notifyEmail:  email address of person to notify of tribute on line #
 //     line#.used:  number of tickets used for line #
 //     line#.usedAt:  event ID of event at which tickets were used for line #
 //     [payment# begins at 1]
@@ -61,6 +73,13 @@ func GetOrderFromRequest(w http.ResponseWriter, r *http.Request) (o *model.Order
 	o = new(model.Order)
 	o.Source = model.OrderSource(r.FormValue("source"))
 	o.SaveForReuse = r.FormValue("saveForReuse") != ""
+	if fee := r.FormValue("coverFee"); fee != "" {
+		if o.CoverFee, err = strconv.Atoi(fee); err != nil || o.CoverFee < 0 {
+			log.Printf("ERROR: invalid coverFee %q", fee)
+			http.Error(w, `400 Bad Request: invalid "coverFee"`, http.StatusBadRequest)
+			goto ERROR
+		}
+	}
 	switch o.Source {
 	case "":
 		o.Source = model.OrderFromPublic
@@ -123,6 +142,13 @@ func GetOrderFromRequest(w http.ResponseWriter, r *http.Request) (o *model.Order
 		}
 	}
 	o.Coupon = strings.ToUpper(strings.TrimSpace(r.FormValue("coupon")))
+	if mostr := r.FormValue("marketingOptIn"); mostr != "" {
+		if o.MarketingOptIn, err = strconv.ParseBool(mostr); err != nil {
+			log.Printf("ERROR: invalid marketingOptIn %q", mostr)
+			http.Error(w, `400 Bad Request: invalid "marketingOptIn"`, http.StatusBadRequest)
+			goto ERROR
+		}
+	}
 	for idx := 1; true; idx++ {
 		var (
 			ol     model.OrderLine
@@ -146,6 +172,11 @@ func GetOrderFromRequest(w http.ResponseWriter, r *http.Request) (o *model.Order
 		ol.GuestName = r.FormValue(prefix + "guestName")
 		ol.GuestEmail = r.FormValue(prefix + "guestEmail")
 		ol.Option = r.FormValue(prefix + "option")
+		ol.Fund = strings.TrimSpace(r.FormValue(prefix + "fund"))
+		ol.Tribute = model.TributeType(r.FormValue(prefix + "tribute"))
+		ol.Honoree = strings.TrimSpace(r.FormValue(prefix + "honoree"))
+		ol.NotifyName = strings.TrimSpace(r.FormValue(prefix + "notifyName"))
+		ol.NotifyEmail = strings.TrimSpace(r.FormValue(prefix + "notifyEmail"))
 		if uval := r.FormValue(prefix + "used"); uval != "" {
 			if ol.Used, err = strconv.Atoi(uval); err != nil || ol.Used < 0 {
 				log.Printf("ERROR: invalid used amount %q", uval)
@@ -202,50 +233,21 @@ ERROR:
 // Emits JSON order for success.
 func CreateOrderCommon(tx db.Tx, w http.ResponseWriter, session *model.Session, order *model.Order) {
 	var (
-		privs   model.Privilege
 		success bool
 		card    string
 		message string
 		receipt bool
 		logverb = "PLACE"
 	)
-	if session != nil {
-		privs = session.Privileges
-	}
-	// Resolve the products and SKUs and validate the prices.
-	if !resolveSKUs(tx, order) {
-		log.Printf("ERROR: invalid products or prices in order %s", order.ToJSON(true))
-		BadRequestError(tx, w, "invalid products or prices")
-		return
-	}
-	// Validate the customer data.
-	if !validateCustomer(tx, order, session) {
-		log.Printf("ERROR: invalid customer data in order %s", order.ToJSON(true))
-		BadRequestError(tx, w, "invalid customer data")
+	// Validate the order, and fill in its token and tickets.
+	if reason := PrepareOrder(tx, session, order); reason != "" {
+		BadRequestError(tx, w, reason)
 		return
 	}
-	// Make sure the rest of the order details are OK.
-	if !validateOrderDetails(tx, order, privs) {
-		log.Printf("ERROR: invalid parameters in order %s", order.ToJSON(true))
-		BadRequestError(tx, w, "invalid parameters")
-		return
-	}
-	// Calculate the order total and verify the payment.
-	if !validatePayment(order) {
-		log.Printf("ERROR: invalid payment in order %s", order.ToJSON(true))
-		BadRequestError(tx, w, "invalid payment")
-		return
-	}
-	// Assign a token to the order.
-	order.Token = newOrderToken(tx)
-	// Generate tickets if needed.  TODO this shouldn't happen until the
-	// order is successfully charged.
-	generateTickets(tx, order)
 	// If we don't have to charge a card through Stripe, the order is now
 	// complete.
 	if len(order.Payments) == 0 {
 		order.Valid = true
-		receipt = true
 	} else {
 		switch order.Payments[0].Type {
 		case model.PaymentCard, model.PaymentCardPresent:
@@ -254,14 +256,17 @@ func CreateOrderCommon(tx db.Tx, w http.ResponseWriter, session *model.Session,
 			order.Valid = true
 		}
 	}
-	// Save the order to the database.
+	// Save the order to the database, and queue its receipt if it's
+	// complete.
 	tx.SaveOrder(order)
+	if order.Valid {
+		receipt = EmitReceipt(tx, order) != nil
+		receipt = EmitTributeNotices(tx, order) != 0 || receipt
+	}
 	Commit(tx)
 	// If we do have to charge a card through Stripe, do it now.
 	if len(order.Payments) == 1 {
 		switch order.Payments[0].Type {
-		case model.PaymentCash, model.PaymentCheck, model.PaymentOther:
-			receipt = true
 		case model.PaymentCard:
 			if order.SaveForReuse && order.Customer == "" {
 				stripe.FindOrCreateCustomer(order)
@@ -279,10 +284,12 @@ func CreateOrderCommon(tx db.Tx, w http.ResponseWriter, session *model.Session,
 				return
 			}
 			order.Valid = true
+			order.Payments[0].Card = card
 			tx.SaveOrder(order)
 			tx.SaveCard(card, order.Name, order.Email)
-			receipt = true
 			order.Name, order.Email = tx.FetchCard(card)
+			receipt = EmitReceipt(tx, order) != nil
+			receipt = EmitTributeNotices(tx, order) != 0 || receipt
 			Commit(tx)
 		case model.PaymentCardPresent:
 			// For card present transactions, we have to create the
@@ -301,7 +308,6 @@ func CreateOrderCommon(tx db.Tx, w http.ResponseWriter, session *model.Session,
 			tx.SaveOrder(order)
 			Commit(tx)
 			logverb = "CREATE"
-			receipt = false
 		}
 	}
 	// Log and return the completed order.
@@ -312,14 +318,65 @@ func CreateOrderCommon(tx db.Tx, w http.ResponseWriter, session *model.Session,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(order.ToJSON(false))
-	if receipt && order.Email != "" {
-		EmitReceipt(order, false)
+	if receipt {
+		SendQueuedEmail()
 	}
 	if order.Valid {
 		UpdateGoogleSheet(order)
 	}
 }
 
+// PrepareOrder makes the common checks on a new order, and then assigns it a
+// token and generates its tickets, leaving it ready to be saved.  It returns an
+// empty string if the order is OK, or the reason for rejecting it otherwise.
+// CreateOrderCommon calls it for single orders; APIs that create orders in bulk
+// call it directly.
+func PrepareOrder(tx db.Tx, session *model.Session, order *model.Order) (reason string) {
+	var privs model.Privilege
+
+	if session != nil {
+		privs = session.Privileges
+	}
+	// Resolve the products and SKUs and validate the prices.
+	if !resolveSKUs(tx, order) {
+		log.Printf("ERROR: invalid products or prices in order %s", order.ToJSON(true))
+		return "invalid products or prices"
+	}
+	// Reject tickets to cancelled events.
+	for _, ol := range order.Lines {
+		if ProductCancelled(ol.Product) {
+			log.Printf("ERROR: ticket to cancelled event in order %s", order.ToJSON(true))
+			return "event has been cancelled"
+		}
+	}
+	// Validate the customer data.
+	if !validateCustomer(tx, order, session) {
+		log.Printf("ERROR: invalid customer data in order %s", order.ToJSON(true))
+		return "invalid customer data"
+	}
+	// Make sure the rest of the order details are OK.
+	if !validateOrderDetails(tx, order, privs) {
+		log.Printf("ERROR: invalid parameters in order %s", order.ToJSON(true))
+		return "invalid parameters"
+	}
+	// Add the processing fee, if the customer chose to cover it.
+	if !addProcessingFee(tx, order) {
+		log.Printf("ERROR: invalid processing fee in order %s", order.ToJSON(true))
+		return "invalid processing fee"
+	}
+	// Calculate the order total and verify the payment.
+	if !validatePayment(order) {
+		log.Printf("ERROR: invalid payment in order %s", order.ToJSON(true))
+		return "invalid payment"
+	}
+	// Assign a token to the order.
+	order.Token = newOrderToken(tx)
+	// Generate tickets if needed.  TODO this shouldn't happen until the
+	// order is successfully charged.
+	generateTickets(tx, order)
+	return ""
+}
+
 // resolveSKUs walks through each line of the order, finding the listed product
 // and verifying the amount of the order line, following the SKU rules
 // documented in db/schema.sql. It returns true if everything resolved
@@ -335,6 +392,7 @@ func resolveSKUs(tx db.Tx, order *model.Order) bool {
 		if line.Product = tx.FetchProduct(line.Product.ID); line.Product == nil {
 			return false
 		}
+		line.FMV = line.Product.FMV
 		if line.Product.Type == model.ProdAuctionItem || line.Product.Type == model.ProdDonation {
 			if line.Price < 1 {
 				return false
@@ -356,6 +414,9 @@ func resolveSKUs(tx db.Tx, order *model.Order) bool {
 		if line.Price != sku.Price {
 			return false
 		}
+		if sku.FMV != 0 {
+			line.FMV = sku.FMV
+		}
 	}
 	if !couponMatch {
 		order.Coupon = ""
@@ -414,6 +475,12 @@ func validateOrderDetails(tx db.Tx, order *model.Order, privs model.Privilege) b
 	// Check the validity of each order line.
 	for _, line := range order.Lines {
 
+		// Fund designations and tributes are allowed only on
+		// donations.
+		if !validDesignation(line) {
+			return false
+		}
+
 		// Additional constraints by product type:
 		switch line.Product.Type {
 		case model.ProdDonation, model.ProdRecording, model.ProdSheetMusic, model.ProdRegistration, model.ProdAuctionItem:
@@ -448,6 +515,43 @@ func validateOrderDetails(tx db.Tx, order *model.Order, privs model.Privilege) b
 	return true
 }
 
+// validDesignation returns whether the fund designation and tribute
+// information on an order line are valid.  A fund must be one of the product's
+// funds.  A tribute must name its honoree, and the notification of it, if any,
+// must have a valid email address.
+func validDesignation(line *model.OrderLine) bool {
+	if line.Product.Type != model.ProdDonation {
+		return line.Fund == "" && line.Tribute == model.TributeNone && line.Honoree == "" &&
+			line.NotifyName == "" && line.NotifyEmail == ""
+	}
+	if line.Fund != "" {
+		var found bool
+		for _, f := range line.Product.Funds {
+			if f == line.Fund {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	switch line.Tribute {
+	case model.TributeNone:
+		return line.Honoree == "" && line.NotifyName == "" && line.NotifyEmail == ""
+	case model.TributeHonor, model.TributeMemory:
+		if line.Honoree == "" {
+			return false
+		}
+	default:
+		return false
+	}
+	if line.NotifyName != "" && line.NotifyEmail == "" {
+		return false
+	}
+	return line.NotifyEmail == "" || ValidEmail(line.NotifyEmail)
+}
+
 // validatePayment returns whether the order payment is valid for the order type
 // and has the correct amount.
 func validatePayment(order *model.Order) bool {
@@ -556,3 +660,21 @@ func UpdateGoogleSheet(order *model.Order) {
 	// a fast response to their order.  The subprocess will continue as an
 	// orphan, and its zombie will be reaped by the init daemon.
 }
+
+// UpdateWalletPasses starts a subprocess that updates the Apple Wallet and
+// Google Wallet passes for the order, after a change to its tickets.  It should
+// be called only for orders that have had a pass issued, and only after the
+// change has been committed.
+func UpdateWalletPasses(order *model.Order) {
+	var (
+		cmd *exec.Cmd
+		err error
+	)
+	cmd = exec.Command(config.Get("bin")+"/update-wallet-passes", strconv.Itoa(int(order.ID)))
+	if err = cmd.Start(); err != nil {
+		log.Printf("ERROR: can't update wallet passes for order %d: %s", order.ID, err)
+		return
+	}
+	// Note that we are intentionally not waiting for the subprocess to
+	// finish, for the same reason as in UpdateGoogleSheet.
+}
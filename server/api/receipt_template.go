@@ -0,0 +1,361 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ReceiptData is the data passed to receipt templates.
+type ReceiptData struct {
+	// Order is the order for which the receipt is being sent.
+	Order *model.Order
+
+	// Kind is "Donation" if the order contains only donations, and "Order"
+	// otherwise.
+	Kind string
+
+	// TicketURL is the URL of the order's ticket page, or empty if the
+	// order has no tickets.  When it is set, the HTML body can show the
+	// order's QR code with <img src="cid:ORDER_QRCODE">.  The HTML body can
+	// always show the Schola logo with <img src="cid:SCHOLA_LOGO">.
+	TicketURL string
+
+	// Lines contains the receipt text for each line of the order, rendered
+	// from the receipt text of its product.  (Use the plain function to
+	// convert them for the plain text body.)
+	Lines []htmltemplate.HTML
+
+	// Payments contains a sentence describing each payment on the order,
+	// e.g., "You paid $30.00 on January 2, 2026 at 3:04pm via cash."
+	Payments []string
+
+	// Fee is the card processing fee the customer chose to cover, in
+	// cents, or zero if they didn't.
+	Fee int
+
+	// Deductible is the tax-deductible portion of the order total, in
+	// cents, and Goods is the fair market value of the goods and services
+	// received in exchange for the partly deductible lines of the order
+	// (registrations and auction items).
+	Deductible int
+	Goods      int
+
+	// TaxStatement is a sentence stating the tax-deductible amount of the
+	// order, or empty if none of it is deductible.  When goods or services
+	// were received in exchange for a contribution, it gives their value,
+	// as the IRS requires.
+	TaxStatement string
+
+	// UnsubscribeURL is the URL of the page where the customer can remove
+	// themselves from the mailing list.  If the rendered receipt doesn't
+	// contain it, a footer with it is added (see addUnsubscribeFooter).
+	UnsubscribeURL string
+}
+
+// receiptFuncs are the functions available to receipt templates (as well as
+// to product receipt text).
+var receiptFuncs = map[string]interface{}{
+	"dollars": func(c int) string { return fmt.Sprintf("%.2f", float64(c)/100.0) },
+	"plain":   plainText,
+}
+
+// defaultReceiptTemplate is the receipt template used for orders that no
+// template in the database applies to.
+var defaultReceiptTemplate = model.ReceiptTemplate{
+	Name:    "(built-in)",
+	Subject: `Schola Cantorum {{ .Kind }} #{{ .Order.ID }}`,
+	HTML: `<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+{{- if .TicketURL }}<div style="float:right"><a href="{{ .TicketURL }}">
+<img src="cid:ORDER_QRCODE" alt="[Ticket Barcode]" style="border-width:0"></a></div>{{ end -}}
+<p>Dear {{ or .Order.Name "Schola Cantorum Patron" }},</p>
+{{- range .Lines }}{{ . }}{{ end -}}
+{{ if .Fee }}<p>Thank you for covering the ${{ dollars .Fee }} card processing fee.</p>{{ end -}}
+{{ if .Payments }}<p>{{ range $i, $p := .Payments }}{{ if $i }}<br>{{ end }}{{ $p }}{{ end }}</p>{{ end -}}
+{{ if .TaxStatement }}<p>{{ .TaxStatement }}</p>{{ end -}}
+<p>Sincerely yours,<br>Schola Cantorum</p>
+<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>
+Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>
+Phone: (650) 254-1700</p>
+{{- if .UnsubscribeURL }}
+<p style="font-size:12px;color:#666">To stop receiving news of Schola Cantorum concerts and events,
+<a href="{{ .UnsubscribeURL }}">unsubscribe from our mailing list</a>.</p>{{ end }}</div></body></html>
+`,
+	Text: `Dear {{ or .Order.Name "Schola Cantorum Patron" }},
+{{ range .Lines }}
+{{ plain . }}
+{{ end }}{{ if .Fee }}
+Thank you for covering the ${{ dollars .Fee }} card processing fee.
+{{ end }}{{ if .Payments }}
+{{ range .Payments }}{{ . }}
+{{ end }}{{ end }}{{ if .TaxStatement }}
+{{ .TaxStatement }}
+{{ end }}{{ if .TicketURL }}
+Your tickets: {{ .TicketURL }}
+{{ end }}
+Sincerely yours,
+Schola Cantorum
+
+Web: https://scholacantorum.org
+Email: info@scholacantorum.org
+Phone: (650) 254-1700
+{{ if .UnsubscribeURL }}
+To stop receiving news of Schola Cantorum concerts and events,
+unsubscribe from our mailing list: {{ .UnsubscribeURL }}
+{{ end }}`,
+}
+
+// CheckReceiptTemplate returns an error if any of the receipt template's parts
+// fail to parse.
+func CheckReceiptTemplate(rt *model.ReceiptTemplate) (err error) {
+	if _, err = texttemplate.New("subject").Funcs(receiptFuncs).Parse(rt.Subject); err != nil {
+		return err
+	}
+	if _, err = htmltemplate.New("html").Funcs(receiptFuncs).Parse(rt.HTML); err != nil {
+		return err
+	}
+	_, err = texttemplate.New("text").Funcs(receiptFuncs).Parse(rt.Text)
+	return err
+}
+
+// SelectReceiptTemplate returns the receipt template to use for the order.
+// A template for a product on the order is preferred, followed by a template
+// for a product type on the order, followed by the default template in the
+// database, followed by the built-in template.  It returns nil if no receipt
+// should be sent for the order:  this happens when no template specific to
+// its products applies, and none of its products have receipt text (e.g. gala
+// products, whose receipts are sent by the gala software).
+func SelectReceiptTemplate(tx db.Tx, order *model.Order) *model.ReceiptTemplate {
+	var (
+		rts      = tx.FetchReceiptTemplates()
+		byType   *model.ReceiptTemplate
+		dflt     *model.ReceiptTemplate
+		havetext bool
+	)
+	for _, ol := range order.Lines {
+		if ol.Product.Receipt != "" {
+			havetext = true
+		}
+		for _, rt := range rts {
+			switch {
+			case rt.Product == ol.Product.ID:
+				return rt
+			case rt.ProductType == ol.Product.Type && byType == nil:
+				byType = rt
+			}
+		}
+	}
+	if byType != nil {
+		return byType
+	}
+	if !havetext {
+		return nil
+	}
+	for _, rt := range rts {
+		if rt.Product == "" && rt.ProductType == "" {
+			dflt = rt
+		}
+	}
+	if dflt != nil {
+		return dflt
+	}
+	return &defaultReceiptTemplate
+}
+
+// RenderReceipt renders the receipt template for the order, returning the
+// subject, HTML body, and plain text body.  The plain text body is empty if
+// the template doesn't have one.
+func RenderReceipt(rt *model.ReceiptTemplate, order *model.Order) (subject, htmlBody, textBody string, err error) {
+	var (
+		data ReceiptData
+		ttmp *texttemplate.Template
+		htmp *htmltemplate.Template
+		buf  bytes.Buffer
+	)
+	if data, err = receiptData(order); err != nil {
+		return "", "", "", err
+	}
+	if ttmp, err = texttemplate.New("subject").Funcs(receiptFuncs).Parse(rt.Subject); err != nil {
+		return "", "", "", err
+	}
+	if err = ttmp.Execute(&buf, &data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+	buf.Reset()
+	if htmp, err = htmltemplate.New("html").Funcs(receiptFuncs).Parse(rt.HTML); err != nil {
+		return "", "", "", err
+	}
+	if err = htmp.Execute(&buf, &data); err != nil {
+		return "", "", "", err
+	}
+	htmlBody = buf.String()
+	buf.Reset()
+	if strings.TrimSpace(rt.Text) != "" {
+		if ttmp, err = texttemplate.New("text").Funcs(receiptFuncs).Parse(rt.Text); err != nil {
+			return "", "", "", err
+		}
+		if err = ttmp.Execute(&buf, &data); err != nil {
+			return "", "", "", err
+		}
+		textBody = buf.String()
+	}
+	htmlBody, textBody = addUnsubscribeFooter(htmlBody, textBody, data.UnsubscribeURL)
+	return subject, htmlBody, textBody, nil
+}
+
+// addUnsubscribeFooter adds a footer with the unsubscribe URL to the HTML and
+// plain text receipt bodies, unless the receipt template already put it there.
+// This ensures that every receipt has it, even those rendered from templates
+// in the database that predate it.
+func addUnsubscribeFooter(htmlBody, textBody, url string) (string, string) {
+	if url == "" {
+		return htmlBody, textBody
+	}
+	if !strings.Contains(htmlBody, url) {
+		footer := fmt.Sprintf(`<p style="font-size:12px;color:#666">To stop receiving news of Schola Cantorum concerts and events, <a href="%s">unsubscribe from our mailing list</a>.</p>`, url)
+		if idx := strings.LastIndex(strings.ToLower(htmlBody), "</body>"); idx >= 0 {
+			htmlBody = htmlBody[:idx] + footer + htmlBody[idx:]
+		} else {
+			htmlBody += footer
+		}
+	}
+	if textBody != "" && !strings.Contains(textBody, url) {
+		textBody = strings.TrimRight(textBody, "\n") +
+			"\n\nTo stop receiving news of Schola Cantorum concerts and events,\nunsubscribe from our mailing list: " + url + "\n"
+	}
+	return htmlBody, textBody
+}
+
+// PreviewReceipt renders the receipt template for the order, for display in a
+// browser.  It is the same as RenderReceipt except that the images referenced
+// by the HTML body are inlined as data URLs.
+func PreviewReceipt(rt *model.ReceiptTemplate, order *model.Order) (subject, htmlBody, textBody string, err error) {
+	var qr []byte
+
+	if subject, htmlBody, textBody, err = RenderReceipt(rt, order); err != nil {
+		return "", "", "", err
+	}
+	htmlBody = strings.Replace(htmlBody, "cid:SCHOLA_LOGO",
+		"data:image/gif;base64,"+string(bytes.Replace(mailLogo, []byte("\n"), nil, -1)), -1)
+	if strings.Contains(htmlBody, "cid:ORDER_QRCODE") {
+		if qr, err = OrderQRCode(order); err != nil {
+			return "", "", "", err
+		}
+		htmlBody = strings.Replace(htmlBody, "cid:ORDER_QRCODE",
+			"data:image/png;base64,"+base64.StdEncoding.EncodeToString(qr), -1)
+	}
+	return subject, htmlBody, textBody, nil
+}
+
+// receiptData returns the data for rendering the receipt for the order.
+func receiptData(order *model.Order) (data ReceiptData, err error) {
+	var (
+		tmpl       *htmltemplate.Template
+		buf        bytes.Buffer
+		quidProQuo bool
+	)
+	data.Order = order
+	if order.Token != "" {
+		data.UnsubscribeURL = config.Get("ordersURL") + "/ticket/" + order.Token + "/unsubscribe"
+	}
+	for _, ol := range order.Lines {
+		data.Deductible += ol.Deductible()
+		if ol.Product.Type == model.ProdRegistration || ol.Product.Type == model.ProdAuctionItem {
+			if ol.Price*ol.Quantity != 0 {
+				quidProQuo = true
+			}
+			data.Goods += ol.GoodsValue()
+		}
+		switch ol.Product.Type {
+		case model.ProdDonation:
+			if data.Kind == "" {
+				data.Kind = "Donation"
+			}
+		case model.ProdFee:
+			// The fee is shown separately, and doesn't affect the
+			// kind of receipt.
+			data.Fee += ol.Price * ol.Quantity
+			continue
+		case model.ProdTicket:
+			data.TicketURL = config.Get("ordersURL") + "/ticket/" + order.Token
+			fallthrough
+		default:
+			data.Kind = "Order"
+		}
+		if tmpl, err = htmltemplate.New("t").Funcs(receiptFuncs).Parse(ol.Product.Receipt); err != nil {
+			return data, fmt.Errorf("receipt text for %s does not parse: %s", ol.Product.ID, err)
+		}
+		buf.Reset()
+		if err = tmpl.Execute(&buf, ol); err != nil {
+			return data, fmt.Errorf("receipt text for %s failed: %s", ol.Product.ID, err)
+		}
+		data.Lines = append(data.Lines, htmltemplate.HTML(buf.String()))
+	}
+	switch {
+	case quidProQuo:
+		data.TaxStatement = fmt.Sprintf("Our good-faith estimate of the fair market value of the goods and services "+
+			"you received is $%.2f.  The tax-deductible portion of your payment is $%.2f.",
+			float64(data.Goods)/100.0, float64(data.Deductible)/100.0)
+	case data.Deductible != 0:
+		data.TaxStatement = fmt.Sprintf("No goods or services were provided in exchange for your contribution of "+
+			"$%.2f, which is fully tax deductible.", float64(data.Deductible)/100.0)
+	}
+	for _, p := range order.Payments {
+		var method string
+
+		switch p.Type {
+		case model.PaymentCard, model.PaymentCardPresent, model.PaymentOther:
+			method = p.Method
+		case model.PaymentCash:
+			method = "cash"
+		case model.PaymentCheck:
+			if p.Method == "" {
+				method = "Check"
+			} else if strings.HasPrefix(strings.ToLower(p.Method), "check") {
+				method = p.Method
+			} else {
+				method = "Check " + p.Method
+			}
+		}
+		if p.Amount >= 0 {
+			data.Payments = append(data.Payments, fmt.Sprintf("You paid $%.2f on %s via %s.",
+				float64(p.Amount)/100.0, p.Created.Format("January 2, 2006 at 3:04pm"), method))
+		} else {
+			data.Payments = append(data.Payments, fmt.Sprintf("You were refunded $%.2f on %s via %s.",
+				-float64(p.Amount)/100.0, p.Created.Format("January 2, 2006 at 3:04pm"), method))
+		}
+	}
+	return data, nil
+}
+
+var (
+	breakRE  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	paraRE   = regexp.MustCompile(`(?i)</?(?:p|div|h[1-6]|li|tr)(?:\s[^>]*)?>`)
+	tagRE    = regexp.MustCompile(`<[^>]*>`)
+	blanksRE = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// plainText converts an HTML fragment to plain text, for use in the plain text
+// body of a receipt.
+func plainText(h htmltemplate.HTML) string {
+	var s = string(h)
+
+	s = breakRE.ReplaceAllString(s, "\n")
+	s = paraRE.ReplaceAllString(s, "\n\n")
+	s = tagRE.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blanksRE.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
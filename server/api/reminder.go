@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"sort"
+	texttemplate "text/template"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// reminderData is the data passed to the reminder email templates.
+type reminderData struct {
+	Order     *model.Order
+	Event     *model.Event
+	When      string
+	Tickets   []string
+	TicketURL string
+	OptOutURL string
+}
+
+// EmitReminder queues an email reminding the customer who placed an order
+// about an upcoming event for which the order has tickets.  tickets gives the
+// number of the order's tickets usable at the event, by ticket class.  The
+// reminder is delivered once the transaction is committed and SendQueuedEmail
+// is called.  It returns the queued message, or nil if there is no reminder
+// to send.  Errors are logged.
+func EmitReminder(tx db.Tx, order *model.Order, event *model.Event, tickets map[string]int) *model.Email {
+	var (
+		buf      bytes.Buffer
+		out      bytes.Buffer
+		data     reminderData
+		classes  []string
+		subject  string
+		htmlBody string
+		textBody string
+		bodyType string
+		body     []byte
+		qr       []byte
+		emailTo  []string
+		email    *model.Email
+		err      error
+	)
+	if order.Email == "" {
+		return nil
+	}
+	data.Order = order
+	data.Event = event
+	data.When = event.Start.Format("Monday, January 2, 2006 at 3:04pm")
+	data.TicketURL = config.Get("ordersURL") + "/ticket/" + order.Token
+	data.OptOutURL = data.TicketURL + "/noreminders"
+	for class := range tickets {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		var count = tickets[class]
+
+		switch {
+		case class == "" && count == 1:
+			data.Tickets = append(data.Tickets, "1 ticket")
+		case class == "":
+			data.Tickets = append(data.Tickets, fmt.Sprintf("%d tickets", count))
+		case count == 1:
+			data.Tickets = append(data.Tickets, fmt.Sprintf("1 %s ticket", class))
+		default:
+			data.Tickets = append(data.Tickets, fmt.Sprintf("%d %s tickets", count, class))
+		}
+	}
+	subject = fmt.Sprintf("Reminder: %s, %s", event.Name, event.Start.Format("January 2 at 3:04pm"))
+	if err = reminderHTMLTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render reminder for order %d, event %s: %s", order.ID, event.ID, err)
+		return nil
+	}
+	htmlBody = out.String()
+	out.Reset()
+	if err = reminderTextTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render reminder for order %d, event %s: %s", order.ID, event.ID, err)
+		return nil
+	}
+	textBody = out.String()
+	if qr, err = OrderQRCode(order); err != nil {
+		log.Printf("ERROR: can't create QR code for order %d: %s", order.ID, err)
+		return nil
+	}
+	bodyType, body = htmlEmailBody(htmlBody, textBody, qr)
+
+	fmt.Fprint(&buf, "From: Schola Cantorum <admin@scholacantorum.org>\r\n")
+	fmt.Fprintf(&buf, "To: %s <%s>\r\n", order.Name, order.Email)
+	fmt.Fprint(&buf, "Reply-To: info@scholacantorum.org\r\n")
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	// These headers let mail programs offer a one-click opt-out (RFC 8058).
+	fmt.Fprintf(&buf, "List-Unsubscribe: <%s>\r\n", data.OptOutURL)
+	fmt.Fprint(&buf, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", bodyType)
+	buf.Write(body)
+
+	if config.Get("mode") == "development" {
+		emailTo = []string{"admin@scholacantorum.org"}
+	} else {
+		emailTo = []string{order.Email}
+	}
+	email = &model.Email{
+		Order:   order.ID,
+		From:    "admin@scholacantorum.org",
+		To:      emailTo,
+		Subject: subject,
+		Message: buf.Bytes(),
+	}
+	QueueEmail(tx, email)
+	return email
+}
+
+var reminderHTMLTemplate = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+<div style="float:right"><a href="{{ .TicketURL }}">
+<img src="cid:ORDER_QRCODE" alt="[Ticket Barcode]" style="border-width:0"></a></div>
+<p>Dear {{ or .Order.Name "Schola Cantorum Patron" }},</p>
+<p>We look forward to seeing you at <b>{{ .Event.Name }}</b> on {{ .When }}{{ if .Event.Venue }}, at {{ .Event.Venue }}{{ end }}.</p>
+<p>Your order #{{ .Order.ID }} includes {{ range $i, $t := .Tickets }}{{ if $i }}, {{ end }}{{ $t }}{{ end }} for this event.
+Please show this email (or <a href="{{ .TicketURL }}">your tickets</a>) at the door.</p>
+{{- if .Event.Parking }}<p><b>Parking:</b> {{ .Event.Parking }}</p>{{ end }}
+<p>Sincerely yours,<br>Schola Cantorum</p>
+<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>
+Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>
+Phone: (650) 254-1700</p>
+<p style="font-size:smaller;color:#666">Don't want event reminders?  <a href="{{ .OptOutURL }}">Stop sending them.</a></p>
+</div></body></html>
+`))
+
+var reminderTextTemplate = texttemplate.Must(texttemplate.New("").Parse(`Dear {{ or .Order.Name "Schola Cantorum Patron" }},
+
+We look forward to seeing you at {{ .Event.Name }} on {{ .When }}{{ if .Event.Venue }}, at {{ .Event.Venue }}{{ end }}.
+
+Your order #{{ .Order.ID }} includes {{ range $i, $t := .Tickets }}{{ if $i }}, {{ end }}{{ $t }}{{ end }} for this event.
+Please show this email (or your tickets, at {{ .TicketURL }}) at the door.
+{{ if .Event.Parking }}
+Parking: {{ .Event.Parking }}
+{{ end }}
+Sincerely yours,
+Schola Cantorum
+
+Web: https://scholacantorum.org
+Email: info@scholacantorum.org
+Phone: (650) 254-1700
+
+Don't want event reminders?  Stop sending them: {{ .OptOutURL }}
+`))
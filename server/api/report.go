@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ParseReportDefinition parses a report definition from the parameters of a
+// report request (or the query string of a saved report).  It returns nil if
+// the parameters are invalid.
+func ParseReportDefinition(tx db.Tx, form url.Values) (def *model.ReportDefinition) {
+	var ok bool
+
+	def = new(model.ReportDefinition)
+	for _, os := range form["orderSource"] {
+		switch os := model.OrderSource(os); os {
+		case model.OrderFromPublic, model.OrderFromMembers, model.OrderFromGala, model.OrderFromOffice, model.OrderInPerson:
+			def.OrderSources = append(def.OrderSources, os)
+		default:
+			return nil
+		}
+	}
+	def.Customer = strings.ToLower(strings.TrimSpace(form.Get("customer")))
+	if before := form.Get("createdBefore"); before != "" {
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05", before, time.Local); err != nil {
+			return nil
+		} else {
+			def.CreatedBefore = t
+		}
+	}
+	if after := form.Get("createdAfter"); after != "" {
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05", after, time.Local); err != nil {
+			return nil
+		} else {
+			def.CreatedAfter = t
+		}
+	}
+	for _, v := range form["orderCoupon"] {
+		if v := strings.TrimSpace(v); v != "" {
+			def.OrderCoupons = append(def.OrderCoupons, strings.ToUpper(v))
+		}
+	}
+	for _, pid := range form["product"] {
+		if tx.FetchProduct(model.ProductID(pid)) == nil {
+			return nil
+		}
+		def.Products = append(def.Products, model.ProductID(pid))
+	}
+	def.PaymentTypes = form["paymentType"]
+	def.TicketClasses = form["ticketClass"]
+	for _, eid := range form["usedAtEvent"] {
+		if eid != "" && tx.FetchEvent(model.EventID(eid)) == nil {
+			return nil
+		}
+		def.UsedAtEvents = append(def.UsedAtEvents, model.EventID(eid))
+	}
+	if def.MinAmount, def.HasMinAmount, ok = parseReportAmount(form.Get("minAmount")); !ok {
+		return nil
+	}
+	if def.MaxAmount, def.HasMaxAmount, ok = parseReportAmount(form.Get("maxAmount")); !ok {
+		return nil
+	}
+	for _, v := range form["orderValid"] {
+		valid, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil
+		}
+		def.OrderValid = append(def.OrderValid, valid)
+	}
+	for _, v := range form["member"] {
+		if v == "any" {
+			def.AnyMember = true
+		} else if member, err := strconv.Atoi(v); err != nil || member < 0 {
+			return nil
+		} else {
+			def.Members = append(def.Members, member)
+		}
+	}
+	for _, pt := range form["productType"] {
+		switch pt := model.ProductType(pt); pt {
+		case model.ProdTicket, model.ProdRecording, model.ProdDonation, model.ProdSheetMusic, model.ProdAuctionItem,
+			model.ProdWardrobe, model.ProdOther, model.ProdRegistration, model.ProdFee:
+			def.ProductTypes = append(def.ProductTypes, pt)
+		default:
+			return nil
+		}
+	}
+	def.Series = form["series"]
+	def.Options = form["option"]
+	def.GuestName = strings.ToLower(strings.TrimSpace(form.Get("guestName")))
+	return def
+}
+
+// parseReportAmount parses a line amount limit, given in dollars (possibly
+// negative), returning it in cents.  has is false if no limit is given, and ok
+// is false if the limit is invalid.
+func parseReportAmount(s string) (amount int, has, ok bool) {
+	var (
+		negative bool
+		f        float64
+		err      error
+	)
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		negative, s = true, s[1:]
+	}
+	if s = strings.TrimPrefix(s, "$"); s == "" {
+		return 0, false, !negative
+	}
+	if f, err = strconv.ParseFloat(s, 64); err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false, false
+	}
+	if amount = int(math.Round(f * 100)); negative {
+		amount = -amount
+	}
+	return amount, true, true
+}
+
+// WriteReportCSV writes the lines of the report results in CSV format.
+func WriteReportCSV(w io.Writer, result *model.ReportResults) {
+	var cw = csv.NewWriter(w)
+
+	cw.Write([]string{"Order", "Time", "Name", "Email", "Quantity", "Product", "Used At", "Source", "Payment Type",
+		"Amount", "Deductible"})
+	for _, rl := range result.Lines {
+		cw.Write([]string{
+			strconv.Itoa(int(rl.OrderID)), rl.OrderTime.Format("2006-01-02 15:04:05"), rl.Name, rl.Email,
+			strconv.Itoa(rl.Quantity), rl.Product, string(rl.UsedAtEvent), string(rl.OrderSource), rl.PaymentType,
+			fmt.Sprintf("%.2f", rl.Amount), fmt.Sprintf("%.2f", rl.Deductible),
+		})
+	}
+	cw.Flush()
+}
+
+// scheduledReportData is the data passed to the scheduled report templates.
+type scheduledReportData struct {
+	Report   *model.SavedReport
+	Result   *model.ReportResults
+	When     string
+	Products []*model.ReportProductCount
+	Attached bool
+}
+
+// EmitScheduledReport queues a scheduled delivery of a saved report to its
+// recipients.  The message gives a summary of the results, and has the
+// matching report lines attached as a CSV file (if the report criteria select
+// lines).  The results must include all of the matching lines, as returned by
+// RunFullReport.  It is delivered once the
+// transaction is committed and SendQueuedEmail is called.  It returns the
+// queued message, or nil if there is an error (which is logged).
+func EmitScheduledReport(tx db.Tx, sr *model.SavedReport, result *model.ReportResults, now time.Time) *model.Email {
+	var (
+		buf      bytes.Buffer
+		out      bytes.Buffer
+		csvbuf   bytes.Buffer
+		data     = scheduledReportData{Report: sr, Result: result, When: now.Format("Monday, January 2, 2006 at 3:04pm")}
+		subject  string
+		htmlBody string
+		textBody string
+		bodyType string
+		body     []byte
+		xw       *multipart.Writer
+		part     io.Writer
+		hdr      textproto.MIMEHeader
+		emailTo  []string
+		email    *model.Email
+		err      error
+	)
+	if len(sr.Recipients) == 0 {
+		return nil
+	}
+	data.Products = append(data.Products, result.Products...)
+	sort.Slice(data.Products, func(i, j int) bool { return data.Products[i].Name < data.Products[j].Name })
+	data.Attached = len(result.Lines) != 0
+	if err = scheduledReportHTMLTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render saved report %d: %s", sr.ID, err)
+		return nil
+	}
+	htmlBody = out.String()
+	out.Reset()
+	if err = scheduledReportTextTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render saved report %d: %s", sr.ID, err)
+		return nil
+	}
+	textBody = out.String()
+	bodyType, body = htmlEmailBody(htmlBody, textBody, nil)
+	subject = fmt.Sprintf("Schola Cantorum report: %s", sr.Name)
+
+	fmt.Fprint(&buf, "From: Schola Cantorum <admin@scholacantorum.org>\r\n")
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(sr.Recipients, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	if !data.Attached {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", bodyType)
+		buf.Write(body)
+	} else {
+		WriteReportCSV(&csvbuf, result)
+		xw = multipart.NewWriter(&buf)
+		fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", xw.Boundary())
+		hdr = make(textproto.MIMEHeader)
+		hdr.Set("Content-Type", bodyType)
+		part, _ = xw.CreatePart(hdr)
+		part.Write(body)
+		hdr = make(textproto.MIMEHeader)
+		hdr.Set("Content-Type", "text/csv; charset=UTF-8")
+		hdr.Set("Content-Transfer-Encoding", "base64")
+		hdr.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%d-%s.csv"`, sr.ID, now.Format("20060102")))
+		part, _ = xw.CreatePart(hdr)
+		writeBase64(part, csvbuf.Bytes())
+		xw.Close()
+	}
+
+	if config.Get("mode") == "development" {
+		emailTo = []string{"admin@scholacantorum.org"}
+	} else {
+		emailTo = sr.Recipients
+	}
+	email = &model.Email{
+		From:    "admin@scholacantorum.org",
+		To:      emailTo,
+		Subject: subject,
+		Message: buf.Bytes(),
+	}
+	QueueEmail(tx, email)
+	return email
+}
+
+var scheduledReportHTMLTemplate = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+<p>Here is the {{ with .Report.Schedule }}{{ . }} {{ end }}report <b>{{ .Report.Name }}</b>, as of {{ .When }}.</p>
+<table style="border-collapse:collapse">
+<tr><td>Orders</td><td style="text-align:right;padding-left:24px">{{ .Result.OrderCount }}</td></tr>
+<tr><td>Items</td><td style="text-align:right;padding-left:24px">{{ .Result.ItemCount }}</td></tr>
+<tr><td>Amount</td><td style="text-align:right;padding-left:24px">${{ printf "%.2f" .Result.TotalAmount }}</td></tr>
+{{- if .Result.TotalFees }}
+<tr><td>Covered fees</td><td style="text-align:right;padding-left:24px">${{ printf "%.2f" .Result.TotalFees }}</td></tr>
+{{- end }}
+{{- if .Result.TotalDeductible }}
+<tr><td>Tax deductible</td><td style="text-align:right;padding-left:24px">${{ printf "%.2f" .Result.TotalDeductible }}</td></tr>
+{{- end }}
+</table>
+{{- if .Products }}
+<p>Items by product:</p>
+<table style="border-collapse:collapse">
+{{- range .Products }}
+<tr><td>{{ .Name }}</td><td style="text-align:right;padding-left:24px">{{ .Count }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .Attached }}
+<p>The matching orders are attached.</p>
+{{- end }}
+</div></body></html>
+`))
+
+var scheduledReportTextTemplate = texttemplate.Must(texttemplate.New("").Parse(`Here is the {{ with .Report.Schedule }}{{ . }} {{ end }}report "{{ .Report.Name }}", as of {{ .When }}.
+
+Orders: {{ .Result.OrderCount }}
+Items:  {{ .Result.ItemCount }}
+Amount: ${{ printf "%.2f" .Result.TotalAmount }}
+{{- if .Result.TotalFees }}
+Covered fees: ${{ printf "%.2f" .Result.TotalFees }}
+{{- end }}
+{{- if .Result.TotalDeductible }}
+Tax deductible: ${{ printf "%.2f" .Result.TotalDeductible }}
+{{- end }}
+{{- if .Products }}
+
+Items by product:
+{{- range .Products }}
+  {{ .Name }}: {{ .Count }}
+{{- end }}
+{{- end }}
+{{- if .Attached }}
+
+The matching orders are attached.
+{{- end }}
+`))
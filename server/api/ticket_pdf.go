@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/model"
+)
+
+// A TicketGroup is a group of unused tickets on an order, all for the same
+// product and all valid at the same event.  If Event is nil, the tickets are
+// not yet assigned to an event, and are valid at any of the product's events.
+type TicketGroup struct {
+	Event   *model.Event
+	Product *model.Product
+	Count   int
+}
+
+// TicketPDF returns a printable PDF rendering of the tickets on an order.  If
+// perEvent is true, there is one page for each event at which the tickets can
+// be used; otherwise, all of the tickets are on a single page.  Each page
+// carries the order's QR code, so any one of them will be accepted at the door.
+// TicketPDF returns nil if the order has no unused tickets.
+func TicketPDF(order *model.Order, perEvent bool) (pdfbytes []byte, err error) {
+	var (
+		groups []*TicketGroup
+		pages  [][]*TicketGroup
+		pdf    *gofpdf.Fpdf
+		qr     []byte
+		logo   []byte
+		buf    bytes.Buffer
+	)
+	if groups = OrderTicketGroups(order); len(groups) == 0 {
+		return nil, nil
+	}
+	if perEvent {
+		for i, g := range groups {
+			if i == 0 || g.Event == nil || groups[i-1].Event == nil || g.Event.ID != groups[i-1].Event.ID {
+				pages = append(pages, nil)
+			}
+			pages[len(pages)-1] = append(pages[len(pages)-1], g)
+		}
+	} else {
+		pages = [][]*TicketGroup{groups}
+	}
+	if qr, err = OrderQRCode(order); err != nil {
+		return nil, err
+	}
+	if logo, err = base64.StdEncoding.DecodeString(string(bytes.ReplaceAll(mailLogo, []byte("\n"), nil))); err != nil {
+		return nil, err
+	}
+	pdf = gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetTitle(fmt.Sprintf("Schola Cantorum Order #%d", order.ID), true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: "GIF"}, bytes.NewReader(logo))
+	pdf.RegisterImageOptionsReader("qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	for _, page := range pages {
+		emitTicketPage(pdf, order, page)
+	}
+	if err = pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OrderTicketGroups returns the unused tickets on the order, grouped by product
+// and event.  The groups are sorted by event start time, with unassigned
+// tickets at the end.
+func OrderTicketGroups(order *model.Order) (groups []*TicketGroup) {
+	var (
+		byKey = make(map[string]*TicketGroup)
+	)
+	for _, ol := range order.Lines {
+		for _, t := range ol.Tickets {
+			var key string
+
+			if !t.Used.IsZero() {
+				continue
+			}
+			if t.Event != nil {
+				key = string(t.Event.ID) + "\000" + string(ol.Product.ID)
+			} else {
+				key = "\000" + string(ol.Product.ID)
+			}
+			if g := byKey[key]; g != nil {
+				g.Count++
+			} else {
+				g = &TicketGroup{Event: t.Event, Product: ol.Product, Count: 1}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Event == nil || groups[j].Event == nil {
+			return groups[j].Event == nil && groups[i].Event != nil
+		}
+		if !groups[i].Event.Start.Equal(groups[j].Event.Start) {
+			return groups[i].Event.Start.Before(groups[j].Event.Start)
+		}
+		return groups[i].Event.ID < groups[j].Event.ID
+	})
+	return groups
+}
+
+// emitTicketPage adds a page to the PDF showing the specified ticket groups.
+func emitTicketPage(pdf *gofpdf.Fpdf, order *model.Order, groups []*TicketGroup) {
+	const (
+		qrSize     = 50.0
+		lineHeight = 6.0
+	)
+	var (
+		tr        = pdf.UnicodeTranslatorFromDescriptor("")
+		lastEvent *model.Event
+		y         float64
+	)
+	pdf.AddPage()
+	pdf.ImageOptions("logo", 20, 20, 120, 0, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.ImageOptions("qr", 216-20-qrSize, 16, qrSize, qrSize, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.SetY(48)
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 8, fmt.Sprintf("Order #%d", order.ID), "", 1, "L", false, 0, "")
+	if order.Name != "" {
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.CellFormat(0, lineHeight, tr(order.Name), "", 1, "L", false, 0, "")
+	}
+	if y = pdf.GetY(); y < 16+qrSize+4 {
+		y = 16 + qrSize + 4
+	}
+	pdf.SetY(y + 4)
+	for _, g := range groups {
+		// Show the event information when it changes.
+		if g.Event == nil || lastEvent == nil || g.Event.ID != lastEvent.ID {
+			pdf.Ln(4)
+			emitTicketEvent(pdf, tr, g)
+		}
+		lastEvent = g.Event
+		// Show the tickets.
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.SetX(30)
+		pdf.CellFormat(0, lineHeight, tr(fmt.Sprintf("%d × %s (%s)",
+			g.Count, TicketClassName(g.Product), g.Product.Name)), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "I", 10)
+	pdf.MultiCell(0, 5, tr(fmt.Sprintf(
+		"Please bring this page, or the QR code from your receipt email, to the event.  "+
+			"Each ticket admits one person.  To check the status of your tickets, visit %s/ticket/%s.",
+		config.Get("ordersURL"), order.Token)), "", "L", false)
+}
+
+// emitTicketEvent adds the description of an event (or, for unassigned
+// tickets, the list of events at which they are valid) to the PDF.
+func emitTicketEvent(pdf *gofpdf.Fpdf, tr func(string) string, g *TicketGroup) {
+	const lineHeight = 6.0
+
+	if g.Event != nil {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 7, tr(g.Event.Name), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.CellFormat(0, lineHeight, g.Event.Start.Format("Monday, January 2, 2006 at 3:04pm"), "", 1, "L", false, 0, "")
+		if g.Event.Venue != "" {
+			pdf.CellFormat(0, lineHeight, tr(g.Event.Venue), "", 1, "L", false, 0, "")
+		}
+		return
+	}
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 7, tr(g.Product.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, lineHeight, "Valid at any of:", "", 1, "L", false, 0, "")
+	for _, pe := range g.Product.Events {
+		var desc = fmt.Sprintf("%s, %s", pe.Event.Name, pe.Event.Start.Format("January 2, 2006 at 3:04pm"))
+		if pe.Event.Venue != "" {
+			desc += ", " + pe.Event.Venue
+		}
+		pdf.SetX(30)
+		pdf.CellFormat(0, lineHeight, tr(desc), "", 1, "L", false, 0, "")
+	}
+}
+
+// TicketClassName returns the name of the ticket class for the product, as it
+// should be shown to a customer.
+func TicketClassName(p *model.Product) string {
+	if p.TicketClass == "" {
+		return "General Admission"
+	}
+	return p.TicketClass
+}
+
+// OrderQRCode returns the QR code for an order, as a PNG image.  The QR code
+// encodes the URL of the order's ticket page, which is what the ticket
+// scanners look for.
+func OrderQRCode(order *model.Order) ([]byte, error) {
+	return qrcode.Encode(fmt.Sprintf("%s/ticket/%s", config.Get("ordersURL"), order.Token), qrcode.Highest, 200)
+}
+
+// writeBase64 writes data to w in base64 encoding, broken into lines of 76
+// characters as required for MIME.
+func writeBase64(w io.Writer, data []byte) {
+	var enc = base64.StdEncoding.EncodeToString(data)
+
+	for len(enc) > 76 {
+		io.WriteString(w, enc[:76]+"\r\n")
+		enc = enc[76:]
+	}
+	io.WriteString(w, enc+"\r\n")
+}
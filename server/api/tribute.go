@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	texttemplate "text/template"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// tributeData is the data passed to the tribute notice templates.
+type tributeData struct {
+	Order *model.Order
+	Line  *model.OrderLine
+	Donor string
+	InWay string // "in honor of" or "in memory of"
+}
+
+// EmitTributeNotices queues a notice of each tribute gift on the order to the
+// person designated to be notified of it.  The notices are delivered once the
+// transaction is committed and SendQueuedEmail is called.  It returns the
+// number of notices queued.  Errors are logged.
+func EmitTributeNotices(tx db.Tx, order *model.Order) (count int) {
+	for _, ol := range order.Lines {
+		if ol.Tribute == model.TributeNone || ol.NotifyEmail == "" {
+			continue
+		}
+		if emitTributeNotice(tx, order, ol) {
+			count++
+		}
+	}
+	return count
+}
+
+// emitTributeNotice queues the notice of a single tribute gift.
+func emitTributeNotice(tx db.Tx, order *model.Order, ol *model.OrderLine) bool {
+	var (
+		buf      bytes.Buffer
+		out      bytes.Buffer
+		data     tributeData
+		subject  string
+		htmlBody string
+		textBody string
+		bodyType string
+		body     []byte
+		emailTo  []string
+		err      error
+	)
+	data.Order, data.Line, data.Donor = order, ol, order.Name
+	if data.Donor == "" {
+		data.Donor = "a friend of Schola Cantorum"
+	}
+	if ol.Tribute == model.TributeMemory {
+		data.InWay = "in memory of"
+	} else {
+		data.InWay = "in honor of"
+	}
+	subject = fmt.Sprintf("A gift to Schola Cantorum %s %s", data.InWay, ol.Honoree)
+	if err = tributeHTMLTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render tribute notice for order %d: %s", order.ID, err)
+		return false
+	}
+	htmlBody = out.String()
+	out.Reset()
+	if err = tributeTextTemplate.Execute(&out, &data); err != nil {
+		log.Printf("ERROR: can't render tribute notice for order %d: %s", order.ID, err)
+		return false
+	}
+	textBody = out.String()
+	bodyType, body = htmlEmailBody(htmlBody, textBody, nil)
+
+	fmt.Fprint(&buf, "From: Schola Cantorum <admin@scholacantorum.org>\r\n")
+	if ol.NotifyName != "" {
+		fmt.Fprintf(&buf, "To: %s <%s>\r\n", mime.QEncoding.Encode("UTF-8", ol.NotifyName), ol.NotifyEmail)
+	} else {
+		fmt.Fprintf(&buf, "To: %s\r\n", ol.NotifyEmail)
+	}
+	fmt.Fprint(&buf, "Bcc: admin@scholacantorum.org\r\n")
+	fmt.Fprint(&buf, "Reply-To: info@scholacantorum.org\r\n")
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", bodyType)
+	buf.Write(body)
+
+	emailTo = []string{"admin@scholacantorum.org"}
+	if config.Get("mode") != "development" {
+		emailTo = append(emailTo, ol.NotifyEmail)
+	}
+	QueueEmail(tx, &model.Email{
+		Order:   order.ID,
+		From:    "admin@scholacantorum.org",
+		To:      emailTo,
+		Subject: subject,
+		Message: buf.Bytes(),
+	})
+	return true
+}
+
+var tributeHTMLTemplate = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html><body style="margin:0"><div style="width:600px;margin:0 auto"><div style="margin-bottom:24px">
+<img src="cid:SCHOLA_LOGO" alt="[Schola Cantorum]" style="border-width:0"></div>
+<p>Dear {{ or .Line.NotifyName "Friend of Schola Cantorum" }},</p>
+<p>We are honored to let you know that {{ .Donor }} has made a gift to Schola Cantorum{{ if .Line.Fund }}, for our {{ .Line.Fund }},{{ end }} {{ .InWay }} {{ .Line.Honoree }}.</p>
+<p>Schola Cantorum is grateful for this generous tribute, which helps us to share the joy of choral music with our community.</p>
+<p>Sincerely yours,<br>Schola Cantorum</p>
+<p>Web: <a href="https://scholacantorum.org">scholacantorum.org</a><br>
+Email: <a href="mailto:info@scholacantorum.org">info@scholacantorum.org</a><br>
+Phone: (650) 254-1700</p></div></body></html>
+`))
+
+var tributeTextTemplate = texttemplate.Must(texttemplate.New("").Parse(`Dear {{ or .Line.NotifyName "Friend of Schola Cantorum" }},
+
+We are honored to let you know that {{ .Donor }} has made a gift to Schola Cantorum{{ if .Line.Fund }}, for our {{ .Line.Fund }},{{ end }} {{ .InWay }} {{ .Line.Honoree }}.
+
+Schola Cantorum is grateful for this generous tribute, which helps us to share the joy of choral music with our community.
+
+Sincerely yours,
+Schola Cantorum
+
+Web: https://scholacantorum.org
+Email: info@scholacantorum.org
+Phone: (650) 254-1700
+`))
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rothskeller/json"
@@ -75,3 +76,25 @@ func NewToken() string {
 	tval := rand.Intn(1000000000000)
 	return fmt.Sprintf("%04d-%04d-%04d", tval/100000000, tval/10000%10000, tval%10000)
 }
+
+// LastNameFirst rearranges a customer name so that it sorts by last name:
+// "John Q. Smith, Jr." becomes "Smith, John Q., Jr.".
+func LastNameFirst(name string) string {
+	var comma, space int
+	var suffix string
+
+	name = strings.TrimSpace(name)
+	if comma = strings.LastIndexByte(name, ','); comma >= 0 {
+		name, suffix = strings.TrimSpace(name[:comma]), strings.TrimSpace(name[comma+1:])
+	}
+	if space = strings.LastIndexByte(name, ' '); space >= 0 {
+		if suffix != "" {
+			return name[space+1:] + ", " + name[:space] + ", " + suffix
+		}
+		return name[space+1:] + ", " + name[:space]
+	}
+	if suffix != "" {
+		return name + ", " + suffix
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// zipCountyFile is the ZIP-COUNTY crosswalk file published quarterly by HUD
+// (https://www.huduser.gov/portal/datasets/usps_crosswalk.html), saved as CSV
+// in the working directory.  Its columns include ZIP, COUNTY (the 5-digit FIPS
+// code), and RES_RATIO (the fraction of the zip code's residential addresses
+// that are in the county).  Replace it with each new release; it is not
+// maintained by hand.
+const zipCountyFile = "zip-county.csv"
+
+// countyNameFile is the national county file published by the Census Bureau
+// (https://www.census.gov/library/reference/code-lists/ansi.html), saved in the
+// working directory.  It is pipe-delimited, and its columns include STATEFP,
+// COUNTYFP, and COUNTYNAME.  It gives the names of the counties in
+// zipCountyFile.
+const countyNameFile = "county-names.txt"
+
+// zipCounties maps 5-digit zip codes to the names of the counties they are in.
+// It is loaded from zipCountyFile and countyNameFile on first use.  A zip code
+// that spans more than one county is assigned to the county with the largest
+// share of its residential addresses; ties (e.g., zip codes with no residential
+// addresses) go to the county with the largest share of all of its addresses,
+// and then to the lower FIPS code.  If zipCountyFile can't be read, zipCounties
+// is empty, so that reports show no counties rather than failing; if
+// countyNameFile can't be read, the counties are identified by FIPS code.
+var zipCounties map[string]string
+
+// ZipCounty returns the county containing the specified zip code (5- or
+// 9-digit), or an empty string if it isn't in the crosswalk (or there is no
+// crosswalk).
+func ZipCounty(zip string) string {
+	if zipCounties == nil {
+		loadZipCounties()
+	}
+	if len(zip) > 5 {
+		zip = zip[:5]
+	}
+	return zipCounties[zip]
+}
+
+// loadZipCounties loads zipCounties from zipCountyFile and countyNameFile.
+func loadZipCounties() {
+	type share struct {
+		county string
+		res    float64
+		tot    float64
+	}
+	var (
+		names = make(map[string]string)
+		best  = make(map[string]share)
+		err   error
+	)
+	zipCounties = make(map[string]string)
+	err = readDataFile(countyNameFile, '|', []string{"STATEFP", "COUNTYFP", "COUNTYNAME"}, func(f []string) {
+		names[padDigits(f[0], 2)+padDigits(f[1], 3)] = f[2]
+	})
+	if err != nil {
+		log.Printf("ERROR: %s; counties will be shown by FIPS code", err)
+	}
+	err = readDataFile(zipCountyFile, ',', []string{"ZIP", "COUNTY", "RES_RATIO", "TOT_RATIO"}, func(f []string) {
+		var s = share{county: padDigits(f[1], 5)}
+
+		f[0] = padDigits(f[0], 5)
+		s.res, _ = strconv.ParseFloat(f[2], 64)
+		s.tot, _ = strconv.ParseFloat(f[3], 64)
+		if b, ok := best[f[0]]; ok {
+			if s.res < b.res || (s.res == b.res && (s.tot < b.tot || (s.tot == b.tot && s.county > b.county))) {
+				return
+			}
+		}
+		best[f[0]] = s
+	})
+	if err != nil {
+		log.Printf("ERROR: %s; no counties will be shown", err)
+		return
+	}
+	for zip, s := range best {
+		if name := names[s.county]; name != "" {
+			zipCounties[zip] = strings.TrimSuffix(name, " County")
+		} else {
+			zipCounties[zip] = s.county
+		}
+	}
+}
+
+// padDigits restores the leading zeros of a numeric code of the specified
+// length, which are lost if the data file has been through a spreadsheet.
+func padDigits(code string, length int) string {
+	if len(code) < length {
+		return strings.Repeat("0", length-len(code)) + code
+	}
+	return code
+}
+
+// readDataFile reads the named data file, with the specified field delimiter,
+// and calls fn with the values of the specified columns (found by name in the
+// header line) for each subsequent line.  It returns an error if the file can't
+// be read or lacks any of the columns.
+func readDataFile(name string, comma rune, columns []string, fn func([]string)) (err error) {
+	var (
+		fh     *os.File
+		cr     *csv.Reader
+		header []string
+		cols   = make([]int, len(columns))
+		values = make([]string, len(columns))
+	)
+	if fh, err = os.Open(name); err != nil {
+		return fmt.Errorf("can't read %s: %s", name, err)
+	}
+	defer fh.Close()
+	cr = csv.NewReader(fh)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	if header, err = cr.Read(); err != nil {
+		return fmt.Errorf("can't parse %s: %s", name, err)
+	}
+COLUMNS:
+	for i, column := range columns {
+		for j, h := range header {
+			if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")), column) {
+				cols[i] = j
+				continue COLUMNS
+			}
+		}
+		return fmt.Errorf("can't parse %s: no %s column", name, column)
+	}
+	for {
+		var record []string
+		if record, err = cr.Read(); err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can't parse %s: %s", name, err)
+		}
+		for i, c := range cols {
+			if c < len(record) {
+				values[i] = strings.TrimSpace(record[c])
+			} else {
+				values[i] = ""
+			}
+		}
+		fn(values)
+	}
+}
@@ -0,0 +1,74 @@
+// donor-statements sends the year-end donation acknowledgement statements for
+// the specified calendar year.  Each donor with an email address is emailed a
+// PDF statement, unless one was already sent.  A mail-merge CSV file for the
+// donors without email addresses is written to standard output.  With the -n
+// flag, no email is sent; only the CSV file is written.
+//
+// usage: donor-statements [-n] year
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"strconv"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+)
+
+func main() {
+	var (
+		args    = os.Args[1:]
+		csvOnly bool
+		year    int
+		logfile *os.File
+		tx      db.Tx
+		list    []*api.DonorStatement
+		count   int
+		err     error
+	)
+	if len(args) != 0 && args[0] == "-n" {
+		csvOnly = true
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: donor-statements [-n] year\n")
+		os.Exit(2)
+	}
+	if year, err = strconv.Atoi(args[0]); err != nil || year < 2000 {
+		fmt.Fprintf(os.Stderr, "usage: donor-statements [-n] year\n")
+		os.Exit(2)
+	}
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("donor-statements")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	db.Open("orders.db")
+	tx = db.Begin()
+	list = api.DonorStatements(tx, year)
+	if !csvOnly {
+		count = api.SendDonorStatements(tx, list, year)
+	}
+	tx.Commit()
+	if count != 0 {
+		log.Printf("queued %d donor statements for %d", count, year)
+		api.SendQueuedEmail()
+	}
+	api.WriteDonorStatementsCSV(os.Stdout, list, year)
+}
@@ -0,0 +1,84 @@
+// make-test-pass-cert generates a self-signed certificate and private keys for
+// testing wallet pass generation without real Apple and Google credentials.
+// It prints the corresponding config.json entries to standard output; merge
+// them into the config.json of a test server.  Passes signed this way will not
+// be accepted by real devices, but their structure and signatures can be
+// checked, e.g. with
+//
+//     unzip tickets.pkpass manifest.json signature
+//     openssl smime -verify -binary -inform DER -in signature \
+//         -content manifest.json -certfile cert.pem -noverify
+//
+// usage: make-test-pass-cert [passTypeID]
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+func main() {
+	var (
+		passTypeID = "pass.org.scholacantorum.test"
+		passKey    *rsa.PrivateKey
+		googleKey  *rsa.PrivateKey
+		template   *x509.Certificate
+		der        []byte
+		out        []byte
+		err        error
+	)
+	switch len(os.Args) {
+	case 1:
+		break
+	case 2:
+		passTypeID = os.Args[1]
+	default:
+		fmt.Fprintf(os.Stderr, "usage: make-test-pass-cert [passTypeID]\n")
+		os.Exit(2)
+	}
+	if passKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if googleKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	// This mimics the subject of a real Apple pass type certificate.
+	template = &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().Unix()),
+		Subject: pkix.Name{
+			CommonName:         "Pass Type ID: " + passTypeID,
+			OrganizationalUnit: []string{"TESTTEAMID"},
+			Organization:       []string{"Schola Cantorum (TEST)"},
+			Country:            []string{"US"},
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().AddDate(1, 0, 0),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if der, err = x509.CreateCertificate(rand.Reader, template, template, &passKey.PublicKey, passKey); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	out, _ = json.MarshalIndent(map[string]string{
+		"applePassTypeID":        passTypeID,
+		"appleTeamID":            "TESTTEAMID",
+		"applePassCert":          string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		"applePassKey":           string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(passKey)})),
+		"googleWalletIssuerID":   "3388000000000000000",
+		"googleWalletEmail":      "wallet-test@example.iam.gserviceaccount.com",
+		"googleWalletPrivateKey": string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(googleKey)})),
+	}, "", "    ")
+	fmt.Println(string(out))
+}
@@ -0,0 +1,109 @@
+// match-customers links each valid order that isn't yet linked to a customer
+// record to the customer that placed it, creating customer records as needed.
+// See api.MatchCustomers for how orders are matched.  It should be run
+// periodically (e.g., nightly from cron); the first run links all existing
+// orders.
+//
+// Card fingerprints are recorded only on payments made since customer matching
+// was added, so orders paid earlier can't be matched by card.  With -cards,
+// match-customers first looks up (from Stripe) the fingerprints of the cards
+// used for the unlinked orders' card payments, and records them on the
+// payments.  This should be done on the first run; it's slow, since it makes a
+// Stripe request for each payment, and payments whose charges Stripe can't
+// find are still left without fingerprints.
+//
+// usage: match-customers [-cards]
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+	"scholacantorum.org/orders/stripe"
+)
+
+func main() {
+	var (
+		logfile *os.File
+		tx      db.Tx
+		cards   bool
+		linked  int
+		created int
+		err     error
+	)
+	switch {
+	case len(os.Args) == 1:
+		break
+	case len(os.Args) == 2 && os.Args[1] == "-cards":
+		cards = true
+	default:
+		fmt.Fprintf(os.Stderr, "usage: match-customers [-cards]\n")
+		os.Exit(2)
+	}
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("match-customers")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	db.Open("orders.db")
+	if cards {
+		backfillCards()
+	}
+	tx = db.Begin()
+	linked, created = api.MatchCustomers(tx)
+	if err = tx.Commit(); err != nil {
+		log.Printf("ERROR: %s", err)
+		os.Exit(1)
+	}
+	if linked != 0 {
+		log.Printf("linked %d orders to customers, %d of them new", linked, created)
+	}
+}
+
+// backfillCards records the fingerprints of the cards used for the card
+// payments of unlinked orders that don't have them.  The Stripe lookups are
+// done outside of any database transaction, so that they don't hold up the
+// web site.
+func backfillCards() {
+	var (
+		tx      db.Tx
+		charges map[model.PaymentID]string
+		found   = make(map[model.PaymentID]string)
+		err     error
+	)
+	tx = db.Begin()
+	charges = tx.FetchUnfingerprintedCharges()
+	tx.Rollback()
+	for pid, charge := range charges {
+		if card := stripe.GetCardFingerprint(charge); card != "" {
+			found[pid] = card
+		}
+	}
+	tx = db.Begin()
+	for pid, card := range found {
+		tx.SetPaymentCard(pid, card)
+	}
+	if err = tx.Commit(); err != nil {
+		log.Printf("ERROR: %s", err)
+		os.Exit(1)
+	}
+	log.Printf("recorded card fingerprints for %d of %d payments", len(found), len(charges))
+}
@@ -0,0 +1,74 @@
+// rebuild-report-summary recomputes the report summary tables (see
+// db/report_summary.go) from all of the orders in the database.  It must be run
+// once after the tables are created, and can be run at any time thereafter to
+// repair them.  With -check, it instead compares the tables against the orders,
+// printing each difference, and exits with status 1 if there are any.
+//
+// usage: rebuild-report-summary [-check]
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"scholacantorum.org/orders/db"
+)
+
+func main() {
+	var (
+		logfile  *os.File
+		tx       db.Tx
+		check    bool
+		problems []string
+		err      error
+	)
+	switch {
+	case len(os.Args) == 1:
+		break
+	case len(os.Args) == 2 && os.Args[1] == "-check":
+		check = true
+	default:
+		fmt.Fprintf(os.Stderr, "usage: rebuild-report-summary [-check]\n")
+		os.Exit(2)
+	}
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("rebuild-report-summary")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	db.Open("orders.db")
+	tx = db.Begin()
+	if check {
+		problems = tx.CheckReportSummary()
+		tx.Rollback()
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) != 0 {
+			log.Printf("report summary has %d discrepancies", len(problems))
+			os.Exit(1)
+		}
+		return
+	}
+	tx.RebuildReportSummary()
+	if err = tx.Commit(); err != nil {
+		log.Printf("ERROR: %s", err)
+		os.Exit(1)
+	}
+	log.Printf("rebuilt report summary")
+}
@@ -0,0 +1,446 @@
+// reconcile-stripe matches the Stripe balance transactions in an exported file
+// against the payments recorded in the orders database.  It reports charges
+// and refunds with no valid order, card payments on valid orders with no
+// Stripe transaction, and amount mismatches, and summarizes the charges,
+// refunds, fees, and net deposit of each payout.  It does not contact Stripe,
+// so the file must contain all of the balance transactions for the period of
+// interest.  The file can be a CSV export from the Stripe dashboard (balance
+// transactions or itemized payout reconciliation) or the JSON returned by the
+// Stripe balance transactions API.  The report is written to standard output.
+// It must be run in the data directory.
+//
+// usage: reconcile-stripe export-file
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// txn is a Stripe balance transaction.
+type txn struct {
+	id          string
+	kind        string // type or reporting category
+	source      string // charge, refund, or payout ID
+	amount      int    // cents
+	fee         int    // cents
+	net         int    // cents
+	created     time.Time
+	availableOn time.Time
+	payout      string // ID of the payout that included it, if known
+}
+
+// payout is the summary of a single payout.
+type payout struct {
+	id       string
+	date     time.Time
+	amount   int  // amount deposited, in cents
+	known    bool // whether the payout itself is in the export
+	charges  int
+	gross    int
+	refunds  int
+	refunded int
+	fees     int
+	other    int // adjustments, disputes, etc.
+	net      int
+}
+
+func main() {
+	var (
+		fh       *os.File
+		txns     []*txn
+		tx       db.Tx
+		payments []db.StripePayment
+		err      error
+	)
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: reconcile-stripe export-file\n")
+		os.Exit(2)
+	}
+	if fh, err = os.Open(os.Args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if strings.HasSuffix(strings.ToLower(os.Args[1]), ".json") {
+		txns, err = readJSON(fh)
+	} else {
+		txns, err = readCSV(fh)
+	}
+	fh.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+	if len(txns) == 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: %s: no balance transactions\n", os.Args[1])
+		os.Exit(1)
+	}
+	if _, err = os.Stat("orders.db"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	db.Open("orders.db")
+	tx = db.Begin()
+	payments = tx.FetchStripePayments()
+	tx.Commit()
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].created.Before(txns[j].created) })
+	reportPayouts(txns)
+	reportProblems(txns, payments)
+}
+
+// reportPayouts prints the summary of each payout.
+func reportPayouts(txns []*txn) {
+	var (
+		payouts  []*payout
+		byID     = make(map[string]*payout)
+		pending  = &payout{id: "(not yet paid out)"}
+		assigned = make(map[*txn]*payout)
+	)
+	// Find the payouts.
+	for _, t := range txns {
+		if isPayout(t) {
+			var p = &payout{id: t.source, date: t.created, amount: -t.amount, known: true}
+
+			if p.id == "" {
+				p.id = t.id
+			}
+			if !t.availableOn.IsZero() {
+				p.date = t.availableOn
+			}
+			byID[p.id] = p
+			payouts = append(payouts, p)
+		}
+	}
+	sort.SliceStable(payouts, func(i, j int) bool { return payouts[i].date.Before(payouts[j].date) })
+	// Assign each other transaction to its payout.  If the export doesn't
+	// say which payout that is, assume it's the first one on or after the
+	// date the funds became available.
+	for _, t := range txns {
+		if isPayout(t) {
+			continue
+		}
+		if t.payout != "" {
+			if byID[t.payout] == nil {
+				// The payout itself isn't in the export, but
+				// we can still summarize what went into it.
+				byID[t.payout] = &payout{id: t.payout}
+				payouts = append(payouts, byID[t.payout])
+			}
+			assigned[t] = byID[t.payout]
+			continue
+		}
+		avail := t.availableOn
+		if avail.IsZero() {
+			avail = t.created
+		}
+		assigned[t] = pending
+		for _, p := range payouts {
+			if !p.date.Before(avail) {
+				assigned[t] = p
+				break
+			}
+		}
+	}
+	for _, t := range txns {
+		var p = assigned[t]
+
+		if p == nil {
+			continue
+		}
+		switch {
+		case isCharge(t):
+			p.charges++
+			p.gross += t.amount
+		case isRefund(t):
+			p.refunds++
+			p.refunded += t.amount
+		case t.kind == "stripe_fee" || t.kind == "fee":
+			p.fees -= t.amount
+		default:
+			p.other += t.amount
+		}
+		p.fees += t.fee
+		p.net += t.net
+	}
+	if pending.net != 0 || pending.charges != 0 || pending.refunds != 0 {
+		payouts = append(payouts, pending)
+	}
+	fmt.Println("PAYOUTS")
+	fmt.Printf("%-30s %-10s %16s %16s %10s %10s %10s %10s\n", "Payout", "Date", "Charges", "Refunds", "Fees", "Other", "Net",
+		"Deposited")
+	for _, p := range payouts {
+		var date, deposited, flag string
+
+		if p.known {
+			date = p.date.In(time.Local).Format("2006-01-02")
+			deposited = dollars(p.amount)
+			if p.net != p.amount {
+				flag = "  MISMATCH"
+			}
+		}
+		fmt.Printf("%-30s %-10s %4d %11s %4d %11s %10s %10s %10s %10s%s\n", p.id, date, p.charges, dollars(p.gross),
+			p.refunds, dollars(p.refunded), dollars(-p.fees), dollars(p.other), dollars(p.net), deposited, flag)
+	}
+	fmt.Println()
+}
+
+// reportProblems prints the discrepancies between the Stripe transactions and
+// the payments in the database.
+func reportProblems(txns []*txn, payments []db.StripePayment) {
+	var (
+		byStripe = make(map[string]*db.StripePayment)
+		seen     = make(map[string]bool)
+		first    time.Time
+		last     time.Time
+		problems int
+	)
+	for i := range payments {
+		if payments[i].Stripe != "" {
+			byStripe[payments[i].Stripe] = &payments[i]
+		}
+	}
+	fmt.Println("PROBLEMS")
+	for _, t := range txns {
+		var p *db.StripePayment
+
+		if !isCharge(t) && !isRefund(t) {
+			continue
+		}
+		if first.IsZero() || t.created.Before(first) {
+			first = t.created
+		}
+		if t.created.After(last) {
+			last = t.created
+		}
+		seen[t.source] = true
+		switch p = byStripe[t.source]; {
+		case p == nil:
+			fmt.Printf("%s %s %s: no order\n", t.source, t.created.In(time.Local).Format("2006-01-02"), dollars(t.amount))
+			problems++
+		case !p.Valid:
+			fmt.Printf("%s %s %s: order %d is not valid\n", t.source, t.created.In(time.Local).Format("2006-01-02"),
+				dollars(t.amount), p.Order)
+			problems++
+		case p.Amount != t.amount:
+			fmt.Printf("%s %s order %d: amount %s in Stripe, %s in order\n", t.source,
+				t.created.In(time.Local).Format("2006-01-02"), p.Order, dollars(t.amount), dollars(p.Amount))
+			problems++
+		}
+	}
+	// Only the payments made during the period covered by the export are
+	// checked, with a day's leeway for time zone differences.
+	first, last = first.Add(-24*time.Hour), last.Add(24*time.Hour)
+	for _, p := range payments {
+		if !p.Valid || p.Amount == 0 || seen[p.Stripe] || p.Created.Before(first) || p.Created.After(last) {
+			continue
+		}
+		if p.Type != model.PaymentCard && p.Type != model.PaymentCardPresent {
+			continue
+		}
+		if p.Stripe == "" {
+			fmt.Printf("order %d %s %s: card payment with no Stripe ID\n", p.Order,
+				p.Created.In(time.Local).Format("2006-01-02"), dollars(p.Amount))
+		} else {
+			fmt.Printf("order %d %s %s: no Stripe transaction for %s\n", p.Order,
+				p.Created.In(time.Local).Format("2006-01-02"), dollars(p.Amount), p.Stripe)
+		}
+		problems++
+	}
+	if problems == 0 {
+		fmt.Println("none")
+	}
+}
+
+// isPayout returns whether the transaction is a payout to our bank account.
+func isPayout(t *txn) bool {
+	return t.kind == "payout" || strings.HasPrefix(t.source, "po_")
+}
+
+// isCharge returns whether the transaction is a card charge.
+func isCharge(t *txn) bool {
+	return t.kind == "charge" || t.kind == "payment"
+}
+
+// isRefund returns whether the transaction is a refund of a card charge.
+func isRefund(t *txn) bool {
+	return t.kind == "refund" || t.kind == "payment_refund"
+}
+
+// dollars formats an amount in cents as dollars.
+func dollars(cents int) string {
+	if cents < 0 {
+		return fmt.Sprintf("-$%d.%02d", -cents/100, -cents%100)
+	}
+	return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+}
+
+// readJSON reads balance transactions in the form returned by the Stripe API:
+// a list object, or an array of balance transaction objects.  Amounts are in
+// cents and times are Unix timestamps.  A "payout" property, if present, gives
+// the payout that included the transaction.
+func readJSON(r io.Reader) (txns []*txn, err error) {
+	type jsonTxn struct {
+		ID          string          `json:"id"`
+		Type        string          `json:"type"`
+		Source      json.RawMessage `json:"source"`
+		Amount      int             `json:"amount"`
+		Fee         int             `json:"fee"`
+		Net         int             `json:"net"`
+		Created     int64           `json:"created"`
+		AvailableOn int64           `json:"available_on"`
+		Payout      string          `json:"payout"`
+	}
+	var (
+		data []byte
+		list struct {
+			Data []jsonTxn `json:"data"`
+		}
+	)
+	if data, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &list.Data); err != nil {
+		if err = json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+	}
+	for _, jt := range list.Data {
+		var t = txn{id: jt.ID, kind: jt.Type, amount: jt.Amount, fee: jt.Fee, net: jt.Net, payout: jt.Payout}
+
+		// The source is an ID, or an object if it was expanded.
+		if json.Unmarshal(jt.Source, &t.source) != nil {
+			var obj struct {
+				ID string `json:"id"`
+			}
+			json.Unmarshal(jt.Source, &obj)
+			t.source = obj.ID
+		}
+		t.created = time.Unix(jt.Created, 0)
+		if jt.AvailableOn != 0 {
+			t.availableOn = time.Unix(jt.AvailableOn, 0)
+		}
+		txns = append(txns, &t)
+	}
+	return txns, nil
+}
+
+// csvColumns maps the column headings of the various Stripe CSV exports to
+// the fields they contain.
+var csvColumns = map[string]string{
+	"id":                     "id",
+	"balance_transaction_id": "id",
+	"type":                   "kind",
+	"reporting_category":     "kind",
+	"source":                 "source",
+	"source_id":              "source",
+	"amount":                 "amount",
+	"gross":                  "amount",
+	"fee":                    "fee",
+	"net":                    "net",
+	"created":                "created",
+	"created (utc)":          "created",
+	"created_utc":            "created",
+	"available on (utc)":     "availableOn",
+	"available_on_utc":       "availableOn",
+	"transfer":               "payout",
+	"payout_id":              "payout",
+	"automatic_payout_id":    "payout",
+}
+
+// readCSV reads balance transactions from a Stripe dashboard CSV export.
+// Amounts are in dollars and times are in UTC.
+func readCSV(r io.Reader) (txns []*txn, err error) {
+	var (
+		cr      = csv.NewReader(r)
+		header  []string
+		columns = make(map[string]int)
+		record  []string
+	)
+	cr.FieldsPerRecord = -1
+	if header, err = cr.Read(); err != nil {
+		return nil, err
+	}
+	for i, h := range header {
+		if field := csvColumns[strings.ToLower(strings.TrimSpace(h))]; field != "" {
+			if _, ok := columns[field]; !ok {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"kind", "source", "amount", "net", "created"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no %s column", field)
+		}
+	}
+	get := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	for line := 2; ; line++ {
+		var t txn
+
+		if record, err = cr.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		t.id, t.kind, t.source, t.payout = get("id"), get("kind"), get("source"), get("payout")
+		if t.amount, err = parseDollars(get("amount")); err == nil {
+			if t.fee, err = parseDollars(get("fee")); err == nil {
+				t.net, err = parseDollars(get("net"))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if t.created, err = parseCSVTime(get("created")); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if s := get("availableOn"); s != "" {
+			if t.availableOn, err = parseCSVTime(s); err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+		}
+		txns = append(txns, &t)
+	}
+	return txns, nil
+}
+
+// parseDollars parses a dollar amount from a CSV export, returning cents.
+func parseDollars(s string) (int, error) {
+	var (
+		f   float64
+		err error
+	)
+	if s == "" {
+		return 0, nil
+	}
+	if f, err = strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64); err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return int(math.Round(f * 100)), nil
+}
+
+// parseCSVTime parses a UTC timestamp from a CSV export.
+func parseCSVTime(s string) (t time.Time, err error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05Z", "2006-01-02"} {
+		if t, err = time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("invalid time %q", s)
+}
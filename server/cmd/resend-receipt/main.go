@@ -1,6 +1,8 @@
-// resend-receipt resends the receipt for the order with the specified number.
+// resend-receipt resends the receipt for the order with the specified number,
+// by queuing it in the email outbox and starting delivery.  With the -n flag,
+// it prints the receipt message instead of queuing it.
 //
-// usage: resend-receipt order-number
+// usage: resend-receipt [-n] order-number
 
 package main
 
@@ -16,17 +18,24 @@ import (
 
 func main() {
 	var (
-		onum  int
-		tx    db.Tx
-		order *model.Order
-		err   error
+		args      = os.Args[1:]
+		printOnly bool
+		onum      int
+		tx        db.Tx
+		order     *model.Order
+		email     *model.Email
+		err       error
 	)
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "usage: resend-receipt order-number\n")
+	if len(args) != 0 && args[0] == "-n" {
+		printOnly = true
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: resend-receipt [-n] order-number\n")
 		os.Exit(2)
 	}
-	if onum, err = strconv.Atoi(os.Args[1]); err != nil || onum < 1 {
-		fmt.Fprintf(os.Stderr, "usage: resend-receipt order-number\n")
+	if onum, err = strconv.Atoi(args[0]); err != nil || onum < 1 {
+		fmt.Fprintf(os.Stderr, "usage: resend-receipt [-n] order-number\n")
 		os.Exit(2)
 	}
 	db.Open("orders.db")
@@ -39,5 +48,18 @@ func main() {
 		fmt.Fprintf(os.Stderr, "ERROR: order has no email\n")
 		os.Exit(1)
 	}
-	api.EmitReceipt(order, true)
+	if email = api.EmitReceipt(tx, order); email == nil {
+		fmt.Fprintf(os.Stderr, "ERROR: no receipt generated for order\n")
+		os.Exit(1)
+	}
+	if printOnly {
+		tx.Rollback()
+		os.Stdout.Write(email.Message)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	api.SendQueuedEmail()
 }
@@ -0,0 +1,138 @@
+// run-scheduled-reports delivers the saved reports whose schedules say they
+// are due.  Daily reports are delivered once each day, weekly reports once
+// each week (starting Monday), and event reports the morning after each event.
+// Each delivery is an email to the report's recipients, with a summary of the
+// results and the matching lines attached as CSV.  It should be run each
+// morning from cron.  If report IDs are given on the command line, those
+// reports are delivered immediately regardless of their schedules.
+//
+// usage: run-scheduled-reports [reportID...]
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+func main() {
+	var (
+		logfile *os.File
+		now     time.Time
+		tx      db.Tx
+		reports []*model.SavedReport
+		queued  int
+		err     error
+	)
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("run-scheduled-reports")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	db.Open("orders.db")
+	now = time.Now()
+	tx = db.Begin()
+	if len(os.Args) > 1 {
+		for _, arg := range os.Args[1:] {
+			var sr *model.SavedReport
+
+			if id, err := strconv.Atoi(arg); err == nil && id > 0 {
+				sr = tx.FetchSavedReport(model.SavedReportID(id))
+			}
+			if sr == nil {
+				fmt.Fprintf(os.Stderr, "usage: run-scheduled-reports [reportID...]\n")
+				os.Exit(2)
+			}
+			reports = append(reports, sr)
+		}
+	} else {
+		for _, sr := range tx.FetchSavedReports("") {
+			if reportDue(tx, sr, now) {
+				reports = append(reports, sr)
+			}
+		}
+	}
+	tx.Commit()
+	// Each report is run and queued in its own transaction, along with the
+	// record that it was run.
+	for _, sr := range reports {
+		var (
+			form   url.Values
+			def    *model.ReportDefinition
+			result *model.ReportResults
+		)
+		tx = db.Begin()
+		if form, err = url.ParseQuery(sr.Query); err == nil {
+			def = api.ParseReportDefinition(tx, form)
+		}
+		if def == nil {
+			// Can happen if a product or event in the definition
+			// has since been removed.
+			log.Printf("ERROR: saved report %d has an invalid definition", sr.ID)
+			tx.Rollback()
+			continue
+		}
+		result = tx.RunFullReport(def)
+		if api.EmitScheduledReport(tx, sr, result, now) == nil {
+			tx.Rollback()
+			continue // error already logged; retry on next run
+		}
+		sr.LastRun = now
+		tx.SaveSavedReport(sr)
+		tx.Commit()
+		log.Printf("queued saved report %d %q to %d recipients", sr.ID, sr.Name, len(sr.Recipients))
+		queued++
+	}
+	if queued != 0 {
+		api.SendQueuedEmail()
+	}
+}
+
+// reportDue returns whether the saved report is due for delivery.
+func reportDue(tx db.Tx, sr *model.SavedReport, now time.Time) bool {
+	var (
+		today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		since = sr.LastRun
+	)
+	if len(sr.Recipients) == 0 {
+		return false
+	}
+	switch sr.Schedule {
+	case model.ScheduleDaily:
+		return sr.LastRun.Before(today)
+	case model.ScheduleWeekly:
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return sr.LastRun.Before(monday)
+	case model.ScheduleEvent:
+		if since.IsZero() {
+			since = sr.Created
+		}
+		for _, event := range tx.FetchEventsStartingBetween(since, today) {
+			if event.Cancelled.IsZero() {
+				return true
+			}
+		}
+	}
+	return false
+}
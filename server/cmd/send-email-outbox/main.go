@@ -0,0 +1,111 @@
+// send-email-outbox delivers the messages waiting in the email outbox.  It is
+// started whenever a message is queued, and should also be run periodically
+// (e.g., every five minutes from cron) to retry failed deliveries.  After each
+// failure, the next attempt is delayed exponentially, starting at one minute;
+// after eight failures, the message is abandoned.  Deliveries are throttled so
+// that bulk mailings (e.g., messages to all ticket holders of an event) don't
+// trip our mail provider's rate limits.
+//
+// usage: send-email-outbox
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/mail"
+	"scholacantorum.org/orders/model"
+)
+
+const (
+	// maxAttempts is the number of delivery attempts made for a message
+	// before it is abandoned.
+	maxAttempts = 8
+
+	// firstRetry is the delay before the first retry of a failed delivery.
+	// Each subsequent retry doubles it.
+	firstRetry = time.Minute
+
+	// lease is the time for which a message is reserved for a delivery
+	// attempt, so that concurrent runs of this command don't deliver it
+	// twice.  If the attempt doesn't finish in that time (e.g. because this
+	// command crashed), the message becomes due again.
+	lease = 10 * time.Minute
+
+	// throttle is the minimum interval between deliveries.
+	throttle = time.Second
+
+	// batch is the number of messages claimed at a time.  It must be small
+	// enough that the batch can be delivered, at the throttled rate, well
+	// within the lease period.
+	batch = 60
+)
+
+func main() {
+	var (
+		logfile *os.File
+		tx      db.Tx
+		emails  []*model.Email
+		sender  mail.Sender
+		last    time.Time
+		err     error
+	)
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("send-email-outbox")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	db.Open("orders.db")
+	sender = mail.NewSender(true)
+	// Keep delivering until there's nothing left that's due, so that we
+	// pick up messages queued while we were running.
+	for {
+		tx = db.Begin()
+		emails = tx.ClaimDueEmails(time.Now(), lease, batch)
+		tx.Commit()
+		if len(emails) == 0 {
+			break
+		}
+		for _, email := range emails {
+			if wait := throttle - time.Since(last); wait > 0 {
+				time.Sleep(wait)
+			}
+			err = sender.Send(email.From, email.To, email.Message)
+			last = time.Now()
+			tx = db.Begin()
+			if err == nil {
+				email.Sent = time.Now()
+				email.NextAttempt = time.Time{}
+			} else {
+				email.Attempts++
+				email.Error = err.Error()
+				if email.Attempts < maxAttempts {
+					email.NextAttempt = time.Now().Add(firstRetry << (email.Attempts - 1))
+					log.Printf("ERROR: can't send email %d (attempt %d): %s", email.ID, email.Attempts, err)
+				} else {
+					email.NextAttempt = time.Time{}
+					log.Printf("ERROR: can't send email %d, giving up: %s", email.ID, err)
+				}
+			}
+			tx.SaveEmail(email)
+			tx.Commit()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// send-reminders sends reminder emails for upcoming events.  For each event
+// starting within the specified window (default 48 hours), it sends a reminder
+// to each valid order holding tickets usable at the event, giving the venue,
+// parking information, the number of tickets, and the QR code for entry.  Each
+// order is reminded only once about each event, and customers who have opted
+// out of reminders are skipped.  It should be run periodically (e.g., hourly
+// from cron).
+//
+// usage: send-reminders [window]
+//
+// The window is a duration such as "48h".
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// defaultWindow is the default time before an event at which its reminders
+// are sent.
+const defaultWindow = 48 * time.Hour
+
+func main() {
+	var (
+		logfile *os.File
+		window  = defaultWindow
+		now     time.Time
+		tx      db.Tx
+		events  []*model.Event
+		queued  int
+		err     error
+	)
+	switch len(os.Args) {
+	case 1:
+		break
+	case 2:
+		if window, err = time.ParseDuration(os.Args[1]); err != nil || window <= 0 {
+			fmt.Fprintf(os.Stderr, "usage: send-reminders [window]\n")
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "usage: send-reminders [window]\n")
+		os.Exit(2)
+	}
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("send-reminders")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	db.Open("orders.db")
+	now = time.Now()
+	tx = db.Begin()
+	events = tx.FetchEventsStartingBetween(now, now.Add(window))
+	tx.Commit()
+	// Each event's reminders are queued in a single transaction, along with
+	// the records that they were sent.
+	for _, event := range events {
+		var count int
+
+		if !event.Cancelled.IsZero() {
+			continue // cancellation notices were sent instead
+		}
+		tx = db.Begin()
+		for _, de := range tx.FetchEventDoorList(event) {
+			var order *model.Order
+
+			if de.Email == "" || tx.FetchReminderSent(event, de.ID) || tx.FetchReminderOptOut(de.Email) {
+				continue
+			}
+			order = tx.FetchOrder(de.ID)
+			if api.EmitReminder(tx, order, event, de.Tickets) == nil {
+				continue // error already logged; retry on next run
+			}
+			tx.SaveReminderSent(event, order.ID, now)
+			count++
+		}
+		tx.Commit()
+		if count != 0 {
+			log.Printf("queued %d reminders for event %s", count, event.ID)
+		}
+		queued += count
+	}
+	if queued != 0 {
+		api.SendQueuedEmail()
+	}
+}
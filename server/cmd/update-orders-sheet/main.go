@@ -127,8 +127,8 @@ func main() {
 		if existing, ok := lines[ol.Product.ID]; ok {
 			// Multiple lines with the same product will be
 			// coalesced.
-			if existing.Price != ol.Price {
-				log.Fatal("can't handle order with multiple lines for same product but different prices")
+			if existing.Price != ol.Price || existing.FMV != ol.FMV {
+				log.Fatal("can't handle order with multiple lines for same product but different prices or values")
 			}
 			clone := *existing
 			clone.Quantity += ol.Quantity
@@ -175,7 +175,7 @@ func main() {
 			continue
 		}
 		// We found a row for this product.  Update the quantity, unit
-		// price, and total on it.
+		// price, total, and deductible amount on it.
 		requests = append(requests, &sheets.Request{UpdateCells: &sheets.UpdateCellsRequest{
 			Start: &sheets.GridCoordinate{
 				SheetId:     sheetnum,
@@ -211,6 +211,17 @@ func main() {
 				UserEnteredValue: &sheets.ExtendedValue{NumberValue: float64(line.Quantity * line.Price / 100)},
 			}}}},
 		}})
+		requests = append(requests, &sheets.Request{UpdateCells: &sheets.UpdateCellsRequest{
+			Start: &sheets.GridCoordinate{
+				SheetId:     sheetnum,
+				RowIndex:    int64(row),
+				ColumnIndex: 15, // P, zero based
+			},
+			Fields: "userEnteredValue",
+			Rows: []*sheets.RowData{{Values: []*sheets.CellData{{
+				UserEnteredValue: &sheets.ExtendedValue{NumberValue: float64(line.Deductible()) / 100.0},
+			}}}},
+		}})
 		delete(lines, line.Product.ID)
 	}
 
@@ -253,6 +264,8 @@ func main() {
 				UserEnteredValue: &sheets.ExtendedValue{NumberValue: float64(line.Price / 100)},
 			}, {
 				UserEnteredValue: &sheets.ExtendedValue{NumberValue: float64(line.Quantity * line.Price / 100)},
+			}, {
+				UserEnteredValue: &sheets.ExtendedValue{NumberValue: float64(line.Deductible()) / 100.0},
 			}}}},
 		}})
 	}
@@ -0,0 +1,83 @@
+// update-wallet-passes updates the Apple Wallet and Google Wallet passes for an
+// order, after a change to its tickets.  Apple Wallet devices registered for
+// the pass are sent a push notification, after which they fetch the new pass
+// from our web service.  The Google Wallet pass is replaced through the Google
+// Wallet API, after which Google updates it on the phones that hold it.
+//
+// usage: update-wallet-passes orderID
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+	"scholacantorum.org/orders/wallet"
+)
+
+func main() {
+	var (
+		logfile *os.File
+		orderID model.OrderID
+		order   *model.Order
+		tx      db.Tx
+		tokens  []string
+		err     error
+	)
+	// Initialize the logger.  Since we expect it to exist, this will also
+	// confirm that we're in the data directory.
+	if logfile, err = os.OpenFile("server.log", os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.SetOutput(logfile)
+	log.SetFlags(log.Ldate | log.Ltime)
+	log.SetPrefix("update-wallet-passes")
+	// Log any panics.
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			log.Printf("PANIC: %v", panicked)
+			fmt.Fprint(logfile, string(debug.Stack()))
+			os.Exit(1)
+		}
+	}()
+	// Get the order ID from the command line.
+	if len(os.Args) == 2 {
+		if id, err := strconv.Atoi(os.Args[1]); err == nil && id > 0 {
+			orderID = model.OrderID(id)
+		}
+	}
+	if orderID == 0 {
+		log.Fatalf("usage: update-wallet-passes orderID")
+	}
+	// Open the database and read the order.  Record that its pass has
+	// changed, and get the list of Apple Wallet devices to notify.
+	db.Open("orders.db")
+	tx = db.Begin()
+	if order = tx.FetchOrder(orderID); order == nil {
+		log.Fatalf("order %d does not exist", orderID)
+	}
+	if tx.FetchWalletPassUpdated(orderID).IsZero() {
+		tx.Commit()
+		return // no pass has been issued for this order
+	}
+	tx.TouchWalletPass(orderID, time.Now())
+	tokens = tx.FetchWalletPushTokens(orderID)
+	tx.Commit()
+	// Notify the Apple Wallet devices.
+	if wallet.AppleEnabled() {
+		if err = wallet.PushAppleUpdate(tokens); err != nil {
+			log.Printf("ERROR: can't notify Apple Wallet devices for order %d: %s", orderID, err)
+		}
+	}
+	// Update the Google Wallet pass.
+	if err = wallet.UpdateGooglePass(order); err != nil {
+		log.Printf("ERROR: can't update Google Wallet pass for order %d: %s", orderID, err)
+	}
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+
+	"scholacantorum.org/orders/model"
+)
+
+// SaveEventCancellation saves the terms of an event cancellation.
+func (tx Tx) SaveEventCancellation(ec *model.EventCancellation) {
+	panicOnExecError(tx.tx.Exec(`
+INSERT OR REPLACE INTO event_cancellation (event, created, username, rescheduled, donation, message) VALUES (?,?,?,?,?,?)`,
+		ec.Event, Time(ec.Created), ec.Username, IDStr(ec.Rescheduled), IDStr(ec.Donation), ec.Message))
+}
+
+// FetchEventCancellation returns the terms of the cancellation of the
+// specified event.  It returns nil if the event has not been cancelled.
+func (tx Tx) FetchEventCancellation(eventID model.EventID) (ec *model.EventCancellation) {
+	var err error
+
+	ec = new(model.EventCancellation)
+	switch err = tx.tx.QueryRow(`
+SELECT event, created, username, rescheduled, donation, message FROM event_cancellation WHERE event=?`, eventID).Scan(
+		&ec.Event, (*Time)(&ec.Created), &ec.Username, (*IDStr)(&ec.Rescheduled), (*IDStr)(&ec.Donation), &ec.Message); err {
+	case nil:
+		return ec
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// cancelledOrderColumns is the list of columns in the cancelled_order table.
+var cancelledOrderColumns = `event, orderid, tickets, amount, outcome, resolved, resolved_by, refund_error`
+
+// scanCancelledOrder scans a cancelled_order table row.
+func scanCancelledOrder(scanner interface{ Scan(...interface{}) error }, co *model.CancelledOrder) error {
+	return scanner.Scan(&co.Event, &co.Order, &co.Tickets, &co.Amount, &co.Outcome, (*Time)(&co.Resolved), &co.ResolvedBy, &co.RefundError)
+}
+
+// SaveCancelledOrder saves the record of an order's tickets to a cancelled
+// event.
+func (tx Tx) SaveCancelledOrder(co *model.CancelledOrder) {
+	panicOnExecError(tx.tx.Exec(`INSERT OR REPLACE INTO cancelled_order (`+cancelledOrderColumns+`) VALUES (?,?,?,?,?,?,?,?)`,
+		co.Event, co.Order, co.Tickets, co.Amount, co.Outcome, Time(co.Resolved), co.ResolvedBy, co.RefundError))
+}
+
+// FetchCancelledOrder returns the record of the specified order's tickets to
+// the specified cancelled event.  It returns nil if there is no such record.
+func (tx Tx) FetchCancelledOrder(eventID model.EventID, orderID model.OrderID) (co *model.CancelledOrder) {
+	var err error
+
+	co = new(model.CancelledOrder)
+	switch err = scanCancelledOrder(tx.tx.QueryRow(
+		`SELECT `+cancelledOrderColumns+` FROM cancelled_order WHERE event=? AND orderid=?`, eventID, orderID), co); err {
+	case nil:
+		return co
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchCancelledOrders returns the records of all orders holding tickets to
+// the specified cancelled event, in order number order.
+func (tx Tx) FetchCancelledOrders(eventID model.EventID) (list []*model.CancelledOrder) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT `+cancelledOrderColumns+` FROM cancelled_order WHERE event=? ORDER BY orderid`, eventID)
+	panicOnError(err)
+	for rows.Next() {
+		var co model.CancelledOrder
+		panicOnError(scanCancelledOrder(rows, &co))
+		list = append(list, &co)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchOrderCancellations returns the records of the specified order's tickets
+// to cancelled events.
+func (tx Tx) FetchOrderCancellations(orderID model.OrderID) (list []*model.CancelledOrder) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT `+cancelledOrderColumns+` FROM cancelled_order WHERE orderid=? ORDER BY event`, orderID)
+	panicOnError(err)
+	for rows.Next() {
+		var co model.CancelledOrder
+		panicOnError(scanCancelledOrder(rows, &co))
+		list = append(list, &co)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchEventTicketOrders returns the IDs of the valid orders holding unused
+// tickets dedicated to the specified event.
+func (tx Tx) FetchEventTicketOrders(event *model.Event) (list []model.OrderID) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT DISTINCT o.id FROM ordert o, order_line ol, ticket t
+WHERE t.event=? AND t.used='' AND t.order_line=ol.id AND ol.orderid=o.id AND o.valid
+ORDER BY o.id`, event.ID)
+	panicOnError(err)
+	for rows.Next() {
+		var oid model.OrderID
+		panicOnError(rows.Scan(&oid))
+		list = append(list, oid)
+	}
+	panicOnError(rows.Err())
+	return list
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"database/sql"
+
+	"scholacantorum.org/orders/model"
+)
+
+// customerColumns is the list of columns in the customer table.
+var customerColumns = `id, name, email, address, city, state, zip, phone, created`
+
+// scanCustomer scans a customer table row.
+func scanCustomer(scanner interface{ Scan(...interface{}) error }, c *model.Customer) error {
+	return scanner.Scan(&c.ID, &c.Name, &c.Email, &c.Address, &c.City, &c.State, &c.Zip, &c.Phone, (*Time)(&c.Created))
+}
+
+// SaveCustomer saves a customer to the database.
+func (tx Tx) SaveCustomer(c *model.Customer) {
+	var (
+		res sql.Result
+		err error
+	)
+	res, err = tx.tx.Exec(`INSERT OR REPLACE INTO customer (`+customerColumns+`) VALUES (?,?,?,?,?,?,?,?,?)`,
+		ID(c.ID), c.Name, c.Email, c.Address, c.City, c.State, c.Zip, c.Phone, Time(c.Created))
+	panicOnError(err)
+	if c.ID == 0 {
+		c.ID = model.CustomerID(lastInsertID(res))
+	}
+}
+
+// FetchCustomer returns the customer with the specified ID.  It returns nil if
+// no such customer exists.
+func (tx Tx) FetchCustomer(id model.CustomerID) (c *model.Customer) {
+	c = new(model.Customer)
+	switch err := scanCustomer(tx.tx.QueryRow(`SELECT `+customerColumns+` FROM customer WHERE id=?`, id), c); err {
+	case nil:
+		return c
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// SearchCustomers returns the customers whose names or email addresses contain
+// the specified text, in order by name, up to the specified limit.
+func (tx Tx) SearchCustomers(text string, limit int) (list []*model.Customer) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT `+customerColumns+` FROM customer WHERE instr(lower(name), lower(?1)) OR instr(lower(email), lower(?1)) ORDER BY name, email, id LIMIT ?2`,
+		text, limit)
+	panicOnError(err)
+	for rows.Next() {
+		var c model.Customer
+		panicOnError(scanCustomer(rows, &c))
+		list = append(list, &c)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchCustomerOrders returns the IDs of the orders linked to the specified
+// customer, in chronological order.
+func (tx Tx) FetchCustomerOrders(id model.CustomerID) (list []model.OrderID) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT id FROM ordert WHERE customer_id=? ORDER BY created, id`, id)
+	panicOnError(err)
+	for rows.Next() {
+		var oid model.OrderID
+		panicOnError(rows.Scan(&oid))
+		list = append(list, oid)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchUnlinkedOrders returns the IDs of the valid orders that aren't linked to
+// any customer, in order number order.
+func (tx Tx) FetchUnlinkedOrders() (list []model.OrderID) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT id FROM ordert WHERE valid AND customer_id IS NULL ORDER BY id`)
+	panicOnError(err)
+	for rows.Next() {
+		var oid model.OrderID
+		panicOnError(rows.Scan(&oid))
+		list = append(list, oid)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchUnfingerprintedCharges returns the Stripe charge IDs of the card
+// payments, on valid orders not linked to any customer, for which no card
+// fingerprint was recorded.  The map is keyed by payment ID.
+func (tx Tx) FetchUnfingerprintedCharges() (charges map[model.PaymentID]string) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	charges = make(map[model.PaymentID]string)
+	rows, err = tx.tx.Query(`
+SELECT p.id, p.stripe FROM payment p, ordert o
+WHERE o.id=p.orderid AND o.valid AND o.customer_id IS NULL AND p.type IN (?,?) AND p.amount>0
+AND p.stripe LIKE 'ch\_%' ESCAPE '\' AND p.card=''`, model.PaymentCard, model.PaymentCardPresent)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			pid    model.PaymentID
+			charge string
+		)
+		panicOnError(rows.Scan(&pid, &charge))
+		charges[pid] = charge
+	}
+	panicOnError(rows.Err())
+	return charges
+}
+
+// SetPaymentCard records the card fingerprint of a payment.
+func (tx Tx) SetPaymentCard(pid model.PaymentID, card string) {
+	panicOnExecError(tx.tx.Exec(`UPDATE payment SET card=? WHERE id=?`, card, pid))
+}
+
+// CustomerKey is the type returned by FetchCustomerKeys (q.v.).
+type CustomerKey struct {
+	Customer model.CustomerID
+	Name     string
+	Email    string
+	Address  string
+	Zip      string
+	Card     string
+}
+
+// FetchCustomerKeys returns the identifying details of every order linked to a
+// customer: one entry for each card used to pay for it, or one entry with an
+// empty Card if none is known.
+func (tx Tx) FetchCustomerKeys() (list []CustomerKey) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT o.customer_id, o.name, o.email, o.address, o.zip, COALESCE(p.card, '')
+FROM ordert o LEFT JOIN payment p ON p.orderid=o.id AND p.card!=''
+WHERE o.customer_id IS NOT NULL`)
+	panicOnError(err)
+	for rows.Next() {
+		var ck CustomerKey
+		panicOnError(rows.Scan(&ck.Customer, &ck.Name, &ck.Email, &ck.Address, &ck.Zip, &ck.Card))
+		list = append(list, ck)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// MergeCustomers merges the duplicate customer into the other one: its orders
+// are linked to the other customer, and it is deleted.
+func (tx Tx) MergeCustomers(into, duplicate *model.Customer) {
+	panicOnExecError(tx.tx.Exec(`UPDATE ordert SET customer_id=? WHERE customer_id=?`, into.ID, duplicate.ID))
+	panicOnNoRows(tx.tx.Exec(`DELETE FROM customer WHERE id=?`, duplicate.ID))
+}
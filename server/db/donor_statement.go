@@ -0,0 +1,49 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// FetchDonationOrders returns the IDs of the valid orders created in the
+// specified time range that include donations, gala registrations, or auction
+// items, in order number order.
+func (tx Tx) FetchDonationOrders(from, to time.Time) (list []model.OrderID) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT DISTINCT o.id FROM ordert o, order_line ol, product p
+WHERE o.valid AND o.created>=? AND o.created<? AND ol.orderid=o.id AND p.id=ol.product AND p.type IN (?,?,?)
+ORDER BY o.id`, Time(from), Time(to), model.ProdDonation, model.ProdRegistration, model.ProdAuctionItem)
+	panicOnError(err)
+	for rows.Next() {
+		var oid model.OrderID
+		panicOnError(rows.Scan(&oid))
+		list = append(list, oid)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchDonorStatementSent returns the time at which the donor's statement for
+// the specified year was sent, or a zero time if it hasn't been.
+func (tx Tx) FetchDonorStatementSent(year int, donor string) (sent time.Time) {
+	switch err := tx.tx.QueryRow(`SELECT sent FROM donor_statement_sent WHERE year=? AND donor=?`, year, donor).Scan(
+		(*Time)(&sent)); err {
+	case nil, sql.ErrNoRows:
+		return sent
+	default:
+		panic(err)
+	}
+}
+
+// SaveDonorStatementSent records that the donor's statement for the specified
+// year was sent.
+func (tx Tx) SaveDonorStatementSent(year int, donor string, sent time.Time, outbox model.EmailID) {
+	panicOnExecError(tx.tx.Exec(`INSERT OR REPLACE INTO donor_statement_sent (year, donor, sent, outbox) VALUES (?,?,?,?)`,
+		year, donor, Time(sent), ID(outbox)))
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// emailColumns is the list of columns in the email_outbox table.
+var emailColumns = `id, orderid, created, sender, recipients, subject, message, attempts, next_attempt, sent, error`
+
+// scanEmail scans an email_outbox table row.
+func scanEmail(scanner interface{ Scan(...interface{}) error }, e *model.Email) (err error) {
+	var recipients string
+
+	err = scanner.Scan(&e.ID, (*ID)(&e.Order), (*Time)(&e.Created), &e.From, &recipients, &e.Subject,
+		&e.Message, &e.Attempts, (*Time)(&e.NextAttempt), (*Time)(&e.Sent), &e.Error)
+	e.To = strings.Split(recipients, ",")
+	return err
+}
+
+// SaveEmail saves a message to the email outbox.
+func (tx Tx) SaveEmail(e *model.Email) {
+	var (
+		res sql.Result
+		err error
+	)
+	res, err = tx.tx.Exec(`INSERT OR REPLACE INTO email_outbox (`+emailColumns+`) VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		ID(e.ID), ID(e.Order), Time(e.Created), e.From, strings.Join(e.To, ","), e.Subject,
+		e.Message, e.Attempts, Time(e.NextAttempt), Time(e.Sent), e.Error)
+	panicOnError(err)
+	if e.ID == 0 {
+		e.ID = model.EmailID(lastInsertID(res))
+	}
+}
+
+// FetchOrderEmails returns the messages in the email outbox about the
+// specified order, in the order they were queued.
+func (tx Tx) FetchOrderEmails(orderID model.OrderID) (emails []*model.Email) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT `+emailColumns+` FROM email_outbox WHERE orderid=? ORDER BY id`, orderID)
+	panicOnError(err)
+	for rows.Next() {
+		var e model.Email
+		panicOnError(scanEmail(rows, &e))
+		emails = append(emails, &e)
+	}
+	panicOnError(rows.Err())
+	return emails
+}
+
+// ClaimDueEmails returns up to limit messages in the email outbox that are due
+// for a delivery attempt at the specified time.  It postpones their next
+// attempt to the end of the lease period, so that no other caller will claim
+// them while the delivery attempt is in progress.
+func (tx Tx) ClaimDueEmails(now time.Time, lease time.Duration, limit int) (emails []*model.Email) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT `+emailColumns+` FROM email_outbox WHERE next_attempt!='' AND next_attempt<=? ORDER BY id LIMIT ?`,
+		Time(now), limit)
+	panicOnError(err)
+	for rows.Next() {
+		var e model.Email
+		panicOnError(scanEmail(rows, &e))
+		emails = append(emails, &e)
+	}
+	panicOnError(rows.Err())
+	for _, e := range emails {
+		e.NextAttempt = now.Add(lease)
+		panicOnExecError(tx.tx.Exec(`UPDATE email_outbox SET next_attempt=? WHERE id=?`, Time(e.NextAttempt), e.ID))
+	}
+	return emails
+}
+
+// FetchEmail returns the message in the email outbox with the specified ID.
+// It returns nil if no such message exists.
+func (tx Tx) FetchEmail(id model.EmailID) (e *model.Email) {
+	e = new(model.Email)
+	switch err := scanEmail(tx.tx.QueryRow(`SELECT `+emailColumns+` FROM email_outbox WHERE id=?`, id), e); err {
+	case nil:
+		return e
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // eventColumns is the list of columns of the event table.
-var eventColumns = `id, members_id, name, series, start, capacity`
+var eventColumns = `id, members_id, name, series, start, capacity, venue, parking, cancelled`
 
 // scanEvent scans an event table row.
 func (tx Tx) scanEvent(scanner interface{ Scan(...interface{}) error }, e *model.Event) error {
@@ -17,7 +17,7 @@ func (tx Tx) scanEvent(scanner interface{ Scan(...interface{}) error }, e *model
 		membersID ID
 		err       error
 	)
-	err = scanner.Scan(&e.ID, &membersID, &e.Name, &e.Series, (*Time)(&e.Start), &e.Capacity)
+	err = scanner.Scan(&e.ID, &membersID, &e.Name, &e.Series, (*Time)(&e.Start), &e.Capacity, &e.Venue, &e.Parking, (*Time)(&e.Cancelled))
 	if err != nil {
 		return err
 	}
@@ -32,8 +32,8 @@ func (tx Tx) SaveEvent(e *model.Event) {
 	)
 	q.WriteString(`INSERT OR REPLACE INTO event (`)
 	q.WriteString(eventColumns)
-	q.WriteString(`) VALUES (?,?,?,?,?,?)`)
-	panicOnExecError(tx.tx.Exec(q.String(), IDStr(e.ID), ID(e.MembersID), e.Name, e.Series, Time(e.Start), e.Capacity))
+	q.WriteString(`) VALUES (?,?,?,?,?,?,?,?,?)`)
+	panicOnExecError(tx.tx.Exec(q.String(), IDStr(e.ID), ID(e.MembersID), e.Name, e.Series, Time(e.Start), e.Capacity, e.Venue, e.Parking, Time(e.Cancelled)))
 }
 
 // DeleteEvent deletes an event.
@@ -125,6 +125,28 @@ func (tx Tx) FetchFutureEvents() (events []*model.Event) {
 	return events
 }
 
+// FetchEventsStartingBetween returns a list of the events that start after
+// from and no later than to, in chronological order.
+func (tx Tx) FetchEventsStartingBetween(from, to time.Time) (events []*model.Event) {
+	var (
+		q    strings.Builder
+		rows *sql.Rows
+		err  error
+	)
+	q.WriteString(`SELECT `)
+	q.WriteString(eventColumns)
+	q.WriteString(` FROM event WHERE start > ? AND start <= ? ORDER BY start`)
+	rows, err = tx.tx.Query(q.String(), Time(from), Time(to))
+	panicOnError(err)
+	for rows.Next() {
+		var e model.Event
+		panicOnError(tx.scanEvent(rows, &e))
+		events = append(events, &e)
+	}
+	panicOnError(rows.Err())
+	return events
+}
+
 // FetchTicketCount returns the number of tickets allocated to the specified
 // event.  These could be either tickets that have been used at the event, or
 // tickets that are labeled for that event.
@@ -133,31 +155,208 @@ func (tx Tx) FetchTicketCount(event *model.Event) (count int) {
 	return count
 }
 
+// TicketSale is the type returned by FetchEventTicketSales (q.v.).
+type TicketSale struct {
+	Created time.Time // when the order was placed
+	Tickets int       // number of tickets to the event on the order line
+	Amount  int       // share of the order line price for those tickets, in cents
+}
+
+// FetchEventTicketSales returns the sales of tickets to the specified event,
+// one entry per order line, in no particular order.  Only valid orders are
+// included.  Tickets are counted toward the event if they are labeled for it
+// or were used at it; Flex Pass tickets not yet used are not counted.  The
+// amount of each sale is the order line price prorated across the tickets
+// issued for each unit.
+func (tx Tx) FetchEventTicketSales(event *model.Event) (list []TicketSale) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT o.created, ol.price, p.ticket_count, COUNT(*) FROM ordert o, order_line ol, product p, ticket t
+WHERE t.event=? AND t.order_line=ol.id AND ol.orderid=o.id AND ol.product=p.id AND o.valid
+GROUP BY ol.id`, event.ID)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			ts    TicketSale
+			price int
+			count int
+		)
+		panicOnError(rows.Scan((*Time)(&ts.Created), &price, &count, &ts.Tickets))
+		if count > 0 {
+			ts.Amount = price * ts.Tickets / count
+		}
+		list = append(list, ts)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
 // EventOrder is the type returned by FetchEventOrders (q.v.).
 type EventOrder struct {
-	ID   model.OrderID
-	Name string
+	ID    model.OrderID
+	Name  string
+	Email string
+	Phone string
 }
 
-// FetchEventOrders returns a list of order IDs and customer names for will call
-// searches.  The list is not sorted.  It contains those orders which are valid,
-// have a customer name, and have either tickets used at the target event or
-// unused tickets that could be used at it.
+// FetchEventOrders returns a list of order IDs and customer names, emails, and
+// phone numbers for will call searches.  The list is not sorted.  It contains
+// those orders which are valid, have a customer name or email, and have either
+// tickets used at the target event or unused tickets that could be used at it.
 func (tx Tx) FetchEventOrders(event *model.Event) (list []EventOrder) {
 	var (
 		rows *sql.Rows
 		err  error
 	)
 	rows, err = tx.tx.Query(`
-SELECT DISTINCT o.id, o.name FROM ordert o, order_line ol, product_event pe, ticket t
-WHERE pe.event=?1 AND pe.product=ol.product AND o.id=ol.orderid AND o.name != ''
+SELECT DISTINCT o.id, o.name, o.email, o.phone FROM ordert o, order_line ol, product_event pe, ticket t
+WHERE pe.event=?1 AND pe.product=ol.product AND o.id=ol.orderid AND (o.name != '' OR o.email != '')
 AND o.valid AND t.order_line=ol.id AND (t.used='' OR t.event=?1)`, event.ID)
 	panicOnError(err)
 	for rows.Next() {
 		var eo EventOrder
-		panicOnError(rows.Scan(&eo.ID, &eo.Name))
+		panicOnError(rows.Scan(&eo.ID, &eo.Name, &eo.Email, &eo.Phone))
 		list = append(list, eo)
 	}
 	panicOnError(rows.Err())
 	return list
 }
+
+// DoorListEntry is the type returned by FetchEventDoorList (q.v.).
+type DoorListEntry struct {
+	ID      model.OrderID
+	Name    string
+	Email   string
+	CNote   string
+	ONote   string
+	Tickets map[string]int // ticket class to count
+	Guests  []string
+}
+
+// FetchEventDoorList returns the list of orders expected at the specified
+// event, for printing a door list.  The list is not sorted.  It contains those
+// orders which are valid and have either tickets used at the target event or
+// unused tickets that could be used at it.  For each order, it gives the number
+// of such tickets in each ticket class, and the guest names on any of the
+// order's lines.
+func (tx Tx) FetchEventDoorList(event *model.Event) (list []*DoorListEntry) {
+	var (
+		rows *sql.Rows
+		byID = make(map[model.OrderID]*DoorListEntry)
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT o.id, o.name, o.email, o.cnote, o.onote, p.ticket_class, COUNT(*)
+FROM ordert o, order_line ol, product p, product_event pe, ticket t
+WHERE pe.event=?1 AND pe.product=ol.product AND p.id=ol.product AND o.id=ol.orderid
+AND o.valid AND t.order_line=ol.id AND (t.used='' OR t.event=?1)
+GROUP BY o.id, p.ticket_class`, event.ID)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			de     DoorListEntry
+			tclass string
+			count  int
+		)
+		panicOnError(rows.Scan(&de.ID, &de.Name, &de.Email, &de.CNote, &de.ONote, &tclass, &count))
+		if existing := byID[de.ID]; existing != nil {
+			existing.Tickets[tclass] += count
+			continue
+		}
+		de.Tickets = map[string]int{tclass: count}
+		byID[de.ID] = &de
+		list = append(list, &de)
+	}
+	panicOnError(rows.Err())
+	rows, err = tx.tx.Query(`
+SELECT orderid, guest_name FROM order_line WHERE guest_name!='' AND orderid IN (
+SELECT DISTINCT ol.orderid FROM order_line ol, product_event pe WHERE pe.event=? AND pe.product=ol.product)
+ORDER BY id`, event.ID)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			oid   model.OrderID
+			guest string
+		)
+		panicOnError(rows.Scan(&oid, &guest))
+		if de := byID[oid]; de != nil {
+			de.Guests = append(de.Guests, guest)
+		}
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchCompTicketCount returns the number of complimentary tickets to the
+// specified event that have already been given to the specified email address.
+func (tx Tx) FetchCompTicketCount(event *model.Event, email string) (count int) {
+	panicOnError(tx.tx.QueryRow(`
+SELECT COUNT(*) FROM ordert o, order_line ol, product_event pe, ticket t
+WHERE pe.event=? AND pe.product=ol.product AND o.id=ol.orderid AND o.valid
+AND o.coupon=? AND o.email=? COLLATE NOCASE AND t.order_line=ol.id`,
+		event.ID, model.CompCoupon, email).Scan(&count))
+	return count
+}
+
+// TicketValue is the type returned by FetchTicketValues (q.v.).
+type TicketValue struct {
+	Product model.ProductID
+	Event   model.EventID // event used at or labeled for; empty if neither
+	Used    time.Time     // zero if not used
+	Value   int           // prorated share of the order line price, in cents
+}
+
+// FetchTicketValues returns all of the tickets sold on valid orders placed
+// before the specified time, in no particular order.  The value of each ticket
+// is its share of the price of the order line on which it was sold; for
+// example, each ticket of a four-admission Flex Pass is valued at a quarter of
+// the pass price.
+func (tx Tx) FetchTicketValues(before time.Time) (list []TicketValue) {
+	var (
+		rows  *sql.Rows
+		start int // index in list of the first ticket of the current line
+		lid   model.OrderLineID
+		total int // total price of the current line
+		err   error
+	)
+	// Spread the line total across its tickets, with any odd cents going
+	// to the first ones.
+	prorate := func() {
+		var tickets = list[start:]
+
+		for i := range tickets {
+			tickets[i].Value = total / len(tickets)
+			if i < total%len(tickets) {
+				tickets[i].Value++
+			}
+		}
+	}
+	rows, err = tx.tx.Query(`
+SELECT ol.id, ol.product, ol.price*ol.quantity, t.event, t.used FROM ordert o, order_line ol, ticket t
+WHERE o.valid AND o.created<? AND ol.orderid=o.id AND t.order_line=ol.id
+ORDER BY ol.id, t.id`, Time(before))
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			tv     TicketValue
+			olid   model.OrderLineID
+			amount int
+		)
+		panicOnError(rows.Scan(&olid, &tv.Product, &amount, (*IDStr)(&tv.Event), (*Time)(&tv.Used)))
+		if olid != lid {
+			if lid != 0 {
+				prorate()
+			}
+			lid, start, total = olid, len(list), amount
+		}
+		list = append(list, tv)
+	}
+	panicOnError(rows.Err())
+	if lid != 0 {
+		prorate()
+	}
+	return list
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"scholacantorum.org/orders/model"
+)
+
+// eventMessageColumns is the list of columns in the event_message table.
+var eventMessageColumns = `id, event, created, username, subject, body, classes`
+
+// scanEventMessage scans an event_message table row.
+func scanEventMessage(scanner interface{ Scan(...interface{}) error }, em *model.EventMessage) (err error) {
+	var classes string
+
+	err = scanner.Scan(&em.ID, &em.Event, (*Time)(&em.Created), &em.Username, &em.Subject, &em.Body, &classes)
+	if classes != "" {
+		em.Classes = strings.Split(classes, ",")
+	}
+	return err
+}
+
+// SaveEventMessage saves an event message, along with its list of recipients.
+func (tx Tx) SaveEventMessage(em *model.EventMessage) {
+	var (
+		res sql.Result
+		err error
+	)
+	res, err = tx.tx.Exec(`INSERT OR REPLACE INTO event_message (`+eventMessageColumns+`) VALUES (?,?,?,?,?,?,?)`,
+		ID(em.ID), em.Event, Time(em.Created), em.Username, em.Subject, em.Body, strings.Join(em.Classes, ","))
+	panicOnError(err)
+	if em.ID == 0 {
+		em.ID = model.EventMessageID(lastInsertID(res))
+	}
+	panicOnExecError(tx.tx.Exec(`DELETE FROM event_message_recipient WHERE message=?`, em.ID))
+	for _, emr := range em.Recipients {
+		panicOnExecError(tx.tx.Exec(`INSERT INTO event_message_recipient (message, email, orderid, outbox) VALUES (?,?,?,?)`,
+			em.ID, emr.Email, emr.Order, emr.Outbox))
+	}
+}
+
+// FetchEventMessage returns the event message with the specified ID, along
+// with its list of recipients.  It returns nil if no such message exists.
+func (tx Tx) FetchEventMessage(id model.EventMessageID) (em *model.EventMessage) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	em = new(model.EventMessage)
+	switch err = scanEventMessage(tx.tx.QueryRow(`SELECT `+eventMessageColumns+` FROM event_message WHERE id=?`, id), em); err {
+	case nil:
+		break
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+	rows, err = tx.tx.Query(`SELECT email, orderid, outbox FROM event_message_recipient WHERE message=? ORDER BY email`, id)
+	panicOnError(err)
+	for rows.Next() {
+		var emr model.EventMessageRecipient
+		panicOnError(rows.Scan(&emr.Email, &emr.Order, &emr.Outbox))
+		em.Recipients = append(em.Recipients, &emr)
+	}
+	panicOnError(rows.Err())
+	return em
+}
+
+// FetchEventMessages returns the messages sent to the ticket holders of the
+// specified event, in the order they were sent.  Their Recipients lists are
+// not filled in.
+func (tx Tx) FetchEventMessages(eventID model.EventID) (list []*model.EventMessage) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT `+eventMessageColumns+` FROM event_message WHERE event=? ORDER BY id`, eventID)
+	panicOnError(err)
+	for rows.Next() {
+		var em model.EventMessage
+		panicOnError(scanEventMessage(rows, &em))
+		list = append(list, &em)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchEventMessageStatus returns the number of copies of the specified event
+// message that have been sent, are queued for delivery, and have failed.
+func (tx Tx) FetchEventMessageStatus(id model.EventMessageID) (sent, queued, failed int) {
+	panicOnError(tx.tx.QueryRow(`
+SELECT COALESCE(SUM(e.sent!=''),0), COALESCE(SUM(e.sent='' AND e.next_attempt!=''),0), COALESCE(SUM(e.sent='' AND e.next_attempt=''),0)
+FROM event_message_recipient r, email_outbox e WHERE r.message=? AND e.id=r.outbox`, id).Scan(&sent, &queued, &failed))
+	return sent, queued, failed
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// glAccountColumns is the list of columns in the gl_account table.
+var glAccountColumns = `id, kind, product_type, series, payment_type, account`
+
+// scanGLAccount scans a gl_account table row.
+func scanGLAccount(scanner interface{ Scan(...interface{}) error }, gla *model.GLAccount) error {
+	return scanner.Scan(&gla.ID, &gla.Kind, &gla.ProductType, &gla.Series, &gla.PaymentType, &gla.Account)
+}
+
+// SaveGLAccount saves a general ledger account mapping to the database.
+func (tx Tx) SaveGLAccount(gla *model.GLAccount) {
+	var (
+		res sql.Result
+		err error
+	)
+	res, err = tx.tx.Exec(`INSERT OR REPLACE INTO gl_account (`+glAccountColumns+`) VALUES (?,?,?,?,?,?)`,
+		ID(gla.ID), gla.Kind, gla.ProductType, gla.Series, gla.PaymentType, gla.Account)
+	panicOnError(err)
+	if gla.ID == 0 {
+		gla.ID = model.GLAccountID(lastInsertID(res))
+	}
+}
+
+// DeleteGLAccount deletes a general ledger account mapping from the database.
+func (tx Tx) DeleteGLAccount(gla *model.GLAccount) {
+	panicOnNoRows(tx.tx.Exec(`DELETE FROM gl_account WHERE id=?`, gla.ID))
+}
+
+// FetchGLAccount returns the general ledger account mapping with the
+// specified ID.  It returns nil if no such mapping exists.
+func (tx Tx) FetchGLAccount(id model.GLAccountID) (gla *model.GLAccount) {
+	gla = new(model.GLAccount)
+	switch err := scanGLAccount(tx.tx.QueryRow(`SELECT `+glAccountColumns+` FROM gl_account WHERE id=?`, id), gla); err {
+	case nil:
+		return gla
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchGLAccounts returns all of the general ledger account mappings, in order
+// by kind and then by the values they match.
+func (tx Tx) FetchGLAccounts() (list []*model.GLAccount) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT ` + glAccountColumns + ` FROM gl_account ORDER BY kind, product_type, series, payment_type`)
+	panicOnError(err)
+	for rows.Next() {
+		var gla model.GLAccount
+		panicOnError(scanGLAccount(rows, &gla))
+		list = append(list, &gla)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// FetchExportedJournal returns the journal lines, keyed by account, of each
+// day whose journal entry has been exported, keyed by day (midnight local
+// time).  Days exported with no lines have empty maps.
+func (tx Tx) FetchExportedJournal() (days map[time.Time]map[string]int) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	days = make(map[time.Time]map[string]int)
+	rows, err = tx.tx.Query(`SELECT d.day, l.account, l.amount FROM gl_exported_day d LEFT JOIN gl_journal_line l ON l.day=d.day`)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			daystr  string
+			day     time.Time
+			account sql.NullString
+			amount  sql.NullInt64
+		)
+		panicOnError(rows.Scan(&daystr, &account, &amount))
+		day, err = time.ParseInLocation("2006-01-02", daystr, time.Local)
+		panicOnError(err)
+		if days[day] == nil {
+			days[day] = make(map[string]int)
+		}
+		if account.Valid {
+			days[day][account.String] = int(amount.Int64)
+		}
+	}
+	panicOnError(rows.Err())
+	return days
+}
+
+// SaveExportedJournalDay records the journal lines, keyed by account, of a
+// day whose journal entry has been exported.
+func (tx Tx) SaveExportedJournalDay(day time.Time, lines map[string]int) {
+	var daystr = day.Format("2006-01-02")
+
+	panicOnExecError(tx.tx.Exec(`INSERT INTO gl_exported_day (day) VALUES (?)`, daystr))
+	for account, amount := range lines {
+		if amount != 0 {
+			panicOnExecError(tx.tx.Exec(`INSERT INTO gl_journal_line (day, account, amount) VALUES (?,?,?)`,
+				daystr, account, amount))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// SaveMarketingOptOut records that the specified email address has asked to
+// be removed from the mailing list.
+func (tx Tx) SaveMarketingOptOut(email string, created time.Time) {
+	panicOnExecError(tx.tx.Exec(`INSERT OR REPLACE INTO marketing_optout (email, created) VALUES (?,?)`,
+		email, Time(created)))
+}
+
+// MailingContact is the type returned by FetchMailingList (q.v.).
+type MailingContact struct {
+	Email        string
+	Name         string
+	LastPurchase time.Time
+	Series       []string
+}
+
+// FetchMailingList returns the contacts who have consented to marketing email,
+// in email address order.  A contact has consented if a valid order placed
+// with their email address has the marketing opt-in flag, and they haven't
+// opted out since.  The name is taken from the most recent valid order placed
+// with the email address, which also gives the last purchase time.  The series
+// are those for which tickets were bought with the email address.
+func (tx Tx) FetchMailingList() (list []*MailingContact) {
+	var (
+		rows      *sql.Rows
+		byEmail   = make(map[string]*MailingContact)
+		consented = make(map[string]time.Time)
+		optedOut  = make(map[string]time.Time)
+		err       error
+	)
+	rows, err = tx.tx.Query(`
+SELECT o.email, o.name, o.created, o.marketing_optin, COALESCE(m.created, '')
+FROM ordert o LEFT JOIN marketing_optout m ON m.email=o.email
+WHERE o.valid AND o.email!='' ORDER BY o.created`)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			mc      MailingContact
+			optin   bool
+			optout  time.Time
+			key     string
+			contact *MailingContact
+		)
+		panicOnError(rows.Scan(&mc.Email, &mc.Name, (*Time)(&mc.LastPurchase), &optin, (*Time)(&optout)))
+		key = strings.ToLower(mc.Email)
+		if contact = byEmail[key]; contact == nil {
+			contact = &mc
+			byEmail[key] = contact
+		} else {
+			contact.Name, contact.LastPurchase = mc.Name, mc.LastPurchase
+		}
+		if optin {
+			consented[key] = mc.LastPurchase
+		}
+		optedOut[key] = optout
+	}
+	panicOnError(rows.Err())
+	for key, contact := range byEmail {
+		if consented[key].IsZero() || !optedOut[key].Before(consented[key]) {
+			delete(byEmail, key)
+			continue
+		}
+		list = append(list, contact)
+	}
+	rows, err = tx.tx.Query(`
+SELECT DISTINCT o.email, p.series FROM ordert o, order_line ol, product p
+WHERE ol.orderid=o.id AND ol.product=p.id AND p.type=? AND p.series!='' AND o.valid AND o.email!=''
+ORDER BY p.series`, model.ProdTicket)
+	panicOnError(err)
+	for rows.Next() {
+		var email, series string
+
+		panicOnError(rows.Scan(&email, &series))
+		if contact := byEmail[strings.ToLower(email)]; contact != nil {
+			if len(contact.Series) == 0 || contact.Series[len(contact.Series)-1] != series {
+				contact.Series = append(contact.Series, series)
+			}
+		}
+	}
+	panicOnError(rows.Err())
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Email) < strings.ToLower(list[j].Email) })
+	return list
+}
@@ -3,19 +3,20 @@ package db
 import (
 	"database/sql"
 	"strings"
+	"time"
 
 	"scholacantorum.org/orders/model"
 )
 
 // orderColumns is the list of columns in the orderT table.
-var orderColumns = `id, token, valid, source, name, email, address, city, state, zip, phone, customer, member, created, cnote, onote, in_access, coupon`
+var orderColumns = `id, token, valid, source, name, email, address, city, state, zip, phone, customer, member, created, cnote, onote, in_access, coupon, marketing_optin, customer_id`
 
 // scanOrder scans an orderT table row.
 func scanOrder(scanner interface{ Scan(...interface{}) error }, o *model.Order) error {
 	return scanner.Scan(&o.ID, &o.Token, &o.Valid, &o.Source, &o.Name, &o.Email,
 		&o.Address, &o.City, &o.State, &o.Zip, &o.Phone, &o.Customer,
 		&o.Member, (*Time)(&o.Created), &o.CNote, &o.ONote, &o.InAccess,
-		&o.Coupon)
+		&o.Coupon, &o.MarketingOptIn, (*ID)(&o.CustomerID))
 }
 
 // FetchOrder returns the order with the specified ID.  It returns nil if no
@@ -43,21 +44,22 @@ func (tx Tx) FetchOrder(id model.OrderID) (o *model.Order) {
 		panic(err)
 	}
 	prows, err = tx.tx.Query(
-		`SELECT id, type, subtype, method, stripe, created, amount FROM payment WHERE orderid=? ORDER BY id`,
+		`SELECT id, type, subtype, method, stripe, card, created, amount FROM payment WHERE orderid=? ORDER BY id`,
 		o.ID)
 	panicOnError(err)
 	for prows.Next() {
 		var p model.Payment
-		panicOnError(prows.Scan(&p.ID, &p.Type, &p.Subtype, &p.Method, &p.Stripe, (*Time)(&p.Created), &p.Amount))
+		panicOnError(prows.Scan(&p.ID, &p.Type, &p.Subtype, &p.Method, &p.Stripe, &p.Card, (*Time)(&p.Created), &p.Amount))
 		o.Payments = append(o.Payments, &p)
 	}
 	panicOnError(prows.Err())
 	lrows, err = tx.tx.Query(
-		`SELECT id, product, quantity, price, guest_name, guest_email, option FROM order_line WHERE orderid=? ORDER BY id`, o.ID)
+		`SELECT id, product, quantity, price, guest_name, guest_email, option, fmv, fund, tribute, honoree, notify_name, notify_email FROM order_line WHERE orderid=? ORDER BY id`, o.ID)
 	panicOnError(err)
 	for lrows.Next() {
 		var ol model.OrderLine
-		panicOnError(lrows.Scan(&ol.ID, &pid, &ol.Quantity, &ol.Price, &ol.GuestName, &ol.GuestEmail, &ol.Option))
+		panicOnError(lrows.Scan(&ol.ID, &pid, &ol.Quantity, &ol.Price, &ol.GuestName, &ol.GuestEmail, &ol.Option, &ol.FMV,
+			&ol.Fund, &ol.Tribute, &ol.Honoree, &ol.NotifyName, &ol.NotifyEmail))
 		ol.Product = tx.FetchProduct(pid)
 		trows, err = tx.tx.Query(`SELECT id, event, used FROM ticket WHERE order_line=? ORDER BY id`, ol.ID)
 		panicOnError(err)
@@ -95,28 +97,32 @@ func (tx Tx) FetchOrderByToken(token string) *model.Order {
 }
 
 // SaveOrder saves an order to the database.  This includes saving all
-// order-specific subsidiary objects.
+// order-specific subsidiary objects, and updating the report summary tables.
 func (tx Tx) SaveOrder(o *model.Order) {
 	var (
-		q   strings.Builder
-		res sql.Result
-		err error
+		q      strings.Builder
+		res    sql.Result
+		before reportSummary
+		err    error
 	)
+	if o.ID != 0 {
+		before = tx.orderReportSummary(o.ID)
+	}
 	q.WriteString(`INSERT OR REPLACE INTO orderT (`)
 	q.WriteString(orderColumns)
-	q.WriteString(`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+	q.WriteString(`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
 	res, err = tx.tx.Exec(q.String(), ID(o.ID), o.Token, o.Valid, o.Source,
 		o.Name, o.Email, o.Address, o.City, o.State, o.Zip, o.Phone,
 		o.Customer, o.Member, Time(o.Created), o.CNote, o.ONote,
-		o.InAccess, o.Coupon)
+		o.InAccess, o.Coupon, o.MarketingOptIn, ID(o.CustomerID))
 	panicOnError(err)
 	if o.ID == 0 {
 		o.ID = model.OrderID(lastInsertID(res))
 	}
 	for i, p := range o.Payments {
 		res, err = tx.tx.Exec(
-			`INSERT OR REPLACE INTO payment (id, orderid, type, subtype, method, stripe, created, initial, amount) VALUES (?,?,?,?,?,?,?,?,?)`,
-			ID(p.ID), o.ID, p.Type, p.Subtype, p.Method, p.Stripe, Time(p.Created), i == 0, p.Amount)
+			`INSERT OR REPLACE INTO payment (id, orderid, type, subtype, method, stripe, card, created, initial, amount) VALUES (?,?,?,?,?,?,?,?,?,?)`,
+			ID(p.ID), o.ID, p.Type, p.Subtype, p.Method, p.Stripe, p.Card, Time(p.Created), i == 0, p.Amount)
 		panicOnError(err)
 		if p.ID == 0 {
 			p.ID = model.PaymentID(lastInsertID(res))
@@ -124,8 +130,9 @@ func (tx Tx) SaveOrder(o *model.Order) {
 	}
 	for _, ol := range o.Lines {
 		res, err = tx.tx.Exec(
-			`INSERT OR REPLACE INTO order_line (id, orderid, product, quantity, price, guest_name, guest_email, option) VALUES (?,?,?,?,?,?,?,?)`,
-			ID(ol.ID), o.ID, ol.Product.ID, ol.Quantity, ol.Price, ol.GuestName, ol.GuestEmail, ol.Option)
+			`INSERT OR REPLACE INTO order_line (id, orderid, product, quantity, price, guest_name, guest_email, option, fmv, fund, tribute, honoree, notify_name, notify_email) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+			ID(ol.ID), o.ID, ol.Product.ID, ol.Quantity, ol.Price, ol.GuestName, ol.GuestEmail, ol.Option, ol.FMV,
+			ol.Fund, ol.Tribute, ol.Honoree, ol.NotifyName, ol.NotifyEmail)
 		panicOnError(err)
 		if ol.ID == 0 {
 			ol.ID = model.OrderLineID(lastInsertID(res))
@@ -146,11 +153,119 @@ func (tx Tx) SaveOrder(o *model.Order) {
 			}
 		}
 	}
+	tx.updateReportSummary(before, tx.orderReportSummary(o.ID))
 }
 
 // DeleteOrder deletes an order from the database.  Generally this is done only
 // if the order was not processed successfully.
 func (tx Tx) DeleteOrder(o *model.Order) {
+	tx.updateReportSummary(tx.orderReportSummary(o.ID), reportSummary{})
 	panicOnExecError(tx.tx.Exec(`DELETE FROM payment WHERE orderid=?`, o.ID))
 	panicOnNoRows(tx.tx.Exec(`DELETE FROM orderT WHERE id=?`, o.ID))
 }
+
+// DeleteOrderLine deletes an order line, and its tickets, from the database.
+func (tx Tx) DeleteOrderLine(ol *model.OrderLine) {
+	var (
+		oid    model.OrderID
+		before reportSummary
+	)
+	panicOnError(tx.tx.QueryRow(`SELECT orderid FROM order_line WHERE id=?`, ol.ID).Scan(&oid))
+	before = tx.orderReportSummary(oid)
+	panicOnExecError(tx.tx.Exec(`DELETE FROM ticket WHERE order_line=?`, ol.ID))
+	panicOnNoRows(tx.tx.Exec(`DELETE FROM order_line WHERE id=?`, ol.ID))
+	tx.updateReportSummary(before, tx.orderReportSummary(oid))
+}
+
+// FetchPaidOrders returns the IDs of the valid orders with payments (or
+// refunds) made before the specified time, in order number order.
+func (tx Tx) FetchPaidOrders(before time.Time) (list []model.OrderID) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT DISTINCT o.id FROM ordert o, payment p
+WHERE o.valid AND p.orderid=o.id AND p.amount!=0 AND p.created<?
+ORDER BY o.id`, Time(before))
+	panicOnError(err)
+	for rows.Next() {
+		var oid model.OrderID
+		panicOnError(rows.Scan(&oid))
+		list = append(list, oid)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// StripePayment is the type returned by FetchStripePayments (q.v.).
+type StripePayment struct {
+	Order   model.OrderID
+	Valid   bool // whether the order is valid
+	Type    model.PaymentType
+	Stripe  string // charge or refund ID; empty if never processed
+	Created time.Time
+	Amount  int
+}
+
+// FetchStripePayments returns all card payments (and refunds), and all other
+// payments with Stripe IDs, in the order they were made.
+func (tx Tx) FetchStripePayments() (list []StripePayment) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT o.id, o.valid, p.type, p.stripe, p.created, p.amount FROM ordert o, payment p
+WHERE p.orderid=o.id AND (p.type IN (?,?) OR p.stripe!='')
+ORDER BY p.created, p.id`, model.PaymentCard, model.PaymentCardPresent)
+	panicOnError(err)
+	for rows.Next() {
+		var sp StripePayment
+		panicOnError(rows.Scan(&sp.Order, &sp.Valid, &sp.Type, &sp.Stripe, (*Time)(&sp.Created), &sp.Amount))
+		list = append(list, sp)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// GeoOrder is the type returned by FetchGeoOrders (q.v.).
+type GeoOrder struct {
+	Order   model.OrderID
+	Name    string
+	Email   string
+	Address string
+	City    string
+	State   string
+	Zip     string
+	Tickets int
+}
+
+// FetchGeoOrders returns the addresses of the valid orders created in the
+// specified time range (either end of which may be zero for no limit) that
+// include products in the specified series (if it is not empty), in order
+// number order.  Tickets is the number of tickets on the order, counting only
+// those in the series if one is specified.
+func (tx Tx) FetchGeoOrders(from, to time.Time, series string) (list []GeoOrder) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if to.IsZero() {
+		to = time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local)
+	}
+	rows, err = tx.tx.Query(`
+SELECT o.id, o.name, o.email, o.address, o.city, o.state, o.zip,
+       SUM(CASE WHEN p.type='ticket' THEN ol.quantity*MAX(p.ticket_count, 1) ELSE 0 END)
+FROM ordert o, order_line ol, product p
+WHERE o.valid AND o.created>=? AND o.created<? AND ol.orderid=o.id AND p.id=ol.product AND (?='' OR p.series=?)
+GROUP BY o.id ORDER BY o.id`, Time(from), Time(to), series, series)
+	panicOnError(err)
+	for rows.Next() {
+		var g GeoOrder
+		panicOnError(rows.Scan(&g.Order, &g.Name, &g.Email, &g.Address, &g.City, &g.State, &g.Zip, &g.Tickets))
+		list = append(list, g)
+	}
+	panicOnError(rows.Err())
+	return list
+}
@@ -8,12 +8,12 @@ import (
 )
 
 // productColumns is the list of columns of the product table.
-var productColumns = `id, series, name, shortname, type, receipt, ticket_count, ticket_class, options`
+var productColumns = `id, series, name, shortname, type, receipt, ticket_count, ticket_class, options, fmv, funds`
 
 // scanProduct scans a product table row.
 func (tx Tx) scanProduct(scanner interface{ Scan(...interface{}) error }, p *model.Product) (err error) {
-	var options string
-	if err = scanner.Scan(&p.ID, &p.Series, &p.Name, &p.ShortName, &p.Type, &p.Receipt, &p.TicketCount, &p.TicketClass, &options); err != nil {
+	var options, funds string
+	if err = scanner.Scan(&p.ID, &p.Series, &p.Name, &p.ShortName, &p.Type, &p.Receipt, &p.TicketCount, &p.TicketClass, &options, &p.FMV, &funds); err != nil {
 		return err
 	}
 	if options == "" {
@@ -21,6 +21,11 @@ func (tx Tx) scanProduct(scanner interface{ Scan(...interface{}) error }, p *mod
 	} else {
 		p.Options = strings.Split(options, ",")
 	}
+	if funds == "" {
+		p.Funds = nil
+	} else {
+		p.Funds = strings.Split(funds, ",")
+	}
 	return nil
 }
 
@@ -31,8 +36,8 @@ func (tx Tx) SaveProduct(p *model.Product) {
 	)
 	q.WriteString(`INSERT OR REPLACE INTO product (`)
 	q.WriteString(productColumns)
-	q.WriteString(`) VALUES (?,?,?,?,?,?,?,?,?)`)
-	panicOnExecError(tx.tx.Exec(q.String(), p.ID, p.Series, p.Name, p.ShortName, p.Type, p.Receipt, p.TicketCount, p.TicketClass, strings.Join(p.Options, ",")))
+	q.WriteString(`) VALUES (?,?,?,?,?,?,?,?,?,?,?)`)
+	panicOnExecError(tx.tx.Exec(q.String(), p.ID, p.Series, p.Name, p.ShortName, p.Type, p.Receipt, p.TicketCount, p.TicketClass, strings.Join(p.Options, ","), p.FMV, strings.Join(p.Funds, ",")))
 	panicOnExecError(tx.tx.Exec(`DELETE FROM product_event WHERE product=?`, p.ID))
 	for _, pe := range p.Events {
 		panicOnNoRows(tx.tx.Exec(
@@ -41,8 +46,8 @@ func (tx Tx) SaveProduct(p *model.Product) {
 	panicOnExecError(tx.tx.Exec(`DELETE FROM sku WHERE product=?`, p.ID))
 	for _, sku := range p.SKUs {
 		panicOnExecError(tx.tx.Exec(
-			`INSERT INTO sku (product, source, coupon, sales_start, sales_end, price) VALUES (?,?,?,?,?,?)`,
-			p.ID, sku.Source, sku.Coupon, Time(sku.SalesStart), Time(sku.SalesEnd), sku.Price))
+			`INSERT INTO sku (product, source, coupon, sales_start, sales_end, price, fmv) VALUES (?,?,?,?,?,?,?)`,
+			p.ID, sku.Source, sku.Coupon, Time(sku.SalesStart), Time(sku.SalesEnd), sku.Price, sku.FMV))
 	}
 }
 
@@ -82,12 +87,12 @@ func (tx Tx) FetchProduct(id model.ProductID) (p *model.Product) {
 	}
 	panicOnError(rows.Err())
 	rows, err = tx.tx.Query(
-		`SELECT source, coupon, sales_start, sales_end, price FROM sku WHERE product=?`, p.ID)
+		`SELECT source, coupon, sales_start, sales_end, price, fmv FROM sku WHERE product=?`, p.ID)
 	panicOnError(err)
 	for rows.Next() {
 		var sku model.SKU
 		panicOnError(rows.Scan(&sku.Source, &sku.Coupon, (*Time)(&sku.SalesStart),
-			(*Time)(&sku.SalesEnd), &sku.Price))
+			(*Time)(&sku.SalesEnd), &sku.Price, &sku.FMV))
 		p.SKUs = append(p.SKUs, &sku)
 	}
 	panicOnError(rows.Err())
@@ -107,19 +112,19 @@ func (tx Tx) FetchProductsByEvent(event *model.Event) (products []*model.Product
 		err      error
 	)
 	prows, err = tx.tx.Query(`
-SELECT p.id, p.series, p.name, p.shortname, p.type, p.receipt, p.ticket_count, p.ticket_class, p.options
+SELECT p.id, p.series, p.name, p.shortname, p.type, p.receipt, p.ticket_count, p.ticket_class, p.options, p.fmv, p.funds
 FROM product p, product_event pe WHERE pe.product=p.id AND pe.event=? ORDER BY pe.priority`, event.ID)
 	panicOnError(err)
 	for prows.Next() {
 		var p model.Product
 		panicOnError(tx.scanProduct(prows, &p))
 		srows, err = tx.tx.Query(
-			`SELECT source, coupon, sales_start, sales_end, price FROM sku WHERE product=?`, p.ID)
+			`SELECT source, coupon, sales_start, sales_end, price, fmv FROM sku WHERE product=?`, p.ID)
 		panicOnError(err)
 		for srows.Next() {
 			var sku model.SKU
 			panicOnError(srows.Scan(&sku.Source, &sku.Coupon, (*Time)(&sku.SalesStart),
-				(*Time)(&sku.SalesEnd), &sku.Price))
+				(*Time)(&sku.SalesEnd), &sku.Price, &sku.FMV))
 			p.SKUs = append(p.SKUs, &sku)
 		}
 		panicOnError(srows.Err())
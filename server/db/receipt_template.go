@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+
+	"scholacantorum.org/orders/model"
+)
+
+// receiptTemplateColumns is the list of columns in the receipt_template table.
+var receiptTemplateColumns = `id, name, product_type, product, subject, html, text`
+
+// scanReceiptTemplate scans a receipt_template table row.
+func scanReceiptTemplate(scanner interface{ Scan(...interface{}) error }, rt *model.ReceiptTemplate) error {
+	return scanner.Scan(&rt.ID, &rt.Name, &rt.ProductType, (*IDStr)(&rt.Product), &rt.Subject, &rt.HTML, &rt.Text)
+}
+
+// SaveReceiptTemplate saves a receipt template to the database.
+func (tx Tx) SaveReceiptTemplate(rt *model.ReceiptTemplate) {
+	var (
+		res sql.Result
+		err error
+	)
+	res, err = tx.tx.Exec(`INSERT OR REPLACE INTO receipt_template (`+receiptTemplateColumns+`) VALUES (?,?,?,?,?,?,?)`,
+		ID(rt.ID), rt.Name, rt.ProductType, IDStr(rt.Product), rt.Subject, rt.HTML, rt.Text)
+	panicOnError(err)
+	if rt.ID == 0 {
+		rt.ID = model.ReceiptTemplateID(lastInsertID(res))
+	}
+}
+
+// DeleteReceiptTemplate deletes a receipt template from the database.
+func (tx Tx) DeleteReceiptTemplate(rt *model.ReceiptTemplate) {
+	panicOnNoRows(tx.tx.Exec(`DELETE FROM receipt_template WHERE id=?`, rt.ID))
+}
+
+// FetchReceiptTemplate returns the receipt template with the specified ID.  It
+// returns nil if no such template exists.
+func (tx Tx) FetchReceiptTemplate(id model.ReceiptTemplateID) (rt *model.ReceiptTemplate) {
+	rt = new(model.ReceiptTemplate)
+	switch err := scanReceiptTemplate(tx.tx.QueryRow(
+		`SELECT `+receiptTemplateColumns+` FROM receipt_template WHERE id=?`, id), rt); err {
+	case nil:
+		return rt
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchReceiptTemplates returns all of the receipt templates, in order by ID.
+func (tx Tx) FetchReceiptTemplates() (rts []*model.ReceiptTemplate) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT ` + receiptTemplateColumns + ` FROM receipt_template ORDER BY id`)
+	panicOnError(err)
+	for rows.Next() {
+		var rt model.ReceiptTemplate
+		panicOnError(scanReceiptTemplate(rows, &rt))
+		rts = append(rts, &rt)
+	}
+	panicOnError(rows.Err())
+	return rts
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// FetchReminderSent returns whether a reminder about the specified event has
+// been sent for the specified order.
+func (tx Tx) FetchReminderSent(event *model.Event, orderID model.OrderID) bool {
+	var (
+		count int
+	)
+	panicOnError(tx.tx.QueryRow(`SELECT COUNT(*) FROM reminder_sent WHERE event=? AND orderid=?`, event.ID, orderID).Scan(&count))
+	return count != 0
+}
+
+// SaveReminderSent records that a reminder about the specified event has been
+// sent for the specified order.
+func (tx Tx) SaveReminderSent(event *model.Event, orderID model.OrderID, sent time.Time) {
+	panicOnExecError(tx.tx.Exec(`INSERT OR REPLACE INTO reminder_sent (event, orderid, sent) VALUES (?,?,?)`,
+		event.ID, orderID, Time(sent)))
+}
+
+// FetchReminderOptOut returns whether the specified email address has opted
+// out of event reminders.
+func (tx Tx) FetchReminderOptOut(email string) bool {
+	var (
+		count int
+	)
+	panicOnError(tx.tx.QueryRow(`SELECT COUNT(*) FROM reminder_optout WHERE email=?`, email).Scan(&count))
+	return count != 0
+}
+
+// SaveReminderOptOut records that the specified email address has opted out
+// of event reminders.
+func (tx Tx) SaveReminderOptOut(email string, created time.Time) {
+	panicOnExecError(tx.tx.Exec(`INSERT OR IGNORE INTO reminder_optout (email, created) VALUES (?,?)`,
+		email, Time(created)))
+}
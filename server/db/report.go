@@ -15,7 +15,7 @@ const maxReportSize = 500
 // telling it which of the report criteria to enforce (either all of them or all
 // but one of them).
 const (
-	critOrderSource byte = 1 << iota
+	critOrderSource uint16 = 1 << iota
 	critCustomer
 	critOrderCreated
 	critOrderCoupon
@@ -23,10 +23,25 @@ const (
 	critPaymentType
 	critTicketClass
 	critUsedAtEvent
+	critAmount
+	critOrderValid
+	critMember
+	critProductType
+	critSeries
+	critOption
+	critGuestName
 	critAll = critOrderSource | critCustomer | critOrderCreated | critOrderCoupon |
-		critProduct | critPaymentType | critTicketClass | critUsedAtEvent
+		critProduct | critPaymentType | critTicketClass | critUsedAtEvent | critAmount |
+		critOrderValid | critMember | critProductType | critSeries | critOption | critGuestName
 )
 
+// reportAmountBounds divide the ranges of line amounts (in cents) for which
+// RunReport returns statistics.  Each bound starts a range that runs up to one
+// cent less than the next bound, and the last range has no upper limit.  There
+// is also a range, with no lower limit, for amounts below the first bound
+// (i.e., refunds).
+var reportAmountBounds = []int{0, 1, 2500, 5000, 10000, 25000, 50000}
+
 // reportProduct contains the information we cache about each product in the DB.
 type reportProduct struct {
 	ptype  model.ProductType
@@ -45,6 +60,7 @@ type reportOrder struct {
 	source      model.OrderSource
 	name        string
 	email       string
+	member      int
 	created     time.Time
 	valid       bool
 	coupon      string
@@ -55,11 +71,14 @@ type reportOrder struct {
 // reportLine contains the information we cache about each order line while
 // processing it.
 type reportLine struct {
-	pid   model.ProductID
-	prod  *reportProduct
-	qty   int
-	price int
-	order *reportOrder
+	pid    model.ProductID
+	prod   *reportProduct
+	qty    int
+	price  int
+	fmv    int
+	option string
+	guest  string
+	order  *reportOrder
 
 	// This field maps event ID to the number of tickets used at that event,
 	// with an entry for "" counting unused tickets.  It's nil for non-
@@ -95,20 +114,97 @@ var paymentTypeMap = map[string]string{
 	"other,":                                  "Other",
 }
 
+// ReportPaymentType returns the payment type used in reports for a payment
+// with the specified type and subtype.
+func ReportPaymentType(ptype, subtype string) string {
+	if mapped := paymentTypeMap[ptype+","+subtype]; mapped != "" {
+		return mapped
+	}
+	if mapped := paymentTypeMap[ptype]; mapped != "" {
+		return mapped + subtype
+	}
+	return ptype + " " + subtype
+}
+
+// reportOrderColumns are the details of an order needed for reports, from the
+// order (o) and its initial payment (p).
+const reportOrderColumns = `o.source, o.name, o.email, o.member, o.created, o.valid, o.coupon, p.type, p.subtype`
+
+// reportOrderQuery reads the details of an order needed for reports.
+const reportOrderQuery = `
+SELECT ` + reportOrderColumns + `
+FROM ordert o LEFT JOIN payment p ON p.orderid=o.id AND p.initial WHERE o.id=?`
+
+// reportLineQuery reads the order lines needed for reports, along with the
+// details of their orders.  It may be followed by a WHERE clause and must be
+// followed by reportLineOrder.
+const reportLineQuery = `
+SELECT ` + reportOrderColumns + `, ol.id, ol.orderid, ol.product, ol.quantity, ol.price, ol.fmv, ol.option, ol.guest_name
+FROM order_line ol JOIN ordert o ON o.id=ol.orderid LEFT JOIN payment p ON p.orderid=o.id AND p.initial`
+
+// reportLineOrder sorts the results of reportLineQuery by order ID so that all
+// of the lines for an order are read together.
+const reportLineOrder = ` ORDER BY ol.orderid, ol.id`
+
+// ticketUsageQuery reads the ticket usage of an order line.
+const ticketUsageQuery = `
+SELECT COUNT(*), CASE WHEN used!='' THEN event ELSE '' END AS used_event FROM ticket WHERE order_line=? GROUP BY used_event`
+
+// scanReportOrder scans reportOrderColumns, followed by the additional
+// columns given in more, from a result of reportOrderQuery or reportLineQuery.
+// It doesn't set the order ID.
+func scanReportOrder(row interface{ Scan(...interface{}) error }, more ...interface{}) (order *reportOrder) {
+	var ptype, psubtype sql.NullString
+
+	order = new(reportOrder)
+	panicOnError(row.Scan(append([]interface{}{&order.source, &order.name, &order.email, &order.member,
+		(*Time)(&order.created), &order.valid, &order.coupon, &ptype, &psubtype}, more...)...))
+	order.coupon = strings.ToUpper(order.coupon)
+	if !ptype.Valid && order.coupon == model.CompCoupon {
+		// Comps are free orders, but we report them separately from
+		// other free orders.
+		order.paymentType = "Comp"
+	} else {
+		order.paymentType = ReportPaymentType(ptype.String, psubtype.String)
+	}
+	return order
+}
+
 // RunReport executes the report defined by the supplied report definition.
+// When the report criteria allow it, the statistics and totals are taken from
+// the report summary tables rather than computed from every order line (see
+// report_summary.go), and only the lines that can match are read.
 func (tx Tx) RunReport(def *model.ReportDefinition) *model.ReportResults {
+	return tx.runReport(def, tx.reportSummaryUsable(def), maxReportSize)
+}
+
+// RunFullReport is like RunReport, except that it returns all of the matching
+// report lines, however many there are.  It is used for reports delivered as
+// files rather than displayed.
+func (tx Tx) RunFullReport(def *model.ReportDefinition) *model.ReportResults {
+	return tx.runReport(def, tx.reportSummaryUsable(def), 0)
+}
+
+// runReport executes the report defined by the supplied report definition,
+// taking statistics from the report summary tables if useSummary is true.  If
+// more than maxLines lines match, no lines are returned; zero means no limit.
+func (tx Tx) runReport(def *model.ReportDefinition, useSummary bool, maxLines int) *model.ReportResults {
 	var (
 		rows            *sql.Rows
 		ticketUsageStmt *sql.Stmt
-		orderStmt       *sql.Stmt
 		order           *reportOrder
+		where           string
+		args            []interface{}
 		err             error
 
 		// If we don't have any criteria, we return only statistics and
 		// no rows.
 		hasAnyCriteria = !def.CreatedAfter.IsZero() || !def.CreatedBefore.IsZero() || def.Customer != "" ||
 			len(def.OrderCoupons) != 0 || len(def.OrderSources) != 0 || len(def.PaymentTypes) != 0 ||
-			len(def.Products) != 0 || len(def.TicketClasses) != 0 || len(def.UsedAtEvents) != 0
+			len(def.Products) != 0 || len(def.TicketClasses) != 0 || len(def.UsedAtEvents) != 0 ||
+			def.HasMinAmount || def.HasMaxAmount || len(def.OrderValid) != 0 || len(def.Members) != 0 ||
+			def.AnyMember || len(def.ProductTypes) != 0 || len(def.Series) != 0 || len(def.Options) != 0 ||
+			def.GuestName != ""
 
 		// Initialize the result.
 		result = model.ReportResults{
@@ -116,8 +212,18 @@ func (tx Tx) RunReport(def *model.ReportDefinition) *model.ReportResults {
 			OrderCoupons:  make(model.StringCounts),
 			PaymentTypes:  make(model.StringCounts),
 			TicketClasses: make(model.StringCounts),
+			OrderValid:    make(map[bool]int),
+			Members:       make(map[int]int),
+			ProductTypes:  make(map[model.ProductType]int),
+			Series:        make(model.StringCounts),
+			Options:       make(model.StringCounts),
 		}
 
+		// The counts for each of the ranges divided by
+		// reportAmountBounds, starting with the one below the first
+		// bound.
+		amounts = make([]int, len(reportAmountBounds)+1)
+
 		// Initialize the products and events caches.
 		products     = make(map[model.ProductID]*reportProduct)
 		usedAtEvents = make(map[model.EventID]*model.ReportEventCount)
@@ -128,7 +234,8 @@ func (tx Tx) RunReport(def *model.ReportDefinition) *model.ReportResults {
 
 	// Because each report needs to return counts of criteria permutations
 	// as well as matching records, every report run will inevitably scan
-	// the entire database. Given that, it's simpler (and possibly even more
+	// the entire database (unless the statistics and totals come from the
+	// report summary tables). Given that, it's simpler (and possibly even more
 	// efficient) that we generate the report using a single linear scan
 	// rather than a bunch of complicated, targeted queries.
 
@@ -161,86 +268,114 @@ func (tx Tx) RunReport(def *model.ReportDefinition) *model.ReportResults {
 	panicOnError(rows.Err())
 	usedAtEvents[""] = &model.ReportEventCount{Name: "(unused)"}
 
-	// We're also going to want a couple of prepared statements for queries
-	// we run often.
-	ticketUsageStmt, err = tx.tx.Prepare(`
-SELECT COUNT(*), CASE WHEN used!='' THEN event ELSE '' END AS used_event FROM ticket WHERE order_line=? GROUP BY used_event`)
-	panicOnError(err)
-	defer ticketUsageStmt.Close()
-	orderStmt, err = tx.tx.Prepare(`
-SELECT o.source, o.name, o.email, o.created, o.valid, o.coupon, p.type, p.subtype
-FROM ordert o LEFT JOIN payment p ON p.orderid=o.id AND p.initial WHERE o.id=?`)
-	panicOnError(err)
-	defer orderStmt.Close()
-
-	// Now, read every order line in the database.  Sort by order ID so that
-	// all of the lines for an order are read together.
-	rows, err = tx.tx.Query(`SELECT id, orderid, product, quantity, price FROM order_line ORDER BY orderid, id`)
-	panicOnError(err)
-	for rows.Next() {
-		var (
-			olid model.OrderLineID
-			ol   reportLine
-			oid  model.OrderID
-		)
+	if useSummary {
+		tx.addReportSummaryStats(def, &result, products, usedAtEvents, amounts)
+	}
+	if useSummary && !hasAnyCriteria {
+		// With no criteria, the report has no lines, and everything but
+		// the order count comes from the summary tables, so there's no
+		// need to read the lines at all.
+		result.OrderCount = tx.countReportOrders()
+	} else {
+		// We're also going to want a prepared statement for a query we
+		// run often.
+		ticketUsageStmt, err = tx.tx.Prepare(ticketUsageQuery)
+		panicOnError(err)
+		defer ticketUsageStmt.Close()
 
-		// Get the order line data, and the corresponding order,
-		// product, and ticket usage data.
-		panicOnError(rows.Scan(&olid, &oid, &ol.pid, &ol.qty, &ol.price))
-		if order == nil || order.id != oid {
-			var ptype, psubtype sql.NullString
-			order = &reportOrder{id: oid}
-			panicOnError(orderStmt.QueryRow(oid).Scan(&order.source, &order.name, &order.email,
-				(*Time)(&order.created), &order.valid, &order.coupon, &ptype, &psubtype))
-			order.coupon = strings.ToUpper(order.coupon)
-			if mapped := paymentTypeMap[ptype.String+","+psubtype.String]; mapped != "" {
-				order.paymentType = mapped
-			} else if mapped := paymentTypeMap[ptype.String]; mapped != "" {
-				order.paymentType = mapped + psubtype.String
-			} else {
-				order.paymentType = ptype.String + " " + psubtype.String
-			}
-		}
-		if !order.valid {
-			continue
-		}
-		ol.order = order
-		ol.prod = products[ol.pid]
-		if ol.prod.ptype == model.ProdTicket {
-			ol.tusage = readTicketUsage(ticketUsageStmt, olid)
+		// Now, read every order line in the database, with its order.
+		// If the statistics come from the summary tables, we need only
+		// the lines that can match the criteria, so we filter them as
+		// far as SQL allows.
+		if useSummary {
+			where, args = reportSummaryFilter(def, products)
 		}
+		rows, err = tx.tx.Query(reportLineQuery+where+reportLineOrder, args...)
+		panicOnError(err)
+		for rows.Next() {
+			var (
+				olid      model.OrderLineID
+				ol        reportLine
+				oid       model.OrderID
+				lineOrder *reportOrder
+			)
 
-		// If all of the criteria match, add this line into the report
-		// results.
-		if lineMatches(def, &ol, critAll) != 0 {
-			if !order.counted {
-				result.OrderCount++
-				order.counted = true
+			// Get the order line data, and the corresponding order,
+			// product, and ticket usage data.
+			lineOrder = scanReportOrder(rows, &olid, &oid, &ol.pid, &ol.qty, &ol.price, &ol.fmv, &ol.option, &ol.guest)
+			if order == nil || order.id != oid {
+				order = lineOrder
+				order.id = oid
 			}
-			if len(def.UsedAtEvents) != 0 {
-				for _, eid := range def.UsedAtEvents {
-					result.ItemCount += ol.tusage[eid]
-					result.TotalAmount += float64(ol.tusage[eid]*ol.price) / float64(ol.prod.tcount) / 100.0
-				}
-			} else if ol.tusage != nil {
-				for _, c := range ol.tusage {
-					result.ItemCount += c
-					result.TotalAmount += float64(c*ol.price) / float64(ol.prod.tcount) / 100.0
-				}
-			} else {
-				result.ItemCount += ol.qty * ol.prod.tcount
-				result.TotalAmount += float64(ol.qty*ol.price) / 100.0
+			ol.order = order
+			ol.prod = products[ol.pid]
+			if useSummary && lineMatches(def, &ol, critAll&^critUsedAtEvent) == 0 {
+				// The line can't contribute to the results (the
+				// statistics and totals all come from the summary
+				// tables), so there's no need to read its ticket usage.
+				continue
 			}
-			if result.Lines != nil && len(result.Lines) >= maxReportSize {
-				result.Lines = nil
+			if ol.prod.ptype == model.ProdTicket {
+				ol.tusage = readTicketUsage(ticketUsageStmt, olid)
 			}
-			if result.Lines != nil {
-				if len(def.UsedAtEvents) != 0 {
-					// One line for each event that tickets were
-					// used at (and that was requested in the
-					// report), with quantity for that event.
+
+			// If all of the criteria match, add this line into the report
+			// results.
+			if lineMatches(def, &ol, critAll) != 0 {
+				if !order.counted {
+					result.OrderCount++
+					order.counted = true
+				}
+				if useSummary {
+					// The totals come from the summary tables.
+				} else if len(def.UsedAtEvents) != 0 {
 					for _, eid := range def.UsedAtEvents {
-						if c := ol.tusage[eid]; c != 0 {
+						result.ItemCount += ol.tusage[eid]
+						result.TotalAmount += float64(ol.tusage[eid]*ol.price) / float64(ol.prod.tcount) / 100.0
+					}
+				} else if ol.tusage != nil {
+					for _, c := range ol.tusage {
+						result.ItemCount += c
+						result.TotalAmount += float64(c*ol.price) / float64(ol.prod.tcount) / 100.0
+					}
+				} else {
+					result.ItemCount += ol.qty * ol.prod.tcount
+					result.TotalAmount += float64(ol.qty*ol.price) / 100.0
+				}
+				if !useSummary {
+					result.TotalDeductible += float64(ol.deductible()) / 100.0
+					if ol.prod.ptype == model.ProdFee {
+						result.TotalFees += float64(ol.qty*ol.price) / 100.0
+					}
+				}
+				if result.Lines != nil && maxLines != 0 && len(result.Lines) >= maxLines {
+					result.Lines = nil
+				}
+				if result.Lines != nil {
+					if len(def.UsedAtEvents) != 0 {
+						// One line for each event that tickets were
+						// used at (and that was requested in the
+						// report), with quantity for that event.
+						for _, eid := range def.UsedAtEvents {
+							if c := ol.tusage[eid]; c != 0 {
+								result.Lines = append(result.Lines, &model.ReportLine{
+									OrderID:     ol.order.id,
+									OrderTime:   ol.order.created,
+									Name:        ol.order.name,
+									Email:       ol.order.email,
+									Quantity:    c,
+									Product:     ol.prod.name,
+									UsedAtEvent: eid,
+									OrderSource: ol.order.source,
+									PaymentType: ol.order.paymentType,
+									Amount:      float64(c*ol.price) / float64(ol.prod.tcount) / 100.0,
+								})
+							}
+						}
+					} else if ol.tusage != nil {
+						// One line for each event that tickets were
+						// used at (including not used), with quantity.
+						for eid, c := range ol.tusage {
 							result.Lines = append(result.Lines, &model.ReportLine{
 								OrderID:     ol.order.id,
 								OrderTime:   ol.order.created,
@@ -254,69 +389,33 @@ FROM ordert o LEFT JOIN payment p ON p.orderid=o.id AND p.initial WHERE o.id=?`)
 								Amount:      float64(c*ol.price) / float64(ol.prod.tcount) / 100.0,
 							})
 						}
-					}
-				} else if ol.tusage != nil {
-					// One line for each event that tickets were
-					// used at (including not used), with quantity.
-					for eid, c := range ol.tusage {
+					} else {
+						// One line for the order line.
 						result.Lines = append(result.Lines, &model.ReportLine{
 							OrderID:     ol.order.id,
 							OrderTime:   ol.order.created,
 							Name:        ol.order.name,
 							Email:       ol.order.email,
-							Quantity:    c,
+							Quantity:    ol.qty * ol.prod.tcount,
 							Product:     ol.prod.name,
-							UsedAtEvent: eid,
 							OrderSource: ol.order.source,
 							PaymentType: ol.order.paymentType,
-							Amount:      float64(c*ol.price) / float64(ol.prod.tcount) / 100.0,
+							Amount:      float64(ol.qty*ol.price) / 100.0,
+							Deductible:  float64(ol.deductible()) / 100.0,
 						})
 					}
-				} else {
-					// One line for the order line.
-					result.Lines = append(result.Lines, &model.ReportLine{
-						OrderID:     ol.order.id,
-						OrderTime:   ol.order.created,
-						Name:        ol.order.name,
-						Email:       ol.order.email,
-						Quantity:    ol.qty * ol.prod.tcount,
-						Product:     ol.prod.name,
-						OrderSource: ol.order.source,
-						PaymentType: ol.order.paymentType,
-						Amount:      float64(ol.qty*ol.price) / 100.0,
-					})
 				}
 			}
-		}
 
-		// If all or all but one of the criteria match, add this line
-		// into the statistics.
-		if tcount := lineMatches(def, &ol, critAll&^critOrderSource); tcount != 0 {
-			result.OrderSources[ol.order.source] += tcount
-		}
-		if tcount := lineMatches(def, &ol, critAll&^critOrderCoupon); tcount != 0 {
-			result.OrderCoupons[ol.order.coupon] += tcount
-		}
-		if tcount := lineMatches(def, &ol, critAll&^critProduct); tcount != 0 {
-			ol.prod.count += tcount
-		}
-		if tcount := lineMatches(def, &ol, critAll&^critPaymentType); tcount != 0 {
-			result.PaymentTypes[ol.order.paymentType] += tcount
-		}
-		if tcount := lineMatches(def, &ol, critAll&^critTicketClass); tcount != 0 {
-			if ol.prod.ptype == model.ProdTicket {
-				result.TicketClasses[ol.prod.tclass] += tcount
-			}
-		}
-		if lineMatches(def, &ol, critAll&^critUsedAtEvent) != 0 {
-			if ol.tusage != nil {
-				for eid, tcount := range ol.tusage {
-					usedAtEvents[eid].Count += tcount
-				}
+			// If all or all but one of the criteria match, add this line
+			// into the statistics.
+			if !useSummary {
+				addSummarizedStats(def, &ol, &result, usedAtEvents)
+				addScannedStats(def, &ol, ol.qty*ol.price, &result, amounts)
 			}
 		}
+		panicOnError(rows.Err())
 	}
-	panicOnError(rows.Err())
 
 	// Generate the result.Products list.
 	for pid, prod := range products {
@@ -339,9 +438,93 @@ FROM ordert o LEFT JOIN payment p ON p.orderid=o.id AND p.initial WHERE o.id=?`)
 		}
 		result.UsedAtEvents = append(result.UsedAtEvents, event)
 	}
+
+	// Generate the result.Amounts list.
+	for i, count := range amounts {
+		var rac = model.ReportAmountCount{Count: count}
+
+		if count == 0 {
+			continue
+		}
+		if i > 0 {
+			rac.HasMin, rac.Min = true, reportAmountBounds[i-1]
+		}
+		if i < len(reportAmountBounds) {
+			rac.HasMax, rac.Max = true, reportAmountBounds[i]-1
+		}
+		result.Amounts = append(result.Amounts, &rac)
+	}
 	return &result
 }
 
+// addSummarizedStats adds an order line into the statistics for those report
+// criteria covered by the report summary tables, for each criterion whose
+// choices it would match if all of the other criteria matched.
+func addSummarizedStats(def *model.ReportDefinition, ol *reportLine, result *model.ReportResults, usedAtEvents map[model.EventID]*model.ReportEventCount) {
+	if tcount := lineMatches(def, ol, critAll&^critOrderSource); tcount != 0 {
+		result.OrderSources[ol.order.source] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critOrderCoupon); tcount != 0 {
+		result.OrderCoupons[ol.order.coupon] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critProduct); tcount != 0 {
+		ol.prod.count += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critPaymentType); tcount != 0 {
+		result.PaymentTypes[ol.order.paymentType] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critTicketClass); tcount != 0 {
+		if ol.prod.ptype == model.ProdTicket {
+			result.TicketClasses[ol.prod.tclass] += tcount
+		}
+	}
+	if lineMatches(def, ol, critAll&^critUsedAtEvent) != 0 {
+		if ol.tusage != nil {
+			for eid, tcount := range ol.tusage {
+				if e := usedAtEvents[eid]; e != nil {
+					e.Count += tcount
+				}
+			}
+		}
+	}
+	if tcount := lineMatches(def, ol, critAll&^critOrderValid); tcount != 0 {
+		result.OrderValid[ol.order.valid] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critProductType); tcount != 0 {
+		result.ProductTypes[ol.prod.ptype] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critSeries); tcount != 0 {
+		result.Series[ol.prod.series] += tcount
+	}
+}
+
+// addScannedStats adds an order line, whose amount (quantity times price) is
+// given, into the statistics for those report criteria that make the report
+// summary tables unusable.
+func addScannedStats(def *model.ReportDefinition, ol *reportLine, amount int, result *model.ReportResults, amounts []int) {
+	if tcount := lineMatches(def, ol, critAll&^critAmount); tcount != 0 {
+		var i int
+
+		for i < len(reportAmountBounds) && amount >= reportAmountBounds[i] {
+			i++
+		}
+		amounts[i] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critMember); tcount != 0 {
+		result.Members[ol.order.member] += tcount
+	}
+	if tcount := lineMatches(def, ol, critAll&^critOption); tcount != 0 {
+		result.Options[ol.option] += tcount
+	}
+}
+
+// deductible returns the tax-deductible portion of the order line, in cents.
+// (Tickets are never deductible, so ticket usage doesn't enter into it.)
+func (ol *reportLine) deductible() int {
+	var line = model.OrderLine{Product: &model.Product{Type: ol.prod.ptype}, Quantity: ol.qty, Price: ol.price, FMV: ol.fmv}
+	return line.Deductible()
+}
+
 // readTicketUsage uses the prepared statement to retrieve the ticket usage for
 // a particular order line.
 func readTicketUsage(stmt *sql.Stmt, olid model.OrderLineID) (usage map[model.EventID]int) {
@@ -364,10 +547,69 @@ func readTicketUsage(stmt *sql.Stmt, olid model.OrderLineID) (usage map[model.Ev
 	return usage
 }
 
+// reportSummaryFilter returns a WHERE clause for reportLineQuery, and its
+// arguments, selecting only the lines that can match the report criteria on
+// product, ticket class, product type, series, order source, coupon, and
+// validity.  (The other criteria covered by the report summary tables aren't
+// easily expressed in SQL; lineMatches checks them.)  It is used when the
+// statistics come from the summary tables, so that the other lines needn't be
+// read at all.
+func reportSummaryFilter(def *model.ReportDefinition, products map[model.ProductID]*reportProduct) (where string, args []interface{}) {
+	var conds []string
+
+	if len(def.Products) != 0 || len(def.TicketClasses) != 0 || len(def.ProductTypes) != 0 || len(def.Series) != 0 {
+		var pids []string
+		for pid, prod := range products {
+			var ol = reportLine{pid: pid, prod: prod, qty: 1, order: new(reportOrder)}
+			if lineMatches(def, &ol, critProduct|critTicketClass|critProductType|critSeries) != 0 {
+				pids = append(pids, "?")
+				args = append(args, pid)
+			}
+		}
+		if len(pids) == 0 {
+			return " WHERE 0", nil
+		}
+		conds = append(conds, "ol.product IN ("+strings.Join(pids, ",")+")")
+	}
+	if len(def.OrderSources) != 0 {
+		var marks = make([]string, len(def.OrderSources))
+		for i, os := range def.OrderSources {
+			marks[i] = "?"
+			args = append(args, os)
+		}
+		conds = append(conds, "o.source IN ("+strings.Join(marks, ",")+")")
+	}
+	if len(def.OrderCoupons) != 0 {
+		var marks = make([]string, len(def.OrderCoupons))
+		for i, oc := range def.OrderCoupons {
+			marks[i] = "?"
+			args = append(args, strings.ToUpper(oc))
+		}
+		conds = append(conds, "UPPER(o.coupon) IN ("+strings.Join(marks, ",")+")")
+	}
+	var valid, invalid = len(def.OrderValid) == 0, false
+	for _, v := range def.OrderValid {
+		if v {
+			valid = true
+		} else {
+			invalid = true
+		}
+	}
+	if valid && !invalid {
+		conds = append(conds, "o.valid")
+	} else if invalid && !valid {
+		conds = append(conds, "NOT o.valid")
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
 // lineMatches determines whether an order line matches the report criteria
 // defined in def and selected in crit.  It returns the number of tickets/items
 // matched, with 0 meaning no match.
-func lineMatches(def *model.ReportDefinition, ol *reportLine, crit byte) int {
+func lineMatches(def *model.ReportDefinition, ol *reportLine, crit uint16) int {
 	if crit&critOrderSource != 0 && len(def.OrderSources) != 0 {
 		var found = false
 		for _, os := range def.OrderSources {
@@ -443,6 +685,83 @@ func lineMatches(def *model.ReportDefinition, ol *reportLine, crit byte) int {
 			return 0
 		}
 	}
+	if crit&critAmount != 0 && def.HasMinAmount && ol.qty*ol.price < def.MinAmount {
+		return 0
+	}
+	if crit&critAmount != 0 && def.HasMaxAmount && ol.qty*ol.price > def.MaxAmount {
+		return 0
+	}
+	if crit&critOrderValid != 0 {
+		if len(def.OrderValid) == 0 {
+			if !ol.order.valid {
+				return 0
+			}
+		} else {
+			var found = false
+			for _, v := range def.OrderValid {
+				if v == ol.order.valid {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return 0
+			}
+		}
+	}
+	if crit&critMember != 0 && (len(def.Members) != 0 || def.AnyMember) {
+		var found = def.AnyMember && ol.order.member != 0
+		for _, m := range def.Members {
+			if m == ol.order.member {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	if crit&critProductType != 0 && len(def.ProductTypes) != 0 {
+		var found = false
+		for _, pt := range def.ProductTypes {
+			if pt == ol.prod.ptype {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	if crit&critSeries != 0 && len(def.Series) != 0 {
+		var found = false
+		for _, s := range def.Series {
+			if s == ol.prod.series {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	if crit&critOption != 0 && len(def.Options) != 0 {
+		var found = false
+		for _, o := range def.Options {
+			if o == ol.option {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	if crit&critGuestName != 0 && def.GuestName != "" {
+		if !strings.Contains(strings.ToLower(ol.guest), def.GuestName) {
+			return 0
+		}
+	}
 	if crit&critUsedAtEvent != 0 && len(def.UsedAtEvents) != 0 {
 		if ol.tusage == nil {
 			return 0
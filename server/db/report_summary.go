@@ -0,0 +1,348 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// The report summary tables (report_summary and report_usage_summary) hold
+// the totals of the order lines for each combination of the report criteria
+// that depend only on the product, on the order as a whole, and on the line's
+// option and amount:  product (and hence ticket class, product type, and
+// series), order source, coupon, validity, payment type, member, option,
+// amount, and the event at which tickets were used.  They let RunReport compute
+// the statistics and totals for reports without reading every order line and
+// its tickets.  SaveOrder, DeleteOrder, and DeleteOrderLine keep them up to
+// date by applying the change in the summary of the affected order.
+
+// reportSummaryKey identifies a row of a report summary table.
+type reportSummaryKey struct {
+	product     model.ProductID
+	source      model.OrderSource
+	coupon      string
+	valid       bool
+	paymentType string
+	member      int
+	option      string
+	lineAmount  int
+	event       model.EventID // report_usage_summary only
+}
+
+// reportSummaryTotals are the totals in a row of a report summary table.  For
+// report_summary rows, count is the total quantity; for report_usage_summary
+// rows, it is the number of tickets, amount is the total of their line prices,
+// and deductible is always zero.
+type reportSummaryTotals struct {
+	count      int
+	amount     int
+	deductible int
+}
+
+// reportSummary is the contents of the report summary tables, or the
+// contribution of an order to them.  lines has the report_summary rows, and
+// usage has the report_usage_summary rows.
+type reportSummary struct {
+	lines map[reportSummaryKey]reportSummaryTotals
+	usage map[reportSummaryKey]reportSummaryTotals
+}
+
+// newReportSummary returns an empty reportSummary.
+func newReportSummary() reportSummary {
+	return reportSummary{
+		lines: make(map[reportSummaryKey]reportSummaryTotals),
+		usage: make(map[reportSummaryKey]reportSummaryTotals),
+	}
+}
+
+// add adds the contents of another reportSummary into this one, multiplied by
+// sign (1 or -1).
+func (rs reportSummary) add(o reportSummary, sign int) {
+	for k, t := range o.lines {
+		rs.lines[k] = rs.lines[k].plus(t, sign)
+	}
+	for k, t := range o.usage {
+		rs.usage[k] = rs.usage[k].plus(t, sign)
+	}
+}
+
+// plus returns the sum of two reportSummaryTotals, with the second multiplied
+// by sign (1 or -1).
+func (t reportSummaryTotals) plus(o reportSummaryTotals, sign int) reportSummaryTotals {
+	return reportSummaryTotals{
+		count:      t.count + sign*o.count,
+		amount:     t.amount + sign*o.amount,
+		deductible: t.deductible + sign*o.deductible,
+	}
+}
+
+// orderReportSummary returns the contribution of the specified order to the
+// report summary tables, based on its current contents in the database.  It
+// is empty if the order doesn't exist.
+func (tx Tx) orderReportSummary(oid model.OrderID) (rs reportSummary) {
+	var (
+		order     *reportOrder
+		count     int
+		rows      *sql.Rows
+		usageStmt *sql.Stmt
+		err       error
+	)
+	rs = newReportSummary()
+	panicOnError(tx.tx.QueryRow(`SELECT COUNT(*) FROM ordert WHERE id=?`, oid).Scan(&count))
+	if count == 0 {
+		return rs
+	}
+	order = scanReportOrder(tx.tx.QueryRow(reportOrderQuery, oid))
+	order.id = oid
+	usageStmt, err = tx.tx.Prepare(ticketUsageQuery)
+	panicOnError(err)
+	defer usageStmt.Close()
+	rows, err = tx.tx.Query(`
+SELECT ol.id, ol.product, ol.quantity, ol.price, ol.fmv, ol.option, p.type
+FROM order_line ol, product p WHERE ol.orderid=? AND p.id=ol.product`, oid)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			olid  model.OrderLineID
+			ol    = reportLine{prod: new(reportProduct), order: order}
+			usage map[model.EventID]int
+			key   reportSummaryKey
+		)
+		panicOnError(rows.Scan(&olid, &ol.pid, &ol.qty, &ol.price, &ol.fmv, &ol.option, &ol.prod.ptype))
+		key = reportSummaryKey{product: ol.pid, source: order.source, coupon: order.coupon, valid: order.valid,
+			paymentType: order.paymentType, member: order.member, option: ol.option, lineAmount: ol.qty * ol.price}
+		if ol.prod.ptype == model.ProdTicket {
+			usage = readTicketUsage(usageStmt, olid)
+		}
+		if usage == nil {
+			if ol.qty != 0 {
+				rs.lines[key] = rs.lines[key].plus(reportSummaryTotals{
+					count: ol.qty, amount: ol.qty * ol.price, deductible: ol.deductible(),
+				}, 1)
+			}
+			continue
+		}
+		for eid, c := range usage {
+			key.event = eid
+			rs.usage[key] = rs.usage[key].plus(reportSummaryTotals{count: c, amount: c * ol.price}, 1)
+		}
+	}
+	panicOnError(rows.Err())
+	return rs
+}
+
+// updateReportSummary applies the change in an order's contribution to the
+// report summary tables, from before to after.
+func (tx Tx) updateReportSummary(before, after reportSummary) {
+	var delta = newReportSummary()
+
+	delta.add(after, 1)
+	delta.add(before, -1)
+	for k, t := range delta.lines {
+		if t == (reportSummaryTotals{}) {
+			continue
+		}
+		panicOnExecError(tx.tx.Exec(`
+INSERT INTO report_summary (product, source, coupon, valid, payment_type, member, option, line_amount, quantity, amount, deductible)
+VALUES (?,?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT DO UPDATE SET quantity=quantity+excluded.quantity, amount=amount+excluded.amount, deductible=deductible+excluded.deductible`,
+			k.product, k.source, k.coupon, k.valid, k.paymentType, k.member, k.option, k.lineAmount, t.count, t.amount, t.deductible))
+		panicOnExecError(tx.tx.Exec(`
+DELETE FROM report_summary WHERE product=? AND source=? AND coupon=? AND valid=? AND payment_type=? AND member=? AND option=?
+AND line_amount=? AND quantity=0 AND amount=0 AND deductible=0`,
+			k.product, k.source, k.coupon, k.valid, k.paymentType, k.member, k.option, k.lineAmount))
+	}
+	for k, t := range delta.usage {
+		if t == (reportSummaryTotals{}) {
+			continue
+		}
+		panicOnExecError(tx.tx.Exec(`
+INSERT INTO report_usage_summary (product, source, coupon, valid, payment_type, member, option, line_amount, event, tickets, amount)
+VALUES (?,?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT DO UPDATE SET tickets=tickets+excluded.tickets, amount=amount+excluded.amount`,
+			k.product, k.source, k.coupon, k.valid, k.paymentType, k.member, k.option, k.lineAmount, k.event, t.count, t.amount))
+		panicOnExecError(tx.tx.Exec(`
+DELETE FROM report_usage_summary WHERE product=? AND source=? AND coupon=? AND valid=? AND payment_type=? AND member=?
+AND option=? AND line_amount=? AND event=? AND tickets=0 AND amount=0`,
+			k.product, k.source, k.coupon, k.valid, k.paymentType, k.member, k.option, k.lineAmount, k.event))
+	}
+}
+
+// fetchReportSummary returns the contents of the report summary tables.
+func (tx Tx) fetchReportSummary() (rs reportSummary) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rs = newReportSummary()
+	rows, err = tx.tx.Query(`
+SELECT product, source, coupon, valid, payment_type, member, option, line_amount, quantity, amount, deductible
+FROM report_summary`)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			k reportSummaryKey
+			t reportSummaryTotals
+		)
+		panicOnError(rows.Scan(&k.product, &k.source, &k.coupon, &k.valid, &k.paymentType, &k.member, &k.option,
+			&k.lineAmount, &t.count, &t.amount, &t.deductible))
+		rs.lines[k] = t
+	}
+	panicOnError(rows.Err())
+	rows, err = tx.tx.Query(`
+SELECT product, source, coupon, valid, payment_type, member, option, line_amount, event, tickets, amount
+FROM report_usage_summary`)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			k reportSummaryKey
+			t reportSummaryTotals
+		)
+		panicOnError(rows.Scan(&k.product, &k.source, &k.coupon, &k.valid, &k.paymentType, &k.member, &k.option,
+			&k.lineAmount, &k.event, &t.count, &t.amount))
+		rs.usage[k] = t
+	}
+	panicOnError(rows.Err())
+	return rs
+}
+
+// scanReportSummary computes the contents of the report summary tables from
+// all of the orders in the database.
+func (tx Tx) scanReportSummary() (rs reportSummary) {
+	var (
+		rows *sql.Rows
+		oids []model.OrderID
+		err  error
+	)
+	rs = newReportSummary()
+	rows, err = tx.tx.Query(`SELECT id FROM ordert ORDER BY id`)
+	panicOnError(err)
+	for rows.Next() {
+		var oid model.OrderID
+		panicOnError(rows.Scan(&oid))
+		oids = append(oids, oid)
+	}
+	panicOnError(rows.Err())
+	for _, oid := range oids {
+		rs.add(tx.orderReportSummary(oid), 1)
+	}
+	return rs
+}
+
+// RebuildReportSummary recomputes the report summary tables from scratch, and
+// records that they have been built so that RunReport will use them.
+func (tx Tx) RebuildReportSummary() {
+	panicOnExecError(tx.tx.Exec(`DELETE FROM report_summary`))
+	panicOnExecError(tx.tx.Exec(`DELETE FROM report_usage_summary`))
+	tx.updateReportSummary(newReportSummary(), tx.scanReportSummary())
+	panicOnExecError(tx.tx.Exec(`DELETE FROM report_summary_built`))
+	panicOnExecError(tx.tx.Exec(`INSERT INTO report_summary_built (built) VALUES (?)`, Time(time.Now())))
+}
+
+// CheckReportSummary compares the report summary tables against the summary
+// computed from all of the orders in the database.  It returns a description
+// of each difference, in sorted order; the list is empty if they agree.
+func (tx Tx) CheckReportSummary() (problems []string) {
+	var (
+		have = tx.fetchReportSummary()
+		want = tx.scanReportSummary()
+	)
+	for k := range want.lines {
+		if _, ok := have.lines[k]; !ok {
+			have.lines[k] = reportSummaryTotals{}
+		}
+	}
+	for k, t := range have.lines {
+		if w := want.lines[k]; t != w {
+			problems = append(problems, fmt.Sprintf("report_summary product=%s source=%s coupon=%q valid=%v payment_type=%q member=%d option=%q line_amount=%d: quantity %d amount %d deductible %d, should be %d, %d, %d",
+				k.product, k.source, k.coupon, k.valid, k.paymentType, k.member, k.option, k.lineAmount,
+				t.count, t.amount, t.deductible, w.count, w.amount, w.deductible))
+		}
+	}
+	for k := range want.usage {
+		if _, ok := have.usage[k]; !ok {
+			have.usage[k] = reportSummaryTotals{}
+		}
+	}
+	for k, t := range have.usage {
+		if w := want.usage[k]; t != w {
+			problems = append(problems, fmt.Sprintf("report_usage_summary product=%s source=%s coupon=%q valid=%v payment_type=%q member=%d option=%q line_amount=%d event=%q: tickets %d amount %d, should be %d, %d",
+				k.product, k.source, k.coupon, k.valid, k.paymentType, k.member, k.option, k.lineAmount, k.event,
+				t.count, t.amount, w.count, w.amount))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// reportSummaryUsable returns whether RunReport can take statistics from the
+// report summary tables for the specified report definition.  It can't if the
+// definition has criteria that the tables don't cover, since the statistics
+// must reflect those criteria.  Nor can it if the tables haven't been built
+// by RebuildReportSummary (as recorded in report_summary_built).
+func (tx Tx) reportSummaryUsable(def *model.ReportDefinition) (usable bool) {
+	if def.Customer != "" || !def.CreatedAfter.IsZero() || !def.CreatedBefore.IsZero() || def.HasMinAmount ||
+		def.HasMaxAmount || len(def.Members) != 0 || def.AnyMember || len(def.Options) != 0 || def.GuestName != "" {
+		return false
+	}
+	panicOnError(tx.tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM report_summary_built)`).Scan(&usable))
+	return usable
+}
+
+// addReportSummaryStats adds the rows of the report summary tables into the
+// statistics and totals of the report.  Each row is treated as an order line,
+// with a quantity or ticket usage of its total.  (The order count can't be
+// taken from the tables, since an order's lines may be in several rows.)
+func (tx Tx) addReportSummaryStats(
+	def *model.ReportDefinition, result *model.ReportResults,
+	products map[model.ProductID]*reportProduct, usedAtEvents map[model.EventID]*model.ReportEventCount, amounts []int,
+) {
+	var rs = tx.fetchReportSummary()
+
+	for k, t := range rs.lines {
+		var ol = reportLine{pid: k.product, prod: products[k.product], qty: t.count, option: k.option,
+			order: &reportOrder{source: k.source, coupon: k.coupon, valid: k.valid, paymentType: k.paymentType, member: k.member}}
+
+		if ol.prod == nil {
+			continue
+		}
+		addSummarizedStats(def, &ol, result, usedAtEvents)
+		addScannedStats(def, &ol, k.lineAmount, result, amounts)
+		if tcount := lineMatches(def, &ol, critAll); tcount != 0 {
+			result.ItemCount += tcount
+			result.TotalAmount += float64(t.amount) / 100.0
+			result.TotalDeductible += float64(t.deductible) / 100.0
+			if ol.prod.ptype == model.ProdFee {
+				result.TotalFees += float64(t.amount) / 100.0
+			}
+		}
+	}
+	for k, t := range rs.usage {
+		var ol = reportLine{pid: k.product, prod: products[k.product], tusage: map[model.EventID]int{k.event: t.count}, option: k.option,
+			order: &reportOrder{source: k.source, coupon: k.coupon, valid: k.valid, paymentType: k.paymentType, member: k.member}}
+
+		if ol.prod == nil {
+			continue
+		}
+		addSummarizedStats(def, &ol, result, usedAtEvents)
+		addScannedStats(def, &ol, k.lineAmount, result, amounts)
+		if tcount := lineMatches(def, &ol, critAll); tcount != 0 {
+			result.ItemCount += tcount
+			result.TotalAmount += float64(t.amount) / float64(ol.prod.tcount) / 100.0
+		}
+	}
+}
+
+// countReportOrders returns the number of valid orders with lines that would
+// appear in a report with no criteria.  It is used instead of reading the lines
+// when the rest of such a report comes from the summary tables.
+func (tx Tx) countReportOrders() (count int) {
+	panicOnError(tx.tx.QueryRow(`
+SELECT COUNT(DISTINCT ol.orderid) FROM order_line ol JOIN ordert o ON o.id=ol.orderid JOIN product p ON p.id=ol.product
+WHERE o.valid AND (ol.quantity!=0 OR (p.type=? AND EXISTS (SELECT 1 FROM ticket t WHERE t.order_line=ol.id)))`,
+		model.ProdTicket).Scan(&count))
+	return count
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"scholacantorum.org/orders/model"
+)
+
+// savedReportColumns is the list of columns in the saved_report table.
+var savedReportColumns = `id, name, owner, query, schedule, recipients, created, last_run`
+
+// scanSavedReport scans a saved_report table row.
+func scanSavedReport(scanner interface{ Scan(...interface{}) error }, sr *model.SavedReport) (err error) {
+	var recipients string
+
+	err = scanner.Scan(&sr.ID, &sr.Name, &sr.Owner, &sr.Query, &sr.Schedule, &recipients,
+		(*Time)(&sr.Created), (*Time)(&sr.LastRun))
+	if recipients != "" {
+		sr.Recipients = strings.Split(recipients, ",")
+	}
+	return err
+}
+
+// FetchSavedReport returns the saved report with the specified ID, or nil if
+// it doesn't exist.
+func (tx Tx) FetchSavedReport(id model.SavedReportID) (sr *model.SavedReport) {
+	sr = new(model.SavedReport)
+	switch err := scanSavedReport(tx.tx.QueryRow(`SELECT `+savedReportColumns+` FROM saved_report WHERE id=?`, id), sr); err {
+	case nil:
+		return sr
+	case sql.ErrNoRows:
+		return nil
+	default:
+		panic(err)
+	}
+}
+
+// FetchSavedReports returns the saved reports owned by the specified user, or
+// all saved reports if owner is empty, in order by name.
+func (tx Tx) FetchSavedReports(owner string) (list []*model.SavedReport) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if owner != "" {
+		rows, err = tx.tx.Query(`SELECT `+savedReportColumns+` FROM saved_report WHERE owner=? ORDER BY name, id`, owner)
+	} else {
+		rows, err = tx.tx.Query(`SELECT ` + savedReportColumns + ` FROM saved_report ORDER BY name, id`)
+	}
+	panicOnError(err)
+	for rows.Next() {
+		var sr model.SavedReport
+		panicOnError(scanSavedReport(rows, &sr))
+		list = append(list, &sr)
+	}
+	panicOnError(rows.Err())
+	return list
+}
+
+// SaveSavedReport saves a saved report.
+func (tx Tx) SaveSavedReport(sr *model.SavedReport) {
+	var (
+		res sql.Result
+		err error
+	)
+	res, err = tx.tx.Exec(`INSERT OR REPLACE INTO saved_report (`+savedReportColumns+`) VALUES (?,?,?,?,?,?,?,?)`,
+		ID(sr.ID), sr.Name, sr.Owner, sr.Query, sr.Schedule, strings.Join(sr.Recipients, ","),
+		Time(sr.Created), Time(sr.LastRun))
+	panicOnError(err)
+	if sr.ID == 0 {
+		sr.ID = model.SavedReportID(lastInsertID(res))
+	}
+}
+
+// DeleteSavedReport deletes a saved report.
+func (tx Tx) DeleteSavedReport(sr *model.SavedReport) {
+	panicOnNoRows(tx.tx.Exec(`DELETE FROM saved_report WHERE id=?`, sr.ID))
+}
@@ -50,10 +50,20 @@ CREATE TABLE orderT (
     in_access boolean NOT NULL DEFAULT 0,
 
     -- Coupon code supplied by the customer (empty if none).
-    coupon text NOT NULL DEFAULT ''
+    coupon text NOT NULL DEFAULT '',
+
+    -- Flag indicating that the customer consented, when placing the order, to
+    -- receive marketing email (the newsletter).  The consent is withdrawn if
+    -- the email address is later listed in marketing_optout.
+    marketing_optin boolean NOT NULL DEFAULT 0,
+
+    -- Identifier of the customer record to which this order is linked, or
+    -- NULL if it hasn't been linked yet.  See api/customer.go.
+    customer_id integer REFERENCES customer
 );
 CREATE INDEX order_name_email_index ON orderT (name, email);
 CREATE INDEX order_email_index      ON orderT (email);
+CREATE INDEX order_customer_index   ON orderT (customer_id);
 
 -- The order_line table tracks lines of Schola Cantorum orders.  Every order has
 -- at least one line.
@@ -85,7 +95,28 @@ CREATE TABLE order_line (
     guest_email text NOT NULL DEFAULT '',
 
     -- The guest-selected option for this line, if the product has options.
-    option text NOT NULL DEFAULT ''
+    option text NOT NULL DEFAULT '',
+
+    -- The fair market value per unit, in cents, of the goods or services
+    -- received for this line, as of the time of sale.  The tax-deductible
+    -- portion of a registration or auction item is the amount by which its
+    -- price exceeds this.  -1 means that no fair market value was determined
+    -- (as for lines recorded before it was tracked), in which case no part of
+    -- the line is claimed to be deductible.
+    fmv integer NOT NULL DEFAULT -1,
+
+    -- The fund to which a donation is designated, or empty for an
+    -- undesignated donation.  It is one of the product's funds.
+    fund text NOT NULL DEFAULT '',
+
+    -- The tribute made by a donation:  "honor" or "memory", or empty if the
+    -- donation isn't a tribute gift.  The honoree is the person honored or
+    -- remembered.  The notify_name and notify_email are the person (e.g., a
+    -- family member) to whom notice of the tribute gift should be sent.
+    tribute      text NOT NULL DEFAULT '',
+    honoree      text NOT NULL DEFAULT '',
+    notify_name  text NOT NULL DEFAULT '',
+    notify_email text NOT NULL DEFAULT ''
 );
 CREATE INDEX order_line_order_index   ON order_line (orderid);
 CREATE INDEX order_line_product_index ON order_line (product);
@@ -134,7 +165,18 @@ CREATE TABLE product (
 
     -- Comma-separated list of options that the purchaser can choose from when
     -- purchasing this product.
-    options text NOT NULL DEFAULT ''
+    options text NOT NULL DEFAULT '',
+
+    -- Fair market value per unit, in cents, of the goods or services received
+    -- by the purchaser.  Relevant only for registrations and auction items,
+    -- which are partly tax-deductible, and required for them.  -1 means that
+    -- no fair market value has been determined (as for products created
+    -- before it was tracked), in which case no deduction is claimed.
+    fmv integer NOT NULL DEFAULT -1,
+
+    -- Comma-separated list of funds to which donors can designate donations
+    -- of this product.  Relevant only for donations.
+    funds text NOT NULL DEFAULT ''
 );
 
 -- The sku table lists all of the SKUs that have been, are, or will be for sale.
@@ -183,7 +225,11 @@ CREATE TABLE sku (
     -- product type, a zero value may mean that the product is free when
     -- purchased with this SKU, or it may mean that the product's price is
     -- variable and will be specified at the time of order (e.g. a donation).
-    price integer NOT NULL
+    price integer NOT NULL,
+
+    -- Fair market value per unit, in cents, for purchases made with this SKU.
+    -- Zero means the product's fair market value applies.
+    fmv integer NOT NULL DEFAULT 0
 );
 CREATE INDEX sku_product_index ON sku (product);
 
@@ -211,7 +257,20 @@ CREATE TABLE event (
     start text NOT NULL,
 
     -- Seating capacity of the event.  Zero means unlimited.
-    capacity integer NOT NULL DEFAULT 0
+    capacity integer NOT NULL DEFAULT 0,
+
+    -- Name of the venue where the event takes place (as it should be shown
+    -- to a customer on a printed ticket).  Empty if not specified.
+    venue text NOT NULL DEFAULT '',
+
+    -- Parking information for the event (as it should be shown to a customer
+    -- in a reminder email).  Empty if not specified.
+    parking text NOT NULL DEFAULT '',
+
+    -- Time the event was cancelled, or empty if it hasn't been.  Products
+    -- dedicated to a cancelled event are not offered for sale.  See the
+    -- event_cancellation table for details.
+    cancelled text NOT NULL DEFAULT ''
 );
 
 -- The product_event table specifies which products grant admission to which
@@ -300,7 +359,10 @@ CREATE TABLE payment (
     initial boolean NOT NULL,
 
     -- Amount of the payment, in cents.  Negative amounts indicate refunds.
-    amount integer NOT NULL
+    amount integer NOT NULL,
+
+    -- Stripe fingerprint of the card used for the payment, if known.
+    card text NOT NULL DEFAULT ''
 );
 CREATE INDEX payment_order_index ON payment (orderid);
 
@@ -347,3 +409,470 @@ CREATE TABLE card_email (
     -- Email address associated with the card.
     email text NOT NULL DEFAULT ''
 );
+
+-- The wallet_pass table lists the orders for which an Apple Wallet or Google
+-- Wallet pass has been issued, and when each was last changed.  Orders not
+-- listed here have never had a pass issued, so there's nothing to update when
+-- they change.
+CREATE TABLE wallet_pass (
+
+    -- Identifier of the order.
+    orderid integer PRIMARY KEY REFERENCES orderT ON DELETE CASCADE,
+
+    -- Timestamp of the most recent change to the order that affects the
+    -- content of its pass.  Apple Wallet devices ask for passes changed since
+    -- a given time.
+    updated text NOT NULL
+);
+
+-- The wallet_registration table lists the Apple Wallet devices that have asked
+-- to be notified of changes to passes.  See Apple's PassKit Web Service
+-- Reference.
+CREATE TABLE wallet_registration (
+
+    -- Device library identifier, assigned by the device.
+    device text NOT NULL,
+
+    -- Identifier of the order whose pass is registered.
+    orderid integer NOT NULL REFERENCES wallet_pass ON DELETE CASCADE,
+
+    -- Push token for the device, used when sending it a change notification
+    -- through the Apple Push Notification service.
+    push_token text NOT NULL,
+
+    PRIMARY KEY (device, orderid)
+);
+CREATE INDEX wallet_registration_order_index ON wallet_registration (orderid);
+
+-- The email_outbox table holds the email messages we send, both those waiting
+-- to be delivered and those that have been.  Messages are added to it in the
+-- same transaction as the change that prompts them, so that none are lost;
+-- the send-email-outbox command delivers them, retrying failures.
+CREATE TABLE email_outbox (
+
+    -- Unique identifier of the message.
+    id integer PRIMARY KEY, -- autoincrement
+
+    -- Identifier of the order the message is about, or NULL if it isn't
+    -- about an order.
+    orderid integer REFERENCES orderT ON DELETE CASCADE,
+
+    -- Time the message was queued.
+    created text NOT NULL,
+
+    -- Envelope sender and recipients of the message.  The recipients are
+    -- separated by commas.
+    sender     text NOT NULL,
+    recipients text NOT NULL,
+
+    -- Subject of the message, for display to office staff.
+    subject text NOT NULL DEFAULT '',
+
+    -- The complete message, with headers, as it is to be delivered.
+    message blob NOT NULL,
+
+    -- Number of failed delivery attempts.
+    attempts integer NOT NULL DEFAULT 0,
+
+    -- Time of the next delivery attempt.  This is empty if the message has
+    -- been delivered or has failed too many times to be retried.
+    next_attempt text NOT NULL DEFAULT '',
+
+    -- Time the message was delivered, or empty if it hasn't been.
+    sent text NOT NULL DEFAULT '',
+
+    -- Error from the most recent failed delivery attempt, if any.
+    error text NOT NULL DEFAULT ''
+);
+CREATE INDEX email_outbox_order_index        ON email_outbox (orderid);
+CREATE INDEX email_outbox_next_attempt_index ON email_outbox (next_attempt);
+
+-- The receipt_template table contains the templates for the email receipts
+-- sent for orders.  A template can be selected for orders containing a
+-- particular product, for orders containing a particular product type, or as
+-- the default.  Receipts for orders that no template applies to use a template
+-- built into the code.  See api/receipt_template.go for the data passed to the
+-- templates.
+CREATE TABLE receipt_template (
+
+    -- Unique identifier of the template.
+    id integer PRIMARY KEY, -- autoincrement
+
+    -- Description of the template, for office staff.
+    name text NOT NULL DEFAULT '',
+
+    -- Product type for which this template is used, or empty if it isn't
+    -- selected by product type.
+    product_type text NOT NULL DEFAULT '',
+
+    -- Product for which this template is used, or NULL if it isn't selected by
+    -- product.  At most one of product_type and product is set; if neither
+    -- is, this is the default template.  No two templates may have the same
+    -- selection.
+    product text REFERENCES product ON DELETE CASCADE,
+
+    -- Templates for the subject line, the HTML body, and the plain text body
+    -- of the receipt.  The subject and plain text templates are Go
+    -- text/templates; the HTML template is a Go html/template.  The plain text
+    -- template may be empty, in which case the receipt is HTML only.
+    subject text NOT NULL,
+    html    text NOT NULL,
+    text    text NOT NULL DEFAULT ''
+);
+
+-- The reminder_sent table records which orders have been sent reminders about
+-- which events, so that the send-reminders command sends each reminder only
+-- once.
+CREATE TABLE reminder_sent (
+
+    -- Identifier of the event.
+    event text NOT NULL REFERENCES event ON DELETE CASCADE,
+
+    -- Identifier of the order.
+    orderid integer NOT NULL REFERENCES orderT ON DELETE CASCADE,
+
+    -- Time the reminder was queued.
+    sent text NOT NULL,
+
+    PRIMARY KEY (event, orderid)
+);
+CREATE INDEX reminder_sent_order_index ON reminder_sent (orderid);
+
+-- The reminder_optout table lists the email addresses of customers who have
+-- asked not to receive event reminders.
+CREATE TABLE reminder_optout (
+
+    -- Email address of the customer.
+    email text PRIMARY KEY COLLATE NOCASE,
+
+    -- Time the customer opted out.
+    created text NOT NULL
+);
+
+-- The marketing_optout table lists the email addresses of customers who have
+-- asked to be removed from the mailing list.  An opt-out applies only to
+-- consents given before it: a later order with marketing_optin set puts the
+-- address back on the list.
+CREATE TABLE marketing_optout (
+
+    -- Email address of the customer.
+    email text PRIMARY KEY COLLATE NOCASE,
+
+    -- Time the customer (most recently) opted out.
+    created text NOT NULL
+);
+
+-- The event_cancellation table gives the terms under which events were
+-- cancelled.  When an event is cancelled, the holders of tickets dedicated to
+-- it are notified, and each such order is listed in the cancelled_order table
+-- until the office or the customer chooses what to do with the tickets:  a
+-- refund, a donation of their value, or an exchange for tickets to the
+-- rescheduled event.
+CREATE TABLE event_cancellation (
+
+    -- Identifier of the cancelled event.
+    event text PRIMARY KEY REFERENCES event ON DELETE CASCADE,
+
+    -- Time the event was cancelled, and the username of the person who did
+    -- it.
+    created  text NOT NULL,
+    username text NOT NULL,
+
+    -- Identifier of the event to which tickets can be exchanged, or NULL if
+    -- the event was not rescheduled.
+    rescheduled text REFERENCES event,
+
+    -- Identifier of the donation product to which ticket values can be
+    -- converted, or NULL if that option is not offered.
+    donation text REFERENCES product,
+
+    -- Additional text for the notice sent to ticket holders.
+    message text NOT NULL DEFAULT ''
+);
+
+-- The cancelled_order table lists the orders holding tickets to cancelled
+-- events, and what was done about them.
+CREATE TABLE cancelled_order (
+
+    -- Identifier of the cancelled event.
+    event text NOT NULL REFERENCES event_cancellation ON DELETE CASCADE,
+
+    -- Identifier of the order.
+    orderid integer NOT NULL REFERENCES orderT ON DELETE CASCADE,
+
+    -- Number of tickets on the order that were dedicated to the event, and
+    -- their value in cents.
+    tickets integer NOT NULL,
+    amount  integer NOT NULL,
+
+    -- What was done with the tickets:  "refund", "donation", "exchange", or
+    -- empty if nothing has been done yet.
+    outcome text NOT NULL DEFAULT '',
+
+    -- Time the outcome was chosen, or empty if it hasn't been.
+    resolved text NOT NULL DEFAULT '',
+
+    -- Username of the office staff member who chose the outcome, or empty if
+    -- it was chosen by the customer.
+    resolved_by text NOT NULL DEFAULT '',
+
+    -- For a refund to a card, "in progress" while the refund is being issued
+    -- through Stripe, and the Stripe error if it failed; empty once it has
+    -- been issued.  The office retries failed refunds.
+    refund_error text NOT NULL DEFAULT '',
+
+    PRIMARY KEY (event, orderid)
+);
+CREATE INDEX cancelled_order_order_index ON cancelled_order (orderid);
+
+-- The event_message table records the ad hoc email messages sent to the ticket
+-- holders of events (e.g., to announce a venue change).  The individual
+-- messages are in the email_outbox table; event_message_recipient links them.
+CREATE TABLE event_message (
+
+    -- Unique identifier.
+    id integer PRIMARY KEY, -- autoincrement
+
+    -- Identifier of the event whose ticket holders were sent the message.
+    event text NOT NULL REFERENCES event ON DELETE CASCADE,
+
+    -- Time the message was sent, and the username of the person who sent it.
+    created  text NOT NULL,
+    username text NOT NULL,
+
+    -- Subject and plain text body of the message.
+    subject text NOT NULL,
+    body    text NOT NULL,
+
+    -- Comma-separated list of the ticket classes whose holders were sent the
+    -- message ("General" for unrestricted tickets), or empty if it was sent
+    -- to the holders of all tickets.
+    classes text NOT NULL DEFAULT ''
+);
+CREATE INDEX event_message_event_index ON event_message (event);
+
+-- The event_message_recipient table lists the recipients of each event
+-- message.  Each distinct email address gets one copy of the message.
+CREATE TABLE event_message_recipient (
+
+    -- Identifier of the event message.
+    message integer NOT NULL REFERENCES event_message ON DELETE CASCADE,
+
+    -- Email address of the recipient.
+    email text NOT NULL COLLATE NOCASE,
+
+    -- Identifier of the (first) order through which the recipient holds
+    -- tickets to the event.
+    orderid integer NOT NULL REFERENCES orderT ON DELETE CASCADE,
+
+    -- Identifier of the copy of the message in the email outbox, from which
+    -- its delivery status can be seen.
+    outbox integer NOT NULL REFERENCES email_outbox ON DELETE CASCADE,
+
+    PRIMARY KEY (message, email)
+);
+
+-- The donor_statement_sent table records the year-end donation acknowledgement
+-- statements that have been emailed, so that each donor gets only one per
+-- year.
+CREATE TABLE donor_statement_sent (
+
+    -- Calendar year covered by the statement.
+    year integer NOT NULL,
+
+    -- Key identifying the donor:  their email address, in lower case.
+    donor text NOT NULL,
+
+    -- Time the statement was queued.
+    sent text NOT NULL,
+
+    -- Identifier of the statement message in the email outbox.
+    outbox integer REFERENCES email_outbox ON DELETE SET NULL,
+
+    PRIMARY KEY (year, donor)
+);
+
+-- The saved_report table contains named report definitions, which can be run
+-- on demand or delivered by email on a schedule.
+CREATE TABLE saved_report (
+
+    -- Unique identifier.
+    id integer PRIMARY KEY, -- autoincrement
+
+    -- Name of the report, for display.
+    name text NOT NULL,
+
+    -- Username of the person who owns the report.
+    owner text NOT NULL,
+
+    -- The report definition, in the URL query string form accepted by
+    -- /ofcapi/report.
+    query text NOT NULL DEFAULT '',
+
+    -- Delivery schedule:  "daily", "weekly", "event" (the morning after each
+    -- event), or empty if the report isn't delivered.
+    schedule text NOT NULL DEFAULT '',
+
+    -- Comma-separated list of email addresses to which scheduled deliveries
+    -- are sent.
+    recipients text NOT NULL DEFAULT '',
+
+    -- Time the report was created, and time of its last scheduled delivery
+    -- (empty if never delivered).
+    created  text NOT NULL,
+    last_run text NOT NULL DEFAULT ''
+);
+CREATE INDEX saved_report_owner_index ON saved_report (owner);
+
+-- The gl_account table maps categories of revenue and payments to the general
+-- ledger accounts to which they are posted in the accounting export.  See
+-- model.GLAccount for how mappings are matched.
+CREATE TABLE gl_account (
+
+    -- Unique identifier.
+    id integer PRIMARY KEY, -- autoincrement
+
+    -- What the mapping applies to: "revenue", "deferred", or "payment".
+    kind text NOT NULL,
+
+    -- Product type of the order lines to which a revenue or deferred mapping
+    -- applies.  Empty to match any product type.
+    product_type text NOT NULL DEFAULT '',
+
+    -- Series of the products to which a revenue or deferred mapping applies.
+    -- Empty to match any series.
+    series text NOT NULL DEFAULT '',
+
+    -- Payment type to which a payment mapping applies.  This is the payment
+    -- type shown in reports (e.g., "Card,Typed"), or the part of it before the
+    -- comma (e.g., "Card").  Empty to match any payment type.
+    payment_type text NOT NULL DEFAULT '',
+
+    -- Name of the account in the accounting software.
+    account text NOT NULL
+);
+
+-- The gl_exported_day table lists the days whose journal entries have been
+-- exported (once the day was over), and gl_journal_line gives the lines of
+-- those entries as exported.  Exported entries never change:  if the entry
+-- computed for an exported day later differs (e.g., because tickets were
+-- refunded), the difference is posted as an adjustment in the next export.
+-- See api/journal.go.
+CREATE TABLE gl_exported_day (
+
+    -- Day, in YYYY-MM-DD format (local time).
+    day text PRIMARY KEY
+);
+CREATE TABLE gl_journal_line (
+
+    -- Day of the journal entry.
+    day text NOT NULL REFERENCES gl_exported_day ON DELETE CASCADE,
+
+    -- Account, and amount in cents (positive for debits, negative for
+    -- credits).
+    account text NOT NULL,
+    amount  integer NOT NULL,
+
+    PRIMARY KEY (day, account)
+);
+
+-- The customer table contains the people who have placed orders.  Orders are
+-- linked to customers by a matching pass (see api/customer.go) that recognizes
+-- orders from the same person by email address, payment card, or name and
+-- address.  The contact details are those of the customer's most recent order
+-- that had them.
+CREATE TABLE customer (
+
+    -- Unique identifier.
+    id integer PRIMARY KEY, -- autoincrement
+
+    -- Contact details.  Any of these may be empty.
+    name    text NOT NULL DEFAULT '',
+    email   text NOT NULL DEFAULT '',
+    address text NOT NULL DEFAULT '',
+    city    text NOT NULL DEFAULT '',
+    state   text NOT NULL DEFAULT '',
+    zip     text NOT NULL DEFAULT '',
+    phone   text NOT NULL DEFAULT '',
+
+    -- Time the customer record was created.
+    created text NOT NULL
+);
+CREATE INDEX customer_email_index ON customer (email);
+
+-- The report_summary and report_usage_summary tables summarize the order lines
+-- in the database, so that the statistics returned by reports (the counts for
+-- each choice of each report criterion, and the totals) can be computed
+-- without scanning every order line.  They are maintained incrementally by SaveOrder, DeleteOrder,
+-- and DeleteOrderLine, and can be rebuilt from scratch (and checked against
+-- the order lines) with the rebuild-report-summary command.  See
+-- db/report_summary.go.
+--
+-- report_summary gives the totals of the lines with each combination of
+-- product, order details, and line details, for lines whose ticket usage isn't
+-- tracked (i.e., non-ticket lines and historical ticket lines without ticket
+-- rows).
+CREATE TABLE report_summary (
+
+    -- Product ordered on the lines.
+    product text NOT NULL,
+
+    -- Source, coupon code (upper case), validity, report payment type (see
+    -- db.ReportPaymentType), and member ID (0 if none) of the orders
+    -- containing the lines.
+    source       text    NOT NULL,
+    coupon       text    NOT NULL,
+    valid        boolean NOT NULL,
+    payment_type text    NOT NULL,
+    member       integer NOT NULL,
+
+    -- Option and amount (quantity times price, in cents) of each line.
+    option      text    NOT NULL,
+    line_amount integer NOT NULL,
+
+    -- Total quantity, amount, and tax-deductible amount of the lines (the
+    -- amounts in cents).
+    quantity   integer NOT NULL,
+    amount     integer NOT NULL,
+    deductible integer NOT NULL,
+
+    PRIMARY KEY (product, source, coupon, valid, payment_type, member, option, line_amount)
+);
+
+-- report_usage_summary gives the number of tickets used at each event (or
+-- unused, with an empty event) for each combination of product, order details,
+-- and line details, for lines whose ticket usage is tracked.
+CREATE TABLE report_usage_summary (
+
+    -- Product ordered on the lines.
+    product text NOT NULL,
+
+    -- Order and line details, as in report_summary.
+    source       text    NOT NULL,
+    coupon       text    NOT NULL,
+    valid        boolean NOT NULL,
+    payment_type text    NOT NULL,
+    member       integer NOT NULL,
+    option       text    NOT NULL,
+    line_amount  integer NOT NULL,
+
+    -- Event at which the tickets were used, or empty for unused tickets.
+    event text NOT NULL,
+
+    -- Number of tickets, and the total of their line prices (in cents).
+    -- Since a line's price covers the product's ticket count, the amount
+    -- paid for the tickets is this total divided by that count.
+    tickets integer NOT NULL,
+    amount  integer NOT NULL,
+
+    PRIMARY KEY (product, source, coupon, valid, payment_type, member, option, line_amount, event)
+);
+
+-- report_summary_built has a single row, recording when the report summary
+-- tables were last rebuilt from scratch.  Until it has a row, the tables are
+-- not trusted and reports compute their statistics from the order lines.
+CREATE TABLE report_summary_built (
+
+    -- Time the tables were rebuilt.
+    built text NOT NULL
+);
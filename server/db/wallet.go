@@ -0,0 +1,114 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"scholacantorum.org/orders/model"
+)
+
+// TouchWalletPass records that the wallet pass for the specified order has
+// changed (or has been issued for the first time) at the specified time.
+func (tx Tx) TouchWalletPass(orderID model.OrderID, updated time.Time) {
+	panicOnExecError(tx.tx.Exec(`INSERT OR REPLACE INTO wallet_pass (orderid, updated) VALUES (?,?)`,
+		orderID, Time(updated)))
+}
+
+// FetchWalletPassUpdated returns the time the wallet pass for the specified
+// order was last changed.  It returns a zero time if no pass has ever been
+// issued for the order.
+func (tx Tx) FetchWalletPassUpdated(orderID model.OrderID) (updated time.Time) {
+	switch err := tx.tx.QueryRow(`SELECT updated FROM wallet_pass WHERE orderid=?`, orderID).Scan((*Time)(&updated)); err {
+	case nil, sql.ErrNoRows:
+		return updated
+	default:
+		panic(err)
+	}
+}
+
+// SaveWalletRegistration registers a device to receive change notifications
+// for the wallet pass for the specified order.  It returns false if the device
+// was already registered.
+func (tx Tx) SaveWalletRegistration(device string, orderID model.OrderID, pushToken string) (created bool) {
+	var (
+		res   sql.Result
+		count int64
+		err   error
+	)
+	res, err = tx.tx.Exec(`INSERT OR IGNORE INTO wallet_registration (device, orderid, push_token) VALUES (?,?,?)`,
+		device, orderID, pushToken)
+	panicOnError(err)
+	count, err = res.RowsAffected()
+	panicOnError(err)
+	if count == 0 {
+		panicOnExecError(tx.tx.Exec(`UPDATE wallet_registration SET push_token=? WHERE device=? AND orderid=?`,
+			pushToken, device, orderID))
+	}
+	return count != 0
+}
+
+// DeleteWalletRegistration removes a device's registration for change
+// notifications for the wallet pass for the specified order.  It returns false
+// if there was no such registration.
+func (tx Tx) DeleteWalletRegistration(device string, orderID model.OrderID) bool {
+	var (
+		res   sql.Result
+		count int64
+		err   error
+	)
+	res, err = tx.tx.Exec(`DELETE FROM wallet_registration WHERE device=? AND orderid=?`, device, orderID)
+	panicOnError(err)
+	count, err = res.RowsAffected()
+	panicOnError(err)
+	return count != 0
+}
+
+// FetchWalletPushTokens returns the push tokens of all devices registered for
+// change notifications for the wallet pass for the specified order.
+func (tx Tx) FetchWalletPushTokens(orderID model.OrderID) (tokens []string) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`SELECT DISTINCT push_token FROM wallet_registration WHERE orderid=?`, orderID)
+	panicOnError(err)
+	for rows.Next() {
+		var token string
+		panicOnError(rows.Scan(&token))
+		tokens = append(tokens, token)
+	}
+	panicOnError(rows.Err())
+	return tokens
+}
+
+// FetchWalletDeviceOrders returns the IDs of the orders whose wallet passes
+// are registered on the specified device and have changed since the specified
+// time (or at all, if since is zero).  It also returns the latest change time
+// of any pass registered on the device.  Change times are truncated to whole
+// seconds, the granularity of the lastUpdated tags that since comes from.
+func (tx Tx) FetchWalletDeviceOrders(device string, since time.Time) (orders []model.OrderID, latest time.Time) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	rows, err = tx.tx.Query(`
+SELECT wp.orderid, wp.updated FROM wallet_pass wp, wallet_registration wr
+WHERE wr.device=? AND wr.orderid=wp.orderid ORDER BY wp.orderid`, device)
+	panicOnError(err)
+	for rows.Next() {
+		var (
+			orderID model.OrderID
+			updated time.Time
+		)
+		panicOnError(rows.Scan(&orderID, (*Time)(&updated)))
+		updated = updated.Truncate(time.Second)
+		if updated.After(since) {
+			orders = append(orders, orderID)
+		}
+		if updated.After(latest) {
+			latest = updated
+		}
+	}
+	panicOnError(rows.Err())
+	return orders, latest
+}
@@ -5,11 +5,13 @@ go 1.23.0
 toolchain go1.24.1
 
 require (
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/magefile/mage v1.8.0
 	github.com/mailru/easyjson v0.7.0
 	github.com/rothskeller/json v0.0.0-20190604180104-831a68af1667
 	github.com/skip2/go-qrcode v0.0.0-20190110000554-dc11ecdae0a9
 	github.com/stripe/stripe-go v70.15.0+incompatible
+	go.mozilla.org/pkcs7 v0.10.0
 	golang.org/x/oauth2 v0.0.0-20190523182746-aaccbc9213b0
 	google.golang.org/api v0.5.0
 	modernc.org/sqlite v1.37.0
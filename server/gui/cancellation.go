@@ -0,0 +1,193 @@
+package gui
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// cancellationItem describes one cancelled event for which the named order has
+// tickets, for the cancellation choice page.
+type cancellationItem struct {
+	Event          *model.Event
+	When           string
+	Cancellation   *model.EventCancellation
+	CancelledOrder *model.CancelledOrder
+	Amount         string
+	Rescheduled    *model.Event
+}
+
+// ShowCancellations handles GET /ticket/$token/cancellation requests, by
+// showing the customer who placed the named order what has become of its
+// tickets to cancelled events, and letting them choose an outcome for those
+// that are still pending.
+func ShowCancellations(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		items []*cancellationItem
+	)
+	if order = tx.FetchOrderByToken(token); order == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if items = fetchCancellationItems(tx, order); len(items) == 0 {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.Commit()
+	showCancellationPage(w, order, items, "")
+}
+
+// ChooseCancellationOutcome handles POST /ticket/$token/cancellation requests,
+// by carrying out the customer's choice of outcome for the named order's
+// tickets to a cancelled event.  The event and outcome form parameters give
+// the choice.
+func ChooseCancellationOutcome(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		items []*cancellationItem
+		item  *cancellationItem
+	)
+	if order = tx.FetchOrderByToken(token); order == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	items = fetchCancellationItems(tx, order)
+	for _, i := range items {
+		if string(i.Event.ID) == r.FormValue("event") {
+			item = i
+		}
+	}
+	if item == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	donation, reason, refundErr := api.ResolveCancellation(tx, item.Cancellation, item.CancelledOrder, order,
+		model.CancellationOutcome(r.FormValue("outcome")), "")
+	if reason != "" {
+		tx.Rollback()
+		w.WriteHeader(http.StatusBadRequest)
+		showCancellationPage(w, order, items, reason)
+		return
+	}
+	log.Printf("- RESOLVE CANCELLATION %s %s ORDER %s", item.Event.ID, item.CancelledOrder.Outcome, order.ToJSON(true))
+	if donation != nil {
+		log.Printf("- DONATION ORDER %s", donation.ToJSON(true))
+	}
+	if refundErr != nil {
+		reason = "We were unable to issue your refund.  The Schola Cantorum office has been notified and will complete it."
+	}
+	showCancellationPage(w, order, items, reason)
+	api.SendQueuedEmail()
+	api.UpdateWalletPasses(order)
+	api.UpdateGoogleSheet(order)
+	if donation != nil {
+		api.UpdateGoogleSheet(donation)
+	}
+}
+
+// fetchCancellationItems returns the cancelled events for which the order has
+// tickets.
+func fetchCancellationItems(tx db.Tx, order *model.Order) (items []*cancellationItem) {
+	for _, co := range tx.FetchOrderCancellations(order.ID) {
+		var item = cancellationItem{CancelledOrder: co, Event: tx.FetchEvent(co.Event)}
+
+		item.When = item.Event.Start.Format("Monday, January 2, 2006 at 3:04pm")
+		item.Amount = fmt.Sprintf("$%.2f", float64(co.Amount)/100.0)
+		if item.Cancellation = tx.FetchEventCancellation(co.Event); item.Cancellation == nil {
+			continue
+		}
+		if item.Cancellation.Rescheduled != "" {
+			item.Rescheduled = tx.FetchEvent(item.Cancellation.Rescheduled)
+		}
+		items = append(items, &item)
+	}
+	return items
+}
+
+// showCancellationPage renders the cancellation choice page.
+func showCancellationPage(w http.ResponseWriter, order *model.Order, items []*cancellationItem, reason string) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err := cancellationTemplate.Execute(w, map[string]interface{}{
+		"Order": order, "Items": items, "Error": reason,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+var cancellationTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>Schola Cantorum Event Cancellation</title>
+    <meta name="viewport" content="width=device-width,initial-scale=1,shrink-to-fit=no">
+    <style type="text/css"><!--
+body {
+  margin: 0;
+  font-family: Arial, Helvetica, sans-serif;
+}
+#header {
+  max-width: 600px;
+  margin: 0 auto 16px;
+  background-color: #0153A5;
+  color: white;
+  padding: 6px 12px;
+}
+h1 {
+  font-size: 20px;
+  margin: 0;
+}
+h2 {
+  font-size: 16px;
+}
+#body {
+  max-width: 600px;
+  margin: 0 auto;
+  padding: 0 12px;
+}
+.error {
+  color: red;
+}
+form {
+  margin: 8px 0;
+}
+    --></style>
+  </head>
+  <body>
+    <div id="header"><h1>Schola Cantorum Event Cancellation</h1></div>
+    <div id="body">
+      {{- if .Error }}
+      <p class="error">{{ .Error }}</p>
+      {{- end }}
+      {{- range .Items }}
+      <h2>{{ .Event.Name }}, {{ .When }}</h2>
+      {{- if .Cancellation.Message }}
+      <p>{{ .Cancellation.Message }}</p>
+      {{- end }}
+      {{- if and (eq .CancelledOrder.Outcome "refund") .CancelledOrder.RefundError }}
+      <p>Your refund for your {{ .CancelledOrder.Tickets }} ticket(s) for this event ({{ .Amount }}) is being processed by the Schola Cantorum office.</p>
+      {{- else if eq .CancelledOrder.Outcome "refund" }}
+      <p>Your {{ .CancelledOrder.Tickets }} ticket(s) for this event have been refunded ({{ .Amount }}).</p>
+      {{- else if eq .CancelledOrder.Outcome "donation" }}
+      <p>Thank you for donating the value of your {{ .CancelledOrder.Tickets }} ticket(s) for this event ({{ .Amount }}) to Schola Cantorum.  A confirmation has been emailed to you.</p>
+      {{- else if eq .CancelledOrder.Outcome "exchange" }}
+      <p>Your {{ .CancelledOrder.Tickets }} ticket(s) for this event have been exchanged for tickets to {{ with .Rescheduled }}{{ .Name }}{{ else }}the rescheduled event{{ end }}.  Your existing tickets and QR code are valid for the rescheduled event.</p>
+      {{- else }}
+      <p>This event has been cancelled.  Please let us know what you would like us to do with your {{ .CancelledOrder.Tickets }} ticket(s) ({{ .Amount }}):</p>
+      {{- if .Rescheduled }}
+      <form method="POST"><input type="hidden" name="event" value="{{ .Event.ID }}"><input type="hidden" name="outcome" value="exchange"><button type="submit">Exchange for tickets to {{ .Rescheduled.Name }}</button></form>
+      {{- end }}
+      {{- if .Cancellation.Donation }}
+      <form method="POST"><input type="hidden" name="event" value="{{ .Event.ID }}"><input type="hidden" name="outcome" value="donation"><button type="submit">Donate the value of my tickets</button></form>
+      {{- end }}
+      <form method="POST"><input type="hidden" name="event" value="{{ .Event.ID }}"><input type="hidden" name="outcome" value="refund"><button type="submit">Refund my tickets</button></form>
+      {{- end }}
+      {{- end }}
+    </div>
+  </body>
+</html>
+`))
@@ -0,0 +1,97 @@
+package gui
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ShowReminderOptOut handles GET /ticket/$token/noreminders requests, by
+// asking the customer who placed the named order to confirm that they don't
+// want event reminders.  (Opting out immediately on GET would let link
+// scanners in mail programs opt people out.)
+func ShowReminderOptOut(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		err   error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil || order.Email == "" {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.Commit()
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err = reminderOptOutTemplate.Execute(w, map[string]interface{}{"Order": order, "Done": false}); err != nil {
+		panic(err)
+	}
+}
+
+// ReminderOptOut handles POST /ticket/$token/noreminders requests, by
+// recording that the customer who placed the named order doesn't want event
+// reminders.  These requests come from the confirmation form shown by
+// ShowReminderOptOut, or directly from mail programs supporting one-click
+// opt-out.
+func ReminderOptOut(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		err   error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil || order.Email == "" {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.SaveReminderOptOut(order.Email, time.Now())
+	tx.Commit()
+	log.Printf("- REMINDER OPT-OUT %s (order %d)", order.Email, order.ID)
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err = reminderOptOutTemplate.Execute(w, map[string]interface{}{"Order": order, "Done": true}); err != nil {
+		panic(err)
+	}
+}
+
+var reminderOptOutTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>Schola Cantorum Event Reminders</title>
+    <meta name="viewport" content="width=device-width,initial-scale=1,shrink-to-fit=no">
+    <style type="text/css"><!--
+body {
+  margin: 0;
+  font-family: Arial, Helvetica, sans-serif;
+}
+#header {
+  max-width: 600px;
+  margin: 0 auto 16px;
+  background-color: #0153A5;
+  color: white;
+  padding: 6px 12px;
+}
+h1 {
+  font-size: 20px;
+  margin: 0;
+}
+#body {
+  max-width: 600px;
+  margin: 0 auto;
+  padding: 0 12px;
+}
+    --></style>
+  </head>
+  <body>
+    <div id="header"><h1>Schola Cantorum Event Reminders</h1></div>
+    <div id="body">
+      {{- if .Done }}
+      <p>We will no longer send event reminders to {{ .Order.Email }}.  You will still receive receipts for your orders.</p>
+      {{- else }}
+      <p>Schola Cantorum sends a reminder to {{ .Order.Email }} shortly before each event for which you have tickets.</p>
+      <form method="POST"><button type="submit">Stop Sending Reminders</button></form>
+      {{- end }}
+    </div>
+  </body>
+</html>
+`))
@@ -1,12 +1,14 @@
 package gui
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
 
 	"scholacantorum.org/orders/api"
 	"scholacantorum.org/orders/db"
 	"scholacantorum.org/orders/model"
+	"scholacantorum.org/orders/wallet"
 )
 
 // ShowTicketInfo handles GET /ticket/$token requests, by showing information
@@ -27,8 +29,46 @@ func ShowTicketInfo(tx db.Tx, w http.ResponseWriter, r *http.Request, token stri
 	}
 }
 
+// ShowTicketPDF handles GET /ticket/$token/pdf requests, by returning printable
+// tickets for the named order.  By default there is one page per event; with
+// the layout=order parameter, all tickets are on a single page.
+func ShowTicketPDF(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		pdf   []byte
+		err   error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.Commit()
+	if pdf, err = api.TicketPDF(order, r.FormValue("layout") != "order"); err != nil {
+		panic(err)
+	}
+	if pdf == nil {
+		http.Error(w, "404 Not Found: no unused tickets on this order", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="tickets-%d.pdf"`, order.ID))
+	w.Write(pdf)
+}
+
 var ticketInfoTemplate = template.Must(template.New("").Funcs(map[string]interface{}{
-	"inc": func(i int) int { return i + 1 },
+	"inc":          func(i int) int { return i + 1 },
+	"appleWallet":  wallet.AppleEnabled,
+	"googleWallet": wallet.GoogleEnabled,
+	"hasTickets": func(o *model.Order) bool {
+		for _, ol := range o.Lines {
+			for _, t := range ol.Tickets {
+				if t.Used.IsZero() {
+					return true
+				}
+			}
+		}
+		return false
+	},
 }).Parse(`<!DOCTYPE html>
 <html>
   <head>
@@ -72,6 +112,12 @@ h1 {
 }
 .entry {
   margin-left: 2em;
+}
+#print {
+  width: 100%;
+  max-width: 600px;
+  margin: 24px auto 12px;
+  padding: 0 6px;
 }
     --></style>
   </head>
@@ -166,6 +212,13 @@ h1 {
 	{{ end }}
       </div>
     {{ end }}
+    {{ if hasTickets . }}
+      <div id="print">
+        <a href="{{ .Token }}/pdf">Printable tickets (PDF)</a>
+        {{ if appleWallet }}<br><a href="{{ .Token }}/pkpass">Add to Apple Wallet</a>{{ end }}
+        {{ if googleWallet }}<br><a href="{{ .Token }}/googlewallet">Save to Google Wallet</a>{{ end }}
+      </div>
+    {{ end }}
   </body>
 </html>
 `))
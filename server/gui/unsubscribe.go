@@ -0,0 +1,96 @@
+package gui
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ShowUnsubscribe handles GET /ticket/$token/unsubscribe requests, by asking
+// the customer who placed the named order to confirm that they want to be
+// removed from the mailing list.  (Unsubscribing immediately on GET would let
+// link scanners in mail programs unsubscribe people.)
+func ShowUnsubscribe(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		err   error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil || order.Email == "" {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.Commit()
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err = unsubscribeTemplate.Execute(w, map[string]interface{}{"Order": order, "Done": false}); err != nil {
+		panic(err)
+	}
+}
+
+// Unsubscribe handles POST /ticket/$token/unsubscribe requests, by recording
+// that the customer who placed the named order wants to be removed from the
+// mailing list.  These requests come from the confirmation form shown by
+// ShowUnsubscribe.
+func Unsubscribe(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		err   error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil || order.Email == "" {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.SaveMarketingOptOut(order.Email, time.Now())
+	tx.Commit()
+	log.Printf("- UNSUBSCRIBE %s (order %d)", order.Email, order.ID)
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err = unsubscribeTemplate.Execute(w, map[string]interface{}{"Order": order, "Done": true}); err != nil {
+		panic(err)
+	}
+}
+
+var unsubscribeTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>Schola Cantorum Mailing List</title>
+    <meta name="viewport" content="width=device-width,initial-scale=1,shrink-to-fit=no">
+    <style type="text/css"><!--
+body {
+  margin: 0;
+  font-family: Arial, Helvetica, sans-serif;
+}
+#header {
+  max-width: 600px;
+  margin: 0 auto 16px;
+  background-color: #0153A5;
+  color: white;
+  padding: 6px 12px;
+}
+h1 {
+  font-size: 20px;
+  margin: 0;
+}
+#body {
+  max-width: 600px;
+  margin: 0 auto;
+  padding: 0 12px;
+}
+    --></style>
+  </head>
+  <body>
+    <div id="header"><h1>Schola Cantorum Mailing List</h1></div>
+    <div id="body">
+      {{- if .Done }}
+      <p>We have removed {{ .Order.Email }} from the Schola Cantorum mailing list.  You will still receive receipts for your orders, and reminders of events for which you have tickets.</p>
+      {{- else }}
+      <p>Schola Cantorum sends news of upcoming concerts and events to those on its mailing list.</p>
+      <form method="POST"><button type="submit">Remove {{ .Order.Email }} from the Mailing List</button></form>
+      {{- end }}
+    </div>
+  </body>
+</html>
+`))
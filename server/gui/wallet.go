@@ -0,0 +1,233 @@
+package gui
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+	"scholacantorum.org/orders/wallet"
+)
+
+// GetApplePass handles GET /ticket/$token/pkpass requests, by returning an
+// Apple Wallet pass for the named order.
+func GetApplePass(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order   *model.Order
+		updated time.Time
+		pkpass  []byte
+		err     error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil || !wallet.AppleEnabled() || !orderHasTickets(order) {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if updated = tx.FetchWalletPassUpdated(order.ID); updated.IsZero() {
+		updated = time.Now()
+		tx.TouchWalletPass(order.ID, updated)
+	}
+	api.Commit(tx)
+	if pkpass, err = wallet.ApplePass(order); err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.pkpass")
+	w.Header().Set("Content-Disposition", `attachment; filename="schola-tickets.pkpass"`)
+	w.Header().Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	w.Write(pkpass)
+}
+
+// GetGooglePass handles GET /ticket/$token/googlewallet requests, by
+// redirecting to the Google Wallet page that saves a pass for the named order.
+func GetGooglePass(tx db.Tx, w http.ResponseWriter, r *http.Request, token string) {
+	var (
+		order *model.Order
+		url   string
+		err   error
+	)
+	if order = tx.FetchOrderByToken(token); order == nil || !wallet.GoogleEnabled() || !orderHasTickets(order) {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if tx.FetchWalletPassUpdated(order.ID).IsZero() {
+		tx.TouchWalletPass(order.ID, time.Now())
+	}
+	api.Commit(tx)
+	if url, err = wallet.GoogleSaveURL(order); err != nil {
+		panic(err)
+	}
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+// RegisterWalletDevice handles POST
+// /ticket/wallet/v1/devices/$device/registrations/$passType/$serial requests,
+// from Apple Wallet devices asking to be notified of changes to a pass.
+func RegisterWalletDevice(tx db.Tx, w http.ResponseWriter, r *http.Request, device, passType, serial string) {
+	var (
+		order     *model.Order
+		pushToken string
+		err       error
+	)
+	if order = walletPassOrder(tx, w, r, passType, serial); order == nil {
+		return
+	}
+	err = json.NewReader(r.Body).Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "pushToken":
+			return json.StringHandler(func(s string) { pushToken = s })
+		default:
+			return json.IgnoreHandler()
+		}
+	}))
+	if err != nil || pushToken == "" {
+		api.BadRequestError(tx, w, "missing pushToken")
+		return
+	}
+	if tx.FetchWalletPassUpdated(order.ID).IsZero() {
+		tx.TouchWalletPass(order.ID, time.Now())
+	}
+	if tx.SaveWalletRegistration(device, order.ID, pushToken) {
+		api.Commit(tx)
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		api.Commit(tx)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// UnregisterWalletDevice handles DELETE
+// /ticket/wallet/v1/devices/$device/registrations/$passType/$serial requests,
+// from Apple Wallet devices that no longer want notifications for a pass.
+func UnregisterWalletDevice(tx db.Tx, w http.ResponseWriter, r *http.Request, device, passType, serial string) {
+	var order *model.Order
+
+	if order = walletPassOrder(tx, w, r, passType, serial); order == nil {
+		return
+	}
+	tx.DeleteWalletRegistration(device, order.ID)
+	api.Commit(tx)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListWalletDevicePasses handles GET
+// /ticket/wallet/v1/devices/$device/registrations/$passType requests, from
+// Apple Wallet devices asking which of their passes have changed.  The
+// passesUpdatedSince parameter is the lastUpdated tag from a previous call.
+func ListWalletDevicePasses(tx db.Tx, w http.ResponseWriter, r *http.Request, device, passType string) {
+	var (
+		since  time.Time
+		orders []model.OrderID
+		latest time.Time
+		tokens []string
+		jw     json.Writer
+	)
+	if passType != wallet.ApplePassTypeID() {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if tag, err := strconv.ParseInt(r.FormValue("passesUpdatedSince"), 10, 64); err == nil {
+		since = time.Unix(tag, 0)
+	}
+	orders, latest = tx.FetchWalletDeviceOrders(device, since)
+	for _, oid := range orders {
+		if order := tx.FetchOrder(oid); order != nil {
+			tokens = append(tokens, order.Token)
+		}
+	}
+	api.Commit(tx)
+	if len(tokens) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("serialNumbers", func() {
+			jw.Array(func() {
+				for _, token := range tokens {
+					jw.String(token)
+				}
+			})
+		})
+		jw.Prop("lastUpdated", strconv.FormatInt(latest.Unix(), 10))
+	})
+	jw.Close()
+}
+
+// GetWalletPass handles GET /ticket/wallet/v1/passes/$passType/$serial
+// requests, from Apple Wallet devices fetching the latest version of a pass.
+func GetWalletPass(tx db.Tx, w http.ResponseWriter, r *http.Request, passType, serial string) {
+	var (
+		order   *model.Order
+		updated time.Time
+		pkpass  []byte
+		err     error
+	)
+	if order = walletPassOrder(tx, w, r, passType, serial); order == nil {
+		return
+	}
+	updated = tx.FetchWalletPassUpdated(order.ID)
+	api.Commit(tx)
+	if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !updated.Truncate(time.Second).After(ims) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if pkpass, err = wallet.ApplePass(order); err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.pkpass")
+	w.Header().Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	w.Write(pkpass)
+}
+
+// LogWalletMessages handles POST /ticket/wallet/v1/log requests, from Apple
+// Wallet devices reporting problems with our web service.
+func LogWalletMessages(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	tx.Rollback()
+	json.NewReader(r.Body).Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "logs":
+			return json.ArrayHandler(func() json.Handlers {
+				return json.StringHandler(func(s string) { log.Printf("WALLET LOG: %s", s) })
+			})
+		default:
+			return json.IgnoreHandler()
+		}
+	}))
+	w.WriteHeader(http.StatusOK)
+}
+
+// walletPassOrder returns the order for the Apple Wallet pass with the
+// specified pass type and serial number, after verifying that the request
+// carries the pass's authentication token.  If the order can't be found or the
+// request isn't authorized, it sends an error and returns nil.
+func walletPassOrder(tx db.Tx, w http.ResponseWriter, r *http.Request, passType, serial string) (order *model.Order) {
+	if passType != wallet.ApplePassTypeID() || !wallet.AppleEnabled() {
+		api.NotFoundError(tx, w)
+		return nil
+	}
+	if order = tx.FetchOrderByToken(serial); order == nil {
+		api.NotFoundError(tx, w)
+		return nil
+	}
+	if r.Header.Get("Authorization") != "ApplePass "+wallet.AppleAuthToken(order) {
+		tx.Rollback()
+		http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	return order
+}
+
+// orderHasTickets returns whether the order includes any tickets.
+func orderHasTickets(order *model.Order) bool {
+	for _, ol := range order.Lines {
+		if len(ol.Tickets) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -16,17 +16,49 @@ var (
 var Default = Build
 
 func Build() {
-	mg.Deps(UpdateOrdersSheet, ResendReceipt, OrdersAPI)
+	mg.Deps(UpdateOrdersSheet, UpdateWalletPasses, SendEmailOutbox, SendReminders, DonorStatements, ResendReceipt, RunScheduledReports, ReconcileStripe, MatchCustomers, RebuildReportSummary, OrdersAPI)
 }
 
 func UpdateOrdersSheet() error {
 	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/update-orders-sheet", "./cmd/update-orders-sheet")
 }
 
+func UpdateWalletPasses() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/update-wallet-passes", "./cmd/update-wallet-passes")
+}
+
+func SendEmailOutbox() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/send-email-outbox", "./cmd/send-email-outbox")
+}
+
+func SendReminders() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/send-reminders", "./cmd/send-reminders")
+}
+
+func DonorStatements() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/donor-statements", "./cmd/donor-statements")
+}
+
 func ResendReceipt() error {
 	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/resend-receipt", "./cmd/resend-receipt")
 }
 
+func RunScheduledReports() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/run-scheduled-reports", "./cmd/run-scheduled-reports")
+}
+
+func ReconcileStripe() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/reconcile-stripe", "./cmd/reconcile-stripe")
+}
+
+func MatchCustomers() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/match-customers", "./cmd/match-customers")
+}
+
+func RebuildReportSummary() error {
+	return sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/rebuild-report-summary", "./cmd/rebuild-report-summary")
+}
+
 func OrdersAPI() error {
 	if err := sh.RunWith(linux, mg.GoCmd(), "build", "-o", "dist/ofcapi", "."); err != nil {
 		return err
@@ -39,7 +71,7 @@ func OrdersAPI() error {
 
 func InstallSandbox() error {
 	mg.Deps(Build)
-	if err := sh.Run("scp", "dist/update-orders-sheet", "dist/resend-receipt", "schola:bin"); err != nil {
+	if err := sh.Run("scp", "dist/update-orders-sheet", "dist/update-wallet-passes", "dist/send-email-outbox", "dist/send-reminders", "dist/donor-statements", "dist/resend-receipt", "dist/run-scheduled-reports", "dist/reconcile-stripe", "dist/match-customers", "dist/rebuild-report-summary", "schola:bin"); err != nil {
 		return err
 	}
 	if err := sh.Run("scp", "dist/ofcapi", "schola:orders-test.scholacantorum.org"); err != nil {
@@ -53,7 +85,7 @@ func InstallSandbox() error {
 
 func InstallProduction() error {
 	mg.Deps(Build)
-	if err := sh.Run("scp", "dist/update-orders-sheet", "dist/resend-receipt", "schola:bin"); err != nil {
+	if err := sh.Run("scp", "dist/update-orders-sheet", "dist/update-wallet-passes", "dist/send-email-outbox", "dist/send-reminders", "dist/donor-statements", "dist/resend-receipt", "dist/run-scheduled-reports", "dist/reconcile-stripe", "dist/match-customers", "dist/rebuild-report-summary", "schola:bin"); err != nil {
 		return err
 	}
 	if err := sh.Run("scp", "dist/ofcapi", "schola:orders.scholacantorum.org"); err != nil {
@@ -0,0 +1,38 @@
+// Package mail sends email messages.  The messages are built by the caller,
+// complete with headers; this package only delivers them.  The delivery method
+// is chosen by the mailTransport configuration variable:  "smtp" sends them
+// directly to an SMTP server, and anything else (including the default, empty
+// value) pipes them to the send-raw-email program.
+package mail
+
+import (
+	"scholacantorum.org/orders/config"
+)
+
+// A Sender delivers email messages.
+type Sender interface {
+	// Send delivers the message to the listed recipients.  The from
+	// address is the envelope sender; the message itself must contain all
+	// of the headers, including From and To.  It returns an error if the
+	// message could not be handed off for delivery.
+	Send(from string, to []string, message []byte) error
+}
+
+// NewSender returns the configured Sender.  If wait is false, the Sender may
+// return before delivery is finished, so that a CGI request can be answered
+// quickly; in that case, delivery errors that happen after the handoff are
+// not reported.  If wait is true, the Sender returns only after delivery has
+// succeeded or failed.
+func NewSender(wait bool) Sender {
+	switch config.Get("mailTransport") {
+	case "smtp":
+		return &SMTP{
+			Host:     config.Get("smtpHost"),
+			Port:     config.Get("smtpPort"),
+			Username: config.Get("smtpUsername"),
+			Password: config.Get("smtpPassword"),
+		}
+	default:
+		return &Sendmail{Program: config.Get("bin") + "/send-raw-email", Wait: wait}
+	}
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Sendmail is a Sender that pipes messages to a sendmail-like program, which
+// takes the recipient addresses as its command line arguments and the message
+// on its standard input.  The program chooses the envelope sender itself.
+type Sendmail struct {
+	// Program is the path of the program to run.
+	Program string
+	// Wait indicates that Send should wait for the program to finish, and
+	// report its failure (including anything it wrote to standard error).
+	// Otherwise, Send returns as soon as the message has been handed to
+	// the program.
+	Wait bool
+}
+
+// Send sends a message by running the program.
+func (s *Sendmail) Send(from string, to []string, message []byte) (err error) {
+	var (
+		cmd    = exec.Command(s.Program, to...)
+		pipe   io.WriteCloser
+		stderr bytes.Buffer
+	)
+	if s.Wait {
+		cmd.Stderr = &stderr
+		// Otherwise, it goes to /dev/null, which is necessary so that
+		// this parent process can exit (and the CGI caller can get a
+		// response) before the child finishes.
+	}
+	if pipe, err = cmd.StdinPipe(); err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	pipe.Write(message)
+	pipe.Close()
+	if s.Wait {
+		if err = cmd.Wait(); err != nil && stderr.Len() != 0 {
+			err = fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+	// Otherwise we are intentionally not waiting for the subprocess to
+	// finish.  The subprocess will continue as an orphan until the email
+	// is sent, and its zombie will be reaped by the init daemon.
+	return nil
+}
@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout is the limit on the time to connect to the SMTP server and on
+// the whole conversation with it, so that an unresponsive server can't hang
+// the request (or the outbox drain) that is sending mail.
+const smtpTimeout = time.Minute
+
+// SMTP is a Sender that delivers messages to an SMTP server.  It always waits
+// for the server to accept or reject the message.
+type SMTP struct {
+	// Host is the name of the SMTP server.
+	Host string
+	// Port is the port of the SMTP server.  The default is 587, the mail
+	// submission port.  If it is 465, the connection uses implicit TLS;
+	// otherwise, it is upgraded with STARTTLS when the server supports it.
+	Port string
+	// Username and Password are the credentials for authenticating to
+	// the server.  If Username is empty, no authentication is done.
+	// Credentials are sent only over TLS connections.
+	Username string
+	Password string
+}
+
+// Send sends a message through the SMTP server.
+func (s *SMTP) Send(from string, to []string, message []byte) (err error) {
+	var (
+		port   = s.Port
+		conn   net.Conn
+		client *smtp.Client
+		tlsc   = &tls.Config{ServerName: s.Host}
+		wc     io.WriteCloser
+	)
+	if s.Host == "" {
+		return errors.New("smtpHost is not configured")
+	}
+	if port == "" {
+		port = "587"
+	}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	if port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(s.Host, port), tlsc)
+	} else {
+		conn, err = dialer.Dial("tcp", net.JoinHostPort(s.Host, port))
+	}
+	if err != nil {
+		return err
+	}
+	if err = conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	if client, err = smtp.NewClient(conn, s.Host); err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && port != "465" {
+		if err = client.StartTLS(tlsc); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		// smtp.PlainAuth refuses to send the credentials unless the
+		// connection is encrypted (or to localhost).
+		if err = client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	if wc, err = client.Data(); err != nil {
+		return err
+	}
+	if _, err = wc.Write(stripBcc(message)); err != nil {
+		return err
+	}
+	if err = wc.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// stripBcc returns the message with any Bcc headers removed.  The Bcc
+// recipients are among the envelope recipients, but the header must not be
+// delivered to anyone.
+func stripBcc(message []byte) []byte {
+	var (
+		out    bytes.Buffer
+		inBcc  bool
+		header = message
+		body   []byte
+	)
+	if idx := bytes.Index(message, []byte("\r\n\r\n")); idx >= 0 {
+		header, body = message[:idx+2], message[idx+2:]
+	}
+	for _, line := range bytes.SplitAfter(header, []byte("\n")) {
+		if len(line) != 0 && (line[0] == ' ' || line[0] == '\t') {
+			// Continuation line; keep it with its header.
+		} else {
+			inBcc = len(line) >= 4 && strings.EqualFold(string(line[:4]), "bcc:")
+		}
+		if !inBcc {
+			out.Write(line)
+		}
+	}
+	out.Write(body)
+	return out.Bytes()
+}
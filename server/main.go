@@ -88,6 +88,63 @@ func router(w http.ResponseWriter, r *http.Request) {
 	switch shiftPath(r) {
 	case "ofcapi":
 		switch shiftPath(r) {
+		case "customer":
+			switch custID := shiftPathID(r); custID {
+			case 0:
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.ListCustomers(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			case -1:
+				api.NotFoundError(txh, w)
+			default:
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						ofcapi.GetCustomer(txh, w, r, model.CustomerID(custID))
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				case "merge":
+					switch r.Method {
+					case http.MethodPost:
+						ofcapi.MergeCustomer(txh, w, r, model.CustomerID(custID))
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			}
+		case "deferredRevenue":
+			switch shiftPath(r) {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.GetDeferredRevenue(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			default:
+				api.NotFoundError(txh, w)
+			}
+		case "donorStatement":
+			switch shiftPath(r) {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.GetDonorStatements(txh, w, r)
+				case http.MethodPost:
+					ofcapi.SendDonorStatements(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			default:
+				api.NotFoundError(txh, w)
+			}
 		case "event":
 			switch eventID := shiftPath(r); eventID {
 			case "":
@@ -97,6 +154,168 @@ func router(w http.ResponseWriter, r *http.Request) {
 				default:
 					methodNotAllowedError(txh, w)
 				}
+			default:
+				switch shiftPath(r) {
+				case "cancel":
+					switch shiftPath(r) {
+					case "":
+						switch r.Method {
+						case http.MethodPost:
+							ofcapi.CancelEvent(txh, w, r, model.EventID(eventID))
+						default:
+							methodNotAllowedError(txh, w)
+						}
+					default:
+						api.NotFoundError(txh, w)
+					}
+				case "cancellation":
+					switch orderID := shiftPathID(r); orderID {
+					case 0:
+						switch r.Method {
+						case http.MethodGet:
+							ofcapi.GetCancellationReport(txh, w, r, model.EventID(eventID))
+						default:
+							methodNotAllowedError(txh, w)
+						}
+					case -1:
+						api.NotFoundError(txh, w)
+					default:
+						switch shiftPath(r) {
+						case "":
+							switch r.Method {
+							case http.MethodPost:
+								ofcapi.ResolveCancelledOrder(txh, w, r, model.EventID(eventID), model.OrderID(orderID))
+							default:
+								methodNotAllowedError(txh, w)
+							}
+						case "refund":
+							switch r.Method {
+							case http.MethodPost:
+								ofcapi.RetryCancellationRefund(txh, w, r, model.EventID(eventID), model.OrderID(orderID))
+							default:
+								methodNotAllowedError(txh, w)
+							}
+						default:
+							api.NotFoundError(txh, w)
+						}
+					}
+				case "message":
+					switch messageID := shiftPathID(r); messageID {
+					case 0:
+						switch r.Method {
+						case http.MethodGet:
+							ofcapi.ListEventMessages(txh, w, r, model.EventID(eventID))
+						case http.MethodPost:
+							ofcapi.SendEventMessage(txh, w, r, model.EventID(eventID))
+						default:
+							methodNotAllowedError(txh, w)
+						}
+					case -1:
+						api.NotFoundError(txh, w)
+					default:
+						switch shiftPath(r) {
+						case "":
+							switch r.Method {
+							case http.MethodGet:
+								ofcapi.GetEventMessage(txh, w, r, model.EventID(eventID), model.EventMessageID(messageID))
+							default:
+								methodNotAllowedError(txh, w)
+							}
+						default:
+							api.NotFoundError(txh, w)
+						}
+					}
+				case "comps":
+					switch shiftPath(r) {
+					case "":
+						switch r.Method {
+						case http.MethodPost:
+							ofcapi.CreateComps(txh, w, r, model.EventID(eventID))
+						default:
+							methodNotAllowedError(txh, w)
+						}
+					default:
+						api.NotFoundError(txh, w)
+					}
+				case "doorlist":
+					switch shiftPath(r) {
+					case "":
+						switch r.Method {
+						case http.MethodGet:
+							ofcapi.GetDoorList(txh, w, r, model.EventID(eventID))
+						default:
+							methodNotAllowedError(txh, w)
+						}
+					default:
+						api.NotFoundError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			}
+		case "fundReport":
+			switch shiftPath(r) {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.GetFundReport(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			default:
+				api.NotFoundError(txh, w)
+			}
+		case "geoReport":
+			switch shiftPath(r) {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.GetGeoReport(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			default:
+				api.NotFoundError(txh, w)
+			}
+		case "glAccount":
+			switch glaID := shiftPathID(r); glaID {
+			case 0:
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.ListGLAccounts(txh, w, r)
+				case http.MethodPost:
+					ofcapi.CreateGLAccount(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			case -1:
+				api.NotFoundError(txh, w)
+			default:
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						ofcapi.GetGLAccount(txh, w, r, model.GLAccountID(glaID))
+					case http.MethodPut:
+						ofcapi.UpdateGLAccount(txh, w, r, model.GLAccountID(glaID))
+					case http.MethodDelete:
+						ofcapi.DeleteGLAccount(txh, w, r, model.GLAccountID(glaID))
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			}
+		case "glExport":
+			switch shiftPath(r) {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.GetGLExport(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
 			default:
 				api.NotFoundError(txh, w)
 			}
@@ -112,18 +331,61 @@ func router(w http.ResponseWriter, r *http.Request) {
 			default:
 				api.NotFoundError(txh, w)
 			}
+		case "mailingList":
+			switch shiftPath(r) {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.GetMailingList(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			default:
+				api.NotFoundError(txh, w)
+			}
 		case "order":
 			switch orderID := shiftPathID(r); orderID {
 			case 0, -1:
 				api.NotFoundError(txh, w)
 			default:
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						ofcapi.GetOrder(txh, w, r, model.OrderID(orderID))
+						// Used by members site to validate recording orders
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				case "emails":
+					switch shiftPath(r) {
+					case "":
+						switch r.Method {
+						case http.MethodGet:
+							ofcapi.ListOrderEmails(txh, w, r, model.OrderID(orderID))
+						case http.MethodPost:
+							ofcapi.ResendOrderReceipt(txh, w, r, model.OrderID(orderID))
+						default:
+							methodNotAllowedError(txh, w)
+						}
+					default:
+						api.NotFoundError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			}
+		case "paceReport":
+			switch shiftPath(r) {
+			case "":
 				switch r.Method {
 				case http.MethodGet:
-					ofcapi.GetOrder(txh, w, r, model.OrderID(orderID))
-					// Used by members site to validate recording orders
+					ofcapi.GetPaceReport(txh, w, r)
 				default:
 					methodNotAllowedError(txh, w)
 				}
+			default:
+				api.NotFoundError(txh, w)
 			}
 		case "product":
 			switch productID := shiftPath(r); productID {
@@ -137,6 +399,53 @@ func router(w http.ResponseWriter, r *http.Request) {
 			default:
 				api.NotFoundError(txh, w)
 			}
+		case "receiptTemplate":
+			switch rtID := shiftPath(r); rtID {
+			case "":
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.ListReceiptTemplates(txh, w, r)
+				case http.MethodPost:
+					ofcapi.CreateReceiptTemplate(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			case "preview":
+				switch r.Method {
+				case http.MethodGet, http.MethodPost:
+					ofcapi.PreviewReceiptTemplate(txh, w, r, 0)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			default:
+				id, err := strconv.Atoi(rtID)
+				if err != nil || id < 1 {
+					api.NotFoundError(txh, w)
+					return
+				}
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						ofcapi.GetReceiptTemplate(txh, w, r, model.ReceiptTemplateID(id))
+					case http.MethodPut:
+						ofcapi.UpdateReceiptTemplate(txh, w, r, model.ReceiptTemplateID(id))
+					case http.MethodDelete:
+						ofcapi.DeleteReceiptTemplate(txh, w, r, model.ReceiptTemplateID(id))
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				case "preview":
+					switch r.Method {
+					case http.MethodGet:
+						ofcapi.PreviewReceiptTemplate(txh, w, r, model.ReceiptTemplateID(id))
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			}
 		case "report":
 			switch shiftPath(r) {
 			case "":
@@ -149,6 +458,36 @@ func router(w http.ResponseWriter, r *http.Request) {
 			default:
 				api.NotFoundError(txh, w)
 			}
+		case "savedReport":
+			switch srID := shiftPathID(r); srID {
+			case 0:
+				switch r.Method {
+				case http.MethodGet:
+					ofcapi.ListSavedReports(txh, w, r)
+				case http.MethodPost:
+					ofcapi.CreateSavedReport(txh, w, r)
+				default:
+					methodNotAllowedError(txh, w)
+				}
+			case -1:
+				api.NotFoundError(txh, w)
+			default:
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						ofcapi.GetSavedReport(txh, w, r, model.SavedReportID(srID))
+					case http.MethodPut:
+						ofcapi.UpdateSavedReport(txh, w, r, model.SavedReportID(srID))
+					case http.MethodDelete:
+						ofcapi.DeleteSavedReport(txh, w, r, model.SavedReportID(srID))
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			}
 		default:
 			api.NotFoundError(txh, w)
 		}
@@ -337,6 +676,65 @@ func router(w http.ResponseWriter, r *http.Request) {
 		switch token := shiftPath(r); token {
 		case "":
 			api.NotFoundError(txh, w)
+		case "wallet":
+			// Apple Wallet PassKit web service.
+			switch shiftPath(r) {
+			case "v1":
+				switch shiftPath(r) {
+				case "devices":
+					device := shiftPath(r)
+					if device == "" || shiftPath(r) != "registrations" {
+						api.NotFoundError(txh, w)
+						return
+					}
+					switch passType := shiftPath(r); passType {
+					case "":
+						api.NotFoundError(txh, w)
+					default:
+						switch serial := shiftPath(r); serial {
+						case "":
+							switch r.Method {
+							case http.MethodGet:
+								gui.ListWalletDevicePasses(txh, w, r, device, passType)
+							default:
+								methodNotAllowedError(txh, w)
+							}
+						default:
+							switch r.Method {
+							case http.MethodPost:
+								gui.RegisterWalletDevice(txh, w, r, device, passType, serial)
+							case http.MethodDelete:
+								gui.UnregisterWalletDevice(txh, w, r, device, passType, serial)
+							default:
+								methodNotAllowedError(txh, w)
+							}
+						}
+					}
+				case "passes":
+					passType, serial := shiftPath(r), shiftPath(r)
+					if passType == "" || serial == "" || shiftPath(r) != "" {
+						api.NotFoundError(txh, w)
+						return
+					}
+					switch r.Method {
+					case http.MethodGet:
+						gui.GetWalletPass(txh, w, r, passType, serial)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				case "log":
+					switch r.Method {
+					case http.MethodPost:
+						gui.LogWalletMessages(txh, w, r)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			default:
+				api.NotFoundError(txh, w)
+			}
 		default:
 			switch shiftPath(r) {
 			case "":
@@ -346,6 +744,84 @@ func router(w http.ResponseWriter, r *http.Request) {
 				default:
 					methodNotAllowedError(txh, w)
 				}
+			case "pdf":
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						gui.ShowTicketPDF(txh, w, r, token)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			case "cancellation":
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						gui.ShowCancellations(txh, w, r, token)
+					case http.MethodPost:
+						gui.ChooseCancellationOutcome(txh, w, r, token)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			case "noreminders":
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						gui.ShowReminderOptOut(txh, w, r, token)
+					case http.MethodPost:
+						gui.ReminderOptOut(txh, w, r, token)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			case "unsubscribe":
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						gui.ShowUnsubscribe(txh, w, r, token)
+					case http.MethodPost:
+						gui.Unsubscribe(txh, w, r, token)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			case "pkpass":
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						gui.GetApplePass(txh, w, r, token)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
+			case "googlewallet":
+				switch shiftPath(r) {
+				case "":
+					switch r.Method {
+					case http.MethodGet:
+						gui.GetGooglePass(txh, w, r, token)
+					default:
+						methodNotAllowedError(txh, w)
+					}
+				default:
+					api.NotFoundError(txh, w)
+				}
 			default:
 				api.NotFoundError(txh, w)
 			}
@@ -6,6 +6,52 @@ import (
 	"time"
 )
 
+type CustomerID int
+
+// A Customer is a person who has placed orders.  The contact details are those
+// of the customer's most recent order that had them.
+type Customer struct {
+	ID      CustomerID
+	Name    string
+	Email   string
+	Address string
+	City    string
+	State   string
+	Zip     string
+	Phone   string
+	Created time.Time
+}
+
+type EmailID int
+
+// An Email is a message in the email outbox.
+type Email struct {
+	ID          EmailID
+	Order       OrderID // zero if the message isn't about an order
+	Created     time.Time
+	From        string
+	To          []string
+	Subject     string
+	Message     []byte
+	Attempts    int       // number of failed delivery attempts
+	NextAttempt time.Time // zero if delivered or abandoned
+	Sent        time.Time // zero if not delivered
+	Error       string    // from the most recent failed attempt
+}
+
+// Status returns "sent", "queued", or "failed", describing the delivery
+// status of the message.
+func (e *Email) Status() string {
+	switch {
+	case !e.Sent.IsZero():
+		return "sent"
+	case !e.NextAttempt.IsZero():
+		return "queued"
+	default:
+		return "failed"
+	}
+}
+
 type EventID string
 
 type Event struct {
@@ -15,8 +61,116 @@ type Event struct {
 	Series    string
 	Start     time.Time
 	Capacity  int
+	Venue     string
+	Parking   string
+	Cancelled time.Time // zero if not cancelled
+}
+
+// An EventCancellation gives the terms under which an event was cancelled.
+type EventCancellation struct {
+	Event       EventID
+	Created     time.Time
+	Username    string
+	Rescheduled EventID   // event to which tickets can be exchanged; empty if none
+	Donation    ProductID // product for donation of ticket value; empty if none
+	Message     string    // added to the notice sent to ticket holders
+}
+
+type CancellationOutcome string
+
+const (
+	// CancelPending indicates that no choice has yet been made about an
+	// order's tickets to a cancelled event.
+	CancelPending CancellationOutcome = ""
+
+	// CancelRefund indicates that the value of the tickets was refunded.
+	CancelRefund = "refund"
+
+	// CancelDonation indicates that the value of the tickets was converted
+	// to a donation.
+	CancelDonation = "donation"
+
+	// CancelExchange indicates that the tickets were exchanged for tickets
+	// to the rescheduled event.
+	CancelExchange = "exchange"
+)
+
+// A CancelledOrder records an order's tickets to a cancelled event and what
+// was done about them.
+type CancelledOrder struct {
+	Event      EventID
+	Order      OrderID
+	Tickets    int // number of tickets to the cancelled event
+	Amount     int // value of those tickets, in cents
+	Outcome    CancellationOutcome
+	Resolved   time.Time // zero if pending
+	ResolvedBy string    // username, or empty if chosen by the customer
+
+	// RefundError is set, for an outcome of CancelRefund, while the card
+	// refund is being issued through Stripe (to RefundInProgress), and
+	// afterward if it failed (to the error).  It is empty once the refund
+	// has been issued, and for refunds not made to a card.
+	RefundError string
+}
+
+// RefundInProgress is the value of CancelledOrder.RefundError while the card
+// refund is being issued.
+const RefundInProgress = "in progress"
+
+type EventMessageID int
+
+// An EventMessage is an ad hoc email message sent to the ticket holders of an
+// event.
+type EventMessage struct {
+	ID         EventMessageID
+	Event      EventID
+	Created    time.Time
+	Username   string
+	Subject    string
+	Body       string   // plain text
+	Classes    []string // ticket classes ("General" for unrestricted); nil for all
+	Recipients []*EventMessageRecipient
+}
+
+// An EventMessageRecipient is one recipient of an EventMessage.
+type EventMessageRecipient struct {
+	Email  string
+	Order  OrderID
+	Outbox EmailID // copy of the message in the email outbox
+}
+
+type GLAccountID int
+
+// A GLAccount maps a category of revenue or payments to the general ledger
+// account to which it is posted in the accounting export.  A mapping with an
+// empty ProductType, Series, or PaymentType matches any value of it; when
+// several mappings match, the most specific one is used.
+type GLAccount struct {
+	ID          GLAccountID
+	Kind        GLAccountKind
+	ProductType ProductType // revenue and deferred mappings only
+	Series      string      // revenue and deferred mappings only
+	PaymentType string      // payment mappings only; as in reports ("Card,Typed") or its category ("Card")
+	Account     string      // name of the account in the accounting software
 }
 
+// GLAccountKind identifies what a GLAccount mapping applies to.
+type GLAccountKind string
+
+const (
+	// GLRevenue maps order lines to the revenue account that is credited
+	// when the revenue is earned.
+	GLRevenue GLAccountKind = "revenue"
+
+	// GLDeferred maps order lines to the deferred revenue account that is
+	// credited when they are paid for before the events they are for.
+	GLDeferred = "deferred"
+
+	// GLPayment maps payments to the account that is debited when they are
+	// received: cash, Stripe clearing, check deposits, etc.
+	GLPayment = "payment"
+)
+
 type OrderID int
 
 type OrderSource string
@@ -43,44 +197,102 @@ const (
 	OrderInPerson = "inperson"
 )
 
+// CompCoupon is the coupon code of complimentary ticket orders.  A product can
+// be given as a comp if it has an office SKU with this coupon and a zero price.
+const CompCoupon = "COMP"
+
 type Order struct {
-	ID           OrderID
-	Token        string
-	Valid        bool
-	SaveForReuse bool
-	Source       OrderSource
-	Name         string
-	Email        string
-	Address      string
-	City         string
-	State        string
-	Zip          string
-	Phone        string
-	Customer     string
-	Member       int
-	Created      time.Time
-	CNote        string
-	ONote        string
-	InAccess     bool
-	Coupon       string
-	Lines        []*OrderLine
-	Payments     []*Payment
+	ID             OrderID
+	Token          string
+	Valid          bool
+	SaveForReuse   bool
+	Source         OrderSource
+	Name           string
+	Email          string
+	Address        string
+	City           string
+	State          string
+	Zip            string
+	Phone          string
+	Customer       string
+	Member         int
+	Created        time.Time
+	CNote          string
+	ONote          string
+	InAccess       bool
+	Coupon         string
+	MarketingOptIn bool       // customer consented to marketing email
+	CustomerID     CustomerID // zero if not yet linked to a customer
+	Lines          []*OrderLine
+	Payments       []*Payment
+	CoverFee       int // not persistent; input only
 }
 
 type OrderLineID int
 
 type OrderLine struct {
-	ID         OrderLineID
-	Product    *Product
-	Quantity   int
-	Price      int
-	GuestName  string
-	GuestEmail string
-	Option     string
-	Tickets    []*Ticket
-	Used       int     // not persistent; input only
-	UsedAt     EventID // not persistent; input only
-	Error      string  // not persistent; output only
+	ID          OrderLineID
+	Product     *Product
+	Quantity    int
+	Price       int
+	GuestName   string
+	GuestEmail  string
+	Option      string
+	FMV         int    // fair market value per unit, as of the sale; -1 if not determined
+	Fund        string // fund to which a donation is designated
+	Tribute     TributeType
+	Honoree     string // person honored or remembered by a tribute gift
+	NotifyName  string // person to be notified of a tribute gift
+	NotifyEmail string // email address of that person
+	Tickets     []*Ticket
+	Used        int     // not persistent; input only
+	UsedAt      EventID // not persistent; input only
+	Error       string  // not persistent; output only
+}
+
+// TributeType identifies the kind of tribute made by a donation, if any.
+type TributeType string
+
+const (
+	// TributeNone is the tribute type of an ordinary donation.
+	TributeNone TributeType = ""
+
+	// TributeHonor is the tribute type of a donation made in honor of
+	// someone.
+	TributeHonor = "honor"
+
+	// TributeMemory is the tribute type of a donation made in memory of
+	// someone.
+	TributeMemory = "memory"
+)
+
+// Deductible returns the tax-deductible portion of the amount paid for the
+// order line.  Donations are fully deductible.  Registrations and auction
+// items are deductible to the extent that their price exceeds the fair market
+// value of what was received; if that hasn't been determined, no deduction is
+// claimed for them.  Everything else, including a covered processing fee, is
+// a purchase, and not deductible.
+func (ol *OrderLine) Deductible() int {
+	switch ol.Product.Type {
+	case ProdDonation:
+		return ol.Price * ol.Quantity
+	case ProdRegistration, ProdAuctionItem:
+		if ol.FMV >= 0 && ol.Price > ol.FMV {
+			return (ol.Price - ol.FMV) * ol.Quantity
+		}
+	}
+	return 0
+}
+
+// GoodsValue returns our good-faith estimate of the fair market value of the
+// goods or services received for a registration or auction item order line.
+// If their fair market value hasn't been determined, the whole amount paid is
+// treated as their value, consistent with Deductible claiming no deduction.
+func (ol *OrderLine) GoodsValue() int {
+	if ol.FMV < 0 {
+		return ol.Price * ol.Quantity
+	}
+	return ol.FMV * ol.Quantity
 }
 
 func (ol *OrderLine) TicketsUsed() (used int) {
@@ -122,6 +334,7 @@ type Payment struct {
 	Method   string
 	Stripe   string
 	StripePM string
+	Card     string // Stripe card fingerprint, if known
 	Created  time.Time
 	Amount   int
 }
@@ -186,6 +399,10 @@ const (
 
 	// ProdRegistration is a registration for an event (generally the gala).
 	ProdRegistration = "registration"
+
+	// ProdFee is a card processing fee that the customer chose to cover.
+	// It is added to an order by the server, never chosen directly.
+	ProdFee = "fee"
 )
 
 type Product struct {
@@ -198,6 +415,8 @@ type Product struct {
 	TicketCount int
 	TicketClass string
 	Options     []string
+	FMV         int      // fair market value of goods/services received, per unit; -1 if not determined
+	Funds       []string // funds to which donations can be designated
 	SKUs        []*SKU
 	Events      []ProductEvent
 }
@@ -207,6 +426,20 @@ type ProductEvent struct {
 	Event    *Event
 }
 
+type ReceiptTemplateID int
+
+// A ReceiptTemplate gives the subject and body of the email receipts for
+// orders containing a particular product or product type.
+type ReceiptTemplate struct {
+	ID          ReceiptTemplateID
+	Name        string      // description for office staff
+	ProductType ProductType // empty if not selected by product type
+	Product     ProductID   // empty if not selected by product
+	Subject     string      // text/template
+	HTML        string      // html/template
+	Text        string      // text/template; empty for HTML-only
+}
+
 type Session struct {
 	Token      string
 	Username   string
@@ -227,6 +460,7 @@ type SKU struct {
 	SalesStart time.Time
 	SalesEnd   time.Time
 	Price      int
+	FMV        int // overrides Product.FMV if nonzero
 }
 
 // InSalesRange returns -1 if the specified time is before the sales range of
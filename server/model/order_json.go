@@ -204,6 +204,76 @@ func encodeOrderLine(out *jwriter.Writer, in *OrderLine, log bool) {
 		}
 		out.String(in.Option)
 	}
+	if in.FMV >= 0 {
+		const prefix string = ",\"fmv\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.FMV))
+	}
+	if in.Fund != "" {
+		const prefix string = ",\"fund\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(in.Fund)
+	}
+	if in.Tribute != "" {
+		const prefix string = ",\"tribute\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Tribute))
+	}
+	if in.Honoree != "" {
+		const prefix string = ",\"honoree\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(in.Honoree)
+	}
+	if in.NotifyName != "" {
+		const prefix string = ",\"notifyName\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(in.NotifyName)
+	}
+	if in.NotifyEmail != "" {
+		const prefix string = ",\"notifyEmail\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(in.NotifyEmail)
+	}
+	if in.Product != nil && in.Deductible() != 0 {
+		const prefix string = ",\"deductible\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(in.Deductible())
+	}
 	if len(in.Tickets) != 0 {
 		const prefix string = ",\"tickets\":"
 		if first {
@@ -399,6 +469,16 @@ func encodeOrder(out *jwriter.Writer, in *Order, log bool) {
 		}
 		out.Int(int(in.Member))
 	}
+	if in.CustomerID != 0 {
+		const prefix string = ",\"customerID\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.CustomerID))
+	}
 	if !in.Created.IsZero() {
 		const prefix string = ",\"created\":"
 		if first {
@@ -449,6 +529,16 @@ func encodeOrder(out *jwriter.Writer, in *Order, log bool) {
 		}
 		out.String(string(in.Coupon))
 	}
+	if in.MarketingOptIn {
+		const prefix string = ",\"marketingOptIn\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Bool(bool(in.MarketingOptIn))
+	}
 	if len(in.Lines) != 0 {
 		const prefix string = ",\"lines\":"
 		if first {
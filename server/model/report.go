@@ -52,6 +52,47 @@ type ReportDefinition struct {
 	// all orders regardless of ticket usage.  An empty string on the list
 	// includes orders with unused tickets.
 	UsedAtEvents []EventID
+
+	// MinAmount and MaxAmount specify the range of line amounts (quantity
+	// times price, in cents, and negative for refunds) of lines to include
+	// in the report.  Each applies only if the corresponding HasMinAmount
+	// or HasMaxAmount is true; otherwise there is no limit.
+	HasMinAmount bool
+	MinAmount    int
+	HasMaxAmount bool
+	MaxAmount    int
+
+	// OrderValid is a list of order validity flags to be included in the
+	// report.  An empty list means valid orders only.
+	OrderValid []bool
+
+	// Members is a list of member IDs (from the members web site) whose
+	// orders should be included in the report.  Including 0 in the list
+	// includes orders not placed by members.  If AnyMember is true, orders
+	// placed by any member are included.  An empty list and a false
+	// AnyMember mean all orders regardless of member.
+	Members   []int
+	AnyMember bool
+
+	// ProductTypes is a list of product types to be included in the
+	// report.  An empty list means all product types.
+	ProductTypes []ProductType
+
+	// Series is a list of product series to be included in the report.
+	// An empty list means all series.  Including an empty string in the
+	// list includes products not associated with a series.
+	Series []string
+
+	// Options is a list of product options (as chosen on order lines) to
+	// be included in the report.  An empty list means all lines regardless
+	// of option.  Including an empty string in the list includes lines with
+	// no option.
+	Options []string
+
+	// GuestName specifies the guest(s) whose lines should be included in
+	// the report.  It is a case-insensitive substring matched against the
+	// guest name of the line.  An empty string means all lines.
+	GuestName string
 }
 
 // ReportResults contains the results of running a report.
@@ -69,9 +110,19 @@ type ReportResults struct {
 	// dollar.
 	TotalAmount float64
 
+	// TotalDeductible gives the sum of the tax-deductible portions (in
+	// dollars) of the lines matching the report criteria.
+	TotalDeductible float64
+
+	// TotalFees gives the sum of the card processing fees (in dollars)
+	// covered by customers on the lines matching the report criteria.  They
+	// are included in TotalAmount.
+	TotalFees float64
+
 	// Lines gives the matching report lines.  It is nil if no report
-	// criteria were given, or if the criteria match too many lines.  It is
-	// an empty slice if no purchases match the report criteria.
+	// criteria were given, or if the criteria match too many lines (except
+	// from Tx.RunFullReport, which has no limit).  It is an empty slice if
+	// no purchases match the report criteria.
 	Lines []*ReportLine
 
 	// OrderSources gives, for each order source, the number of results that
@@ -104,6 +155,37 @@ type ReportResults struct {
 	// only one selected.  There will be an entry for "" representing unused
 	// tickets.
 	UsedAtEvents []*ReportEventCount
+
+	// Amounts gives, for each of a fixed set of line amount ranges, the
+	// number of results that would match all of the other report criteria
+	// if that range were the one selected.
+	Amounts []*ReportAmountCount
+
+	// OrderValid gives, for each order validity flag, the number of
+	// results that would match all of the other report criteria if that
+	// flag were the only one selected.
+	OrderValid map[bool]int
+
+	// Members gives, for each member ID (with 0 for orders not placed by
+	// members), the number of results that would match all of the other
+	// report criteria if that member were the only one selected.
+	Members map[int]int
+
+	// ProductTypes gives, for each product type, the number of results
+	// that would match all of the other report criteria if that product
+	// type were the only one selected.
+	ProductTypes map[ProductType]int
+
+	// Series gives, for each product series, the number of results that
+	// would match all of the other report criteria if that series were the
+	// only one selected.
+	Series StringCounts
+
+	// Options gives, for each product option, the number of results that
+	// would match all of the other report criteria if that option were the
+	// only one selected.  There will be an entry for "" representing lines
+	// with no option.
+	Options StringCounts
 }
 
 // A ReportLine is one line in a report.
@@ -118,6 +200,7 @@ type ReportLine struct {
 	OrderSource OrderSource
 	PaymentType string
 	Amount      float64
+	Deductible  float64
 }
 
 // A ReportProductCount provides the statistical and hierarchical information
@@ -140,6 +223,50 @@ type ReportEventCount struct {
 	Count  int
 }
 
+// A ReportAmountCount provides the statistical information for one range of
+// line amounts in a report.
+type ReportAmountCount struct {
+	HasMin bool
+	Min    int // cents; valid only if HasMin
+	HasMax bool
+	Max    int // cents; valid only if HasMax
+	Count  int
+}
+
 // StringCounts is a map from string to integer, with associated methods for
 // sorted access.
 type StringCounts map[string]int
+
+type SavedReportID int
+
+// A SavedReport is a named report definition, which can be delivered by email
+// on a schedule.
+type SavedReport struct {
+	ID         SavedReportID
+	Name       string
+	Owner      string // username
+	Query      string // report definition, as a URL query string
+	Schedule   ReportSchedule
+	Recipients []string
+	Created    time.Time
+	LastRun    time.Time // zero if never delivered
+}
+
+// ReportSchedule is the delivery schedule of a saved report.
+type ReportSchedule string
+
+const (
+	// ScheduleNone indicates that the report isn't delivered.
+	ScheduleNone ReportSchedule = ""
+
+	// ScheduleDaily indicates that the report is delivered every morning.
+	ScheduleDaily = "daily"
+
+	// ScheduleWeekly indicates that the report is delivered every Monday
+	// morning.
+	ScheduleWeekly = "weekly"
+
+	// ScheduleEvent indicates that the report is delivered the morning
+	// after each event.
+	ScheduleEvent = "event"
+)
@@ -0,0 +1,357 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// CancelEvent handles POST /ofcapi/event/${id}/cancel requests.  It marks the
+// event cancelled, which stops sales of products dedicated to it, and sends a
+// notice to each holder of tickets dedicated to it, inviting them to choose a
+// refund, a donation of the tickets' value, or an exchange for tickets to the
+// rescheduled event.  The request body is a JSON object with optional
+// rescheduled (event ID), donation (product ID), and message properties;
+// exchanges and donations are offered only if the corresponding property is
+// given.
+func CancelEvent(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
+	var (
+		session  *model.Session
+		event    *model.Event
+		ec       = &model.EventCancellation{Event: eventID}
+		resched  *model.Event
+		donation *model.Product
+		orders   int
+		notified int
+		jw       json.Writer
+		err      error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if event = tx.FetchEvent(eventID); event == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if !event.Cancelled.IsZero() {
+		api.BadRequestError(tx, w, "event is already cancelled")
+		return
+	}
+	err = json.NewReader(r.Body).Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "rescheduled":
+			return json.StringHandler(func(s string) { ec.Rescheduled = model.EventID(s) })
+		case "donation":
+			return json.StringHandler(func(s string) { ec.Donation = model.ProductID(s) })
+		case "message":
+			return json.StringHandler(func(s string) { ec.Message = s })
+		default:
+			return json.RejectHandler()
+		}
+	}))
+	if err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	if ec.Rescheduled != "" {
+		if resched = tx.FetchEvent(ec.Rescheduled); resched == nil || resched.ID == event.ID || !resched.Cancelled.IsZero() {
+			api.BadRequestError(tx, w, "invalid rescheduled event")
+			return
+		}
+	}
+	if ec.Donation != "" {
+		if donation = tx.FetchProduct(ec.Donation); donation == nil || donation.Type != model.ProdDonation {
+			api.BadRequestError(tx, w, "invalid donation product")
+			return
+		}
+	}
+	event.Cancelled = time.Now()
+	tx.SaveEvent(event)
+	ec.Created = event.Cancelled
+	ec.Username = session.Username
+	tx.SaveEventCancellation(ec)
+	for _, oid := range tx.FetchEventTicketOrders(event) {
+		var (
+			order = tx.FetchOrder(oid)
+			co    = &model.CancelledOrder{Event: event.ID, Order: oid}
+		)
+		if co.Tickets, co.Amount = api.CancelledTickets(order, event.ID); co.Tickets == 0 {
+			continue
+		}
+		tx.SaveCancelledOrder(co)
+		orders++
+		if api.EmitCancellationNotice(tx, order, event, ec, co) != nil {
+			notified++
+		}
+	}
+	api.Commit(tx)
+	log.Printf("%s CANCEL EVENT %s rescheduled=%q donation=%q orders=%d notified=%d",
+		session.Username, event.ID, ec.Rescheduled, ec.Donation, orders, notified)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("orders", orders)
+		jw.Prop("notified", notified)
+	})
+	jw.Close()
+	api.SendQueuedEmail()
+}
+
+// ResolveCancelledOrder handles POST /ofcapi/event/${id}/cancellation/${oid}
+// requests.  It carries out the outcome given in the outcome property of the
+// JSON request body ("refund", "donation", or "exchange") for the order's
+// tickets to the cancelled event, and sends the customer a confirmation.  If
+// the outcome is recorded but the card refund fails, it returns a 502 Bad
+// Gateway error, and the refund can be retried with RetryCancellationRefund.
+func ResolveCancelledOrder(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID, orderID model.OrderID) {
+	var (
+		session   *model.Session
+		event     *model.Event
+		ec        *model.EventCancellation
+		co        *model.CancelledOrder
+		order     *model.Order
+		donation  *model.Order
+		outcome   model.CancellationOutcome
+		reason    string
+		refundErr error
+		err       error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if event = tx.FetchEvent(eventID); event == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if ec = tx.FetchEventCancellation(eventID); ec == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if co = tx.FetchCancelledOrder(eventID, orderID); co == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	err = json.NewReader(r.Body).Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "outcome":
+			return json.StringHandler(func(s string) { outcome = model.CancellationOutcome(s) })
+		default:
+			return json.RejectHandler()
+		}
+	}))
+	if err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	order = tx.FetchOrder(orderID)
+	if donation, reason, refundErr = api.ResolveCancellation(tx, ec, co, order, outcome, session.Username); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	log.Printf("%s RESOLVE CANCELLATION %s %s ORDER %s", session.Username, event.ID, co.Outcome, order.ToJSON(true))
+	if donation != nil {
+		log.Printf("%s DONATION ORDER %s", session.Username, donation.ToJSON(true))
+	}
+	if refundErr != nil {
+		http.Error(w, "502 Bad Gateway: outcome recorded, but the refund failed: "+refundErr.Error(), http.StatusBadGateway)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		jw := json.NewWriter(w)
+		emitCancelledOrder(jw, co, order)
+		jw.Close()
+	}
+	api.SendQueuedEmail()
+	api.UpdateWalletPasses(order)
+	api.UpdateGoogleSheet(order)
+	if donation != nil {
+		api.UpdateGoogleSheet(donation)
+	}
+}
+
+// RetryCancellationRefund handles POST
+// /ofcapi/event/${id}/cancellation/${oid}/refund requests.  It retries a card
+// refund for the order's tickets to the cancelled event that failed when the
+// outcome was chosen, and if it succeeds, sends the customer a confirmation.
+// If it fails again, it returns a 502 Bad Gateway error.
+func RetryCancellationRefund(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID, orderID model.OrderID) {
+	var (
+		session   *model.Session
+		ec        *model.EventCancellation
+		co        *model.CancelledOrder
+		order     *model.Order
+		reason    string
+		refundErr error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if ec = tx.FetchEventCancellation(eventID); ec == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if co = tx.FetchCancelledOrder(eventID, orderID); co == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	order = tx.FetchOrder(orderID)
+	if reason, refundErr = api.RetryCancellationRefund(tx, ec, co, order); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	if refundErr != nil {
+		http.Error(w, "502 Bad Gateway: the refund failed: "+refundErr.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("%s RETRY CANCELLATION REFUND %s ORDER %s", session.Username, eventID, order.ToJSON(true))
+	w.Header().Set("Content-Type", "application/json")
+	jw := json.NewWriter(w)
+	emitCancelledOrder(jw, co, order)
+	jw.Close()
+	api.SendQueuedEmail()
+	api.UpdateGoogleSheet(order)
+}
+
+// GetCancellationReport handles GET /ofcapi/event/${id}/cancellation
+// requests.  It returns the list of orders holding tickets to the cancelled
+// event, with the outcome for each, and totals by outcome.  The report is
+// returned as JSON unless the format=csv parameter is given.
+func GetCancellationReport(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
+	var (
+		event  *model.Event
+		ec     *model.EventCancellation
+		list   []*model.CancelledOrder
+		orders = make(map[model.OrderID]*model.Order)
+		jw     json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if event = tx.FetchEvent(eventID); event == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if ec = tx.FetchEventCancellation(eventID); ec == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	list = tx.FetchCancelledOrders(eventID)
+	for _, co := range list {
+		orders[co.Order] = tx.FetchOrder(co.Order)
+	}
+	api.Commit(tx)
+	if r.FormValue("format") == "csv" {
+		emitCancellationReportCSV(w, event, list, orders)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("event", string(event.ID))
+		jw.Prop("cancelled", ec.Created.Format(time.RFC3339))
+		jw.Prop("cancelledBy", ec.Username)
+		if ec.Rescheduled != "" {
+			jw.Prop("rescheduled", string(ec.Rescheduled))
+		}
+		if ec.Donation != "" {
+			jw.Prop("donation", string(ec.Donation))
+		}
+		jw.Prop("orders", func() {
+			jw.Array(func() {
+				for _, co := range list {
+					emitCancelledOrder(jw, co, orders[co.Order])
+				}
+			})
+		})
+		jw.Prop("totals", func() {
+			jw.Object(func() {
+				for _, outcome := range []model.CancellationOutcome{
+					model.CancelPending, model.CancelRefund, model.CancelDonation, model.CancelExchange,
+				} {
+					var count, tickets, amount int
+
+					for _, co := range list {
+						if co.Outcome == outcome {
+							count++
+							tickets += co.Tickets
+							amount += co.Amount
+						}
+					}
+					jw.Prop(cancellationOutcomeName(outcome), func() {
+						jw.Object(func() {
+							jw.Prop("orders", count)
+							jw.Prop("tickets", tickets)
+							jw.Prop("amount", amount)
+						})
+					})
+				}
+			})
+		})
+	})
+	jw.Close()
+}
+
+// emitCancelledOrder writes the JSON representation of a cancelled order.
+func emitCancelledOrder(jw json.Writer, co *model.CancelledOrder, order *model.Order) {
+	jw.Object(func() {
+		jw.Prop("id", int(co.Order))
+		jw.Prop("name", order.Name)
+		jw.Prop("email", order.Email)
+		jw.Prop("tickets", co.Tickets)
+		jw.Prop("amount", co.Amount)
+		jw.Prop("outcome", cancellationOutcomeName(co.Outcome))
+		if !co.Resolved.IsZero() {
+			jw.Prop("resolved", co.Resolved.Format(time.RFC3339))
+			if co.ResolvedBy != "" {
+				jw.Prop("resolvedBy", co.ResolvedBy)
+			}
+		}
+		if co.RefundError != "" {
+			jw.Prop("refundError", co.RefundError)
+		}
+	})
+}
+
+// emitCancellationReportCSV writes the cancellation report in CSV format.
+func emitCancellationReportCSV(w http.ResponseWriter, event *model.Event, list []*model.CancelledOrder, orders map[model.OrderID]*model.Order) {
+	var cw *csv.Writer
+
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="cancellation-%s.csv"`, event.ID))
+	cw = csv.NewWriter(w)
+	cw.Write([]string{"Order", "Name", "Email", "Tickets", "Amount", "Outcome", "Resolved", "Resolved By", "Refund Error"})
+	for _, co := range list {
+		var resolved, resolvedBy string
+
+		if !co.Resolved.IsZero() {
+			resolved = co.Resolved.Format("2006-01-02 15:04")
+			if resolvedBy = co.ResolvedBy; resolvedBy == "" {
+				resolvedBy = "(customer)"
+			}
+		}
+		cw.Write([]string{
+			strconv.Itoa(int(co.Order)), orders[co.Order].Name, orders[co.Order].Email, strconv.Itoa(co.Tickets),
+			fmt.Sprintf("%.2f", float64(co.Amount)/100.0), cancellationOutcomeName(co.Outcome), resolved, resolvedBy,
+			co.RefundError,
+		})
+	}
+	cw.Flush()
+}
+
+// cancellationOutcomeName returns the name of a cancellation outcome for
+// reports.
+func cancellationOutcomeName(outcome model.CancellationOutcome) string {
+	if outcome == model.CancelPending {
+		return "pending"
+	}
+	return string(outcome)
+}
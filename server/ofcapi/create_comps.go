@@ -0,0 +1,201 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// defaultCompAllowance is the number of complimentary tickets each person may
+// receive for an event, unless the request specifies otherwise.
+const defaultCompAllowance = 2
+
+// compRow is one row of the CSV file given to CreateComps.
+type compRow struct {
+	line  int
+	name  string
+	email string
+	count int
+}
+
+// CreateComps handles POST /ofcapi/event/${id}/comps requests.  The request
+// body is a CSV file with name, email, and count columns (an initial header row
+// is optional).  For each row, it creates a complimentary order for that many
+// tickets to the event, and emails the receipt to the recipient.  The tickets
+// are for the event's comp product:  the ticket product with an office SKU for
+// the COMP coupon at no charge.  If there is more than one, the product
+// parameter says which to use.  No one may receive more than the allowance
+// parameter's number of comp tickets to the event (default 2), including comps
+// they were given previously.  If any row is invalid, no orders are created.
+func CreateComps(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
+	var (
+		session   *model.Session
+		event     *model.Event
+		product   *model.Product
+		allowance = defaultCompAllowance
+		rows      []*compRow
+		given     = make(map[string]int)
+		total     int
+		orders    []*model.Order
+		jw        json.Writer
+		err       error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if event = tx.FetchEvent(eventID); event == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if product = compProduct(tx, event, model.ProductID(r.FormValue("product"))); product == nil {
+		api.BadRequestError(tx, w, "no unique comp product for event")
+		return
+	}
+	if astr := r.FormValue("allowance"); astr != "" {
+		if allowance, err = strconv.Atoi(astr); err != nil || allowance < 0 {
+			api.BadRequestError(tx, w, `invalid "allowance"`)
+			return
+		}
+	}
+	if rows, err = readCompRows(r.Body, product.TicketCount); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	// Check each recipient's allowance, counting both comps they already
+	// have and those given to them on earlier rows of this request.
+	for _, row := range rows {
+		var key = strings.ToLower(row.email)
+
+		if _, ok := given[key]; !ok {
+			given[key] = tx.FetchCompTicketCount(event, row.email)
+		}
+		if given[key]+row.count > allowance {
+			api.BadRequestError(tx, w, fmt.Sprintf("line %d: %s would have %d comp tickets; the allowance is %d",
+				row.line, row.email, given[key]+row.count, allowance))
+			return
+		}
+		given[key] += row.count
+		total += row.count
+	}
+	if event.Capacity != 0 && tx.FetchTicketCount(event)+total > event.Capacity {
+		api.BadRequestError(tx, w, "not enough seats left in the event")
+		return
+	}
+	// Create the orders.
+	for _, row := range rows {
+		var order = &model.Order{
+			Source: model.OrderFromOffice,
+			Name:   row.name,
+			Email:  row.email,
+			Coupon: model.CompCoupon,
+			Lines: []*model.OrderLine{{
+				Product:  &model.Product{ID: product.ID},
+				Quantity: row.count / product.TicketCount,
+			}},
+		}
+		if reason := api.PrepareOrder(tx, session, order); reason != "" {
+			api.BadRequestError(tx, w, fmt.Sprintf("line %d: %s", row.line, reason))
+			return
+		}
+		order.Valid = true
+		tx.SaveOrder(order)
+		api.EmitReceipt(tx, order)
+		orders = append(orders, order)
+	}
+	api.Commit(tx)
+	for _, order := range orders {
+		log.Printf("%s COMP ORDER %s", session.Username, order.ToJSON(true))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for _, order := range orders {
+			jw.Object(func() {
+				jw.Prop("id", int(order.ID))
+				jw.Prop("name", order.Name)
+				jw.Prop("email", order.Email)
+				jw.Prop("count", order.Lines[0].Quantity*product.TicketCount)
+			})
+		}
+	})
+	jw.Close()
+	api.SendQueuedEmail()
+	for _, order := range orders {
+		api.UpdateGoogleSheet(order)
+	}
+}
+
+// compProduct returns the comp product for the event, or nil if there isn't
+// exactly one.  If productID is specified, it must be one of the event's comp
+// products.
+func compProduct(tx db.Tx, event *model.Event, productID model.ProductID) (product *model.Product) {
+	for _, p := range tx.FetchProductsByEvent(event) {
+		if p.Type != model.ProdTicket || p.TicketCount == 0 || (productID != "" && p.ID != productID) {
+			continue
+		}
+		for _, sku := range p.SKUs {
+			if sku.Coupon == model.CompCoupon && sku.Price == 0 &&
+				api.MatchingSKU(sku, model.CompCoupon, model.OrderFromOffice, false) {
+				if product != nil {
+					return nil
+				}
+				product = p
+				break
+			}
+		}
+	}
+	return product
+}
+
+// readCompRows reads the CSV file given to CreateComps.  The count on each row
+// must be a multiple of ticketCount, the number of tickets per unit of the comp
+// product.
+func readCompRows(r io.Reader, ticketCount int) (rows []*compRow, err error) {
+	var (
+		cr     = csv.NewReader(r)
+		record []string
+	)
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+	for {
+		var row compRow
+
+		if record, err = cr.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		row.line, _ = cr.FieldPos(0)
+		if row.line == 1 && strings.EqualFold(strings.TrimSpace(record[2]), "count") {
+			continue // header row
+		}
+		row.name = strings.TrimSpace(record[0])
+		row.email = strings.TrimSpace(record[1])
+		if row.name == "" {
+			return nil, fmt.Errorf("line %d: missing name", row.line)
+		}
+		if !api.ValidEmail(row.email) {
+			return nil, fmt.Errorf("line %d: invalid email %q", row.line, row.email)
+		}
+		if row.count, err = strconv.Atoi(strings.TrimSpace(record[2])); err != nil || row.count < 1 || row.count%ticketCount != 0 {
+			return nil, fmt.Errorf("line %d: invalid count %q", row.line, record[2])
+		}
+		rows = append(rows, &row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no comps requested")
+	}
+	return rows, nil
+}
@@ -69,6 +69,10 @@ func parseCreateEvent(r io.Reader) (e *model.Event, err error) {
 			return json.TimeHandler(func(t time.Time) { e.Start = t })
 		case "capacity":
 			return json.IntHandler(func(i int) { e.Capacity = i })
+		case "venue":
+			return json.StringHandler(func(s string) { e.Venue = s })
+		case "parking":
+			return json.StringHandler(func(s string) { e.Parking = s })
 		default:
 			return json.RejectHandler()
 		}
@@ -92,6 +96,12 @@ func emitCreatedEvent(e *model.Event) []byte {
 		if e.Capacity != 0 {
 			jw.Prop("capacity", e.Capacity)
 		}
+		if e.Venue != "" {
+			jw.Prop("venue", e.Venue)
+		}
+		if e.Parking != "" {
+			jw.Prop("parking", e.Parking)
+		}
 	})
 	jw.Close()
 	return buf.Bytes()
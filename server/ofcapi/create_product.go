@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rothskeller/json"
@@ -32,10 +33,14 @@ func CreateProduct(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 		api.BadRequestError(tx, w, err.Error())
 		return
 	}
-	if product.ID == "" || product.Name == "" || product.ShortName == "" || product.Type == "" || product.TicketCount < 0 {
+	if product.ID == "" || product.Name == "" || product.ShortName == "" || product.Type == "" || product.TicketCount < 0 || product.FMV < -1 {
 		api.BadRequestError(tx, w, "invalid parameters")
 		return
 	}
+	if (product.Type == model.ProdRegistration || product.Type == model.ProdAuctionItem) && product.FMV < 0 {
+		api.BadRequestError(tx, w, "registrations and auction items must have a fair market value")
+		return
+	}
 	if tx.FetchProduct(product.ID) != nil {
 		api.BadRequestError(tx, w, "duplicate product ID")
 		return
@@ -73,6 +78,22 @@ func CreateProduct(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 			seenPrio0 = true
 		}
 	}
+	if len(product.Funds) != 0 && product.Type != model.ProdDonation {
+		api.BadRequestError(tx, w, "only donation products can have funds")
+		return
+	}
+	for i, fund := range product.Funds {
+		if fund == "" || strings.Contains(fund, ",") {
+			api.BadRequestError(tx, w, "invalid fund")
+			return
+		}
+		for _, prev := range product.Funds[:i] {
+			if prev == fund {
+				api.BadRequestError(tx, w, "duplicate fund")
+				return
+			}
+		}
+	}
 	for i, sku := range product.SKUs {
 		switch sku.Source {
 		case model.OrderFromPublic, model.OrderFromMembers, model.OrderFromGala, model.OrderFromOffice, model.OrderInPerson:
@@ -80,7 +101,7 @@ func CreateProduct(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 			api.BadRequestError(tx, w, "invalid SKU source")
 			return
 		}
-		if sku.Price < 0 || (!sku.SalesStart.IsZero() && !sku.SalesEnd.IsZero() && !sku.SalesEnd.After(sku.SalesStart)) {
+		if sku.Price < 0 || sku.FMV < 0 || (!sku.SalesStart.IsZero() && !sku.SalesEnd.IsZero() && !sku.SalesEnd.After(sku.SalesStart)) {
 			api.BadRequestError(tx, w, "invalid SKU parameters")
 			return
 		}
@@ -101,11 +122,12 @@ func CreateProduct(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 	w.Write(out)
 }
 
-// parseCreateProduct reads the product details from the request body.
+// parseCreateProduct reads the product details from the request body.  The
+// fair market value is -1 (not determined) if it isn't given.
 func parseCreateProduct(r io.Reader) (p *model.Product, err error) {
 	var jr = json.NewReader(r)
 
-	p = new(model.Product)
+	p = &model.Product{FMV: -1}
 	err = jr.Read(json.ObjectHandler(func(key string) json.Handlers {
 		switch key {
 		case "id":
@@ -124,6 +146,12 @@ func parseCreateProduct(r io.Reader) (p *model.Product, err error) {
 			return json.IntHandler(func(i int) { p.TicketCount = i })
 		case "ticketClass":
 			return json.StringHandler(func(s string) { p.TicketClass = s })
+		case "fmv":
+			return json.IntHandler(func(i int) { p.FMV = i })
+		case "funds":
+			return json.ArrayHandler(func() json.Handlers {
+				return json.StringHandler(func(s string) { p.Funds = append(p.Funds, strings.TrimSpace(s)) })
+			})
 		case "skus":
 			return json.ArrayHandler(func() json.Handlers {
 				var sku model.SKU
@@ -154,6 +182,8 @@ func parseCreateProductSKU(sku *model.SKU) json.Handlers {
 			return json.TimeHandler(func(t time.Time) { sku.SalesEnd = t })
 		case "price":
 			return json.IntHandler(func(i int) { sku.Price = i })
+		case "fmv":
+			return json.IntHandler(func(i int) { sku.FMV = i })
 		default:
 			return json.RejectHandler()
 		}
@@ -215,6 +245,18 @@ func emitProduct(p *model.Product) []byte {
 		if p.TicketClass != "" {
 			jw.Prop("ticketClass", p.TicketClass)
 		}
+		if p.FMV >= 0 {
+			jw.Prop("fmv", p.FMV)
+		}
+		if len(p.Funds) != 0 {
+			jw.Prop("funds", func() {
+				jw.Array(func() {
+					for _, fund := range p.Funds {
+						jw.String(fund)
+					}
+				})
+			})
+		}
 		jw.Prop("skus", func() {
 			jw.Array(func() {
 				for _, sku := range p.SKUs {
@@ -230,6 +272,9 @@ func emitProduct(p *model.Product) []byte {
 							jw.Prop("salesEnd", sku.SalesEnd.Format(time.RFC3339))
 						}
 						jw.Prop("price", sku.Price)
+						if sku.FMV != 0 {
+							jw.Prop("fmv", sku.FMV)
+						}
 					})
 				}
 			})
@@ -0,0 +1,194 @@
+package ofcapi
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// maxCustomerSearch is the maximum number of customers returned by a search.
+const maxCustomerSearch = 100
+
+// ListCustomers handles GET /ofcapi/customer?search=... requests.  It returns
+// the customers whose names or email addresses contain the search text.
+func ListCustomers(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		search string
+		list   []*model.Customer
+		jw     json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if search = strings.TrimSpace(r.FormValue("search")); len(search) < 2 {
+		api.BadRequestError(tx, w, "search must be at least two characters")
+		return
+	}
+	list = tx.SearchCustomers(search, maxCustomerSearch)
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for _, c := range list {
+			jw.Object(func() {
+				jw.Prop("id", int(c.ID))
+				jw.Prop("name", c.Name)
+				jw.Prop("email", c.Email)
+				jw.Prop("city", c.City)
+			})
+		}
+	})
+	jw.Close()
+}
+
+// GetCustomer handles GET /ofcapi/customer/${id} requests.  It returns the
+// customer's contact details, lifetime history, and orders.  The lifetime
+// history covers only valid orders: the total paid, the total given (the
+// tax-deductible amount), the number of tickets used, and the times of the
+// first and last purchases.
+func GetCustomer(tx db.Tx, w http.ResponseWriter, r *http.Request, custID model.CustomerID) {
+	var (
+		cust    *model.Customer
+		orders  []*model.Order
+		paid    int
+		given   int
+		used    int
+		first   time.Time
+		last    time.Time
+		jw      json.Writer
+		ordered = func(o *model.Order) (total int) {
+			for _, ol := range o.Lines {
+				total += ol.Price * ol.Quantity
+			}
+			return total
+		}
+		gift = func(o *model.Order) (total int) {
+			for _, ol := range o.Lines {
+				total += ol.Deductible()
+			}
+			return total
+		}
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if cust = tx.FetchCustomer(custID); cust == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	for _, oid := range tx.FetchCustomerOrders(custID) {
+		var order = tx.FetchOrder(oid)
+
+		orders = append(orders, order)
+		if !order.Valid {
+			continue
+		}
+		for _, p := range order.Payments {
+			paid += p.Amount
+		}
+		given += gift(order)
+		for _, ol := range order.Lines {
+			used += ol.TicketsUsed()
+		}
+		if first.IsZero() {
+			first = order.Created
+		}
+		last = order.Created
+	}
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("id", int(cust.ID))
+		jw.Prop("name", cust.Name)
+		jw.Prop("email", cust.Email)
+		jw.Prop("address", cust.Address)
+		jw.Prop("city", cust.City)
+		jw.Prop("state", cust.State)
+		jw.Prop("zip", cust.Zip)
+		jw.Prop("phone", cust.Phone)
+		jw.Prop("created", cust.Created.Format(time.RFC3339))
+		jw.Prop("totalPaid", paid)
+		jw.Prop("totalGiven", given)
+		jw.Prop("ticketsUsed", used)
+		if !first.IsZero() {
+			jw.Prop("firstPurchase", first.Format(time.RFC3339))
+			jw.Prop("lastPurchase", last.Format(time.RFC3339))
+		}
+		jw.Prop("orders", func() {
+			jw.Array(func() {
+				for _, o := range orders {
+					jw.Object(func() {
+						jw.Prop("id", int(o.ID))
+						jw.Prop("created", o.Created.Format(time.RFC3339))
+						jw.Prop("source", string(o.Source))
+						jw.Prop("valid", o.Valid)
+						jw.Prop("name", o.Name)
+						jw.Prop("email", o.Email)
+						jw.Prop("total", ordered(o))
+						jw.Prop("given", gift(o))
+					})
+				}
+			})
+		})
+	})
+	jw.Close()
+}
+
+// MergeCustomer handles POST /ofcapi/customer/${id}/merge requests.  The
+// customer given by the duplicate parameter is merged into the customer given
+// in the URL: its orders are linked to that customer, any contact details that
+// customer lacks are taken from it, and it is deleted.
+func MergeCustomer(tx db.Tx, w http.ResponseWriter, r *http.Request, custID model.CustomerID) {
+	var (
+		session *model.Session
+		cust    *model.Customer
+		dup     *model.Customer
+		dupID   int
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if cust = tx.FetchCustomer(custID); cust == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if dupID, err = strconv.Atoi(r.FormValue("duplicate")); err != nil || dupID == int(custID) {
+		api.BadRequestError(tx, w, "invalid duplicate")
+		return
+	}
+	if dup = tx.FetchCustomer(model.CustomerID(dupID)); dup == nil {
+		api.BadRequestError(tx, w, "invalid duplicate")
+		return
+	}
+	if cust.Name == "" {
+		cust.Name = dup.Name
+	}
+	if cust.Email == "" {
+		cust.Email = dup.Email
+	}
+	if cust.Address == "" {
+		cust.Address, cust.City, cust.State, cust.Zip = dup.Address, dup.City, dup.State, dup.Zip
+	}
+	if cust.Phone == "" {
+		cust.Phone = dup.Phone
+	}
+	if dup.Created.Before(cust.Created) {
+		cust.Created = dup.Created
+	}
+	tx.MergeCustomers(cust, dup)
+	tx.SaveCustomer(cust)
+	api.Commit(tx)
+	log.Printf("%s MERGE CUSTOMER %d INTO %d", session.Username, dup.ID, cust.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
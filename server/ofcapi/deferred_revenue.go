@@ -0,0 +1,206 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ticketLiability gives the earned and deferred ticket revenue for a single
+// product, or the totals of a series or of the whole report.
+type ticketLiability struct {
+	product         *model.Product // nil for totals
+	usedTickets     int
+	used            int // cents
+	expiredTickets  int
+	expired         int // cents
+	deferredTickets int
+	deferred        int // cents
+}
+
+// add adds the figures of another ticketLiability into this one.
+func (tl *ticketLiability) add(o *ticketLiability) {
+	tl.usedTickets += o.usedTickets
+	tl.used += o.used
+	tl.expiredTickets += o.expiredTickets
+	tl.expired += o.expired
+	tl.deferredTickets += o.deferredTickets
+	tl.deferred += o.deferred
+}
+
+// seriesLiability gives the ticketLiability of each product in a series.
+type seriesLiability struct {
+	series   string
+	total    ticketLiability
+	products []*ticketLiability
+}
+
+// GetDeferredRevenue handles GET /ofcapi/deferredRevenue requests.  It
+// computes, as of the end of the asOf date (YYYY-MM-DD; the default is today),
+// the revenue earned and deferred on all tickets sold by then.  Revenue is
+// earned on tickets that have been used ("used"), and on unused tickets that
+// are no longer valid for any future event ("expired").  It is deferred on
+// unused tickets that are still valid for a future event, including tickets to
+// cancelled events whose holders haven't yet chosen a refund, donation, or
+// exchange.  Each ticket is valued at its share of the price of the order line
+// on which it was sold.  The figures are broken out by series and product, and
+// returned as JSON unless the format=csv parameter is given.
+func GetDeferredRevenue(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		asOf     time.Time
+		end      time.Time
+		total    ticketLiability
+		series   []*seriesLiability
+		bySeries = make(map[string]*seriesLiability)
+		byProd   = make(map[model.ProductID]*ticketLiability)
+		events   = make(map[model.EventID]*model.Event)
+		jw       json.Writer
+		err      error
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	asOf = time.Now()
+	if s := r.FormValue("asOf"); s != "" {
+		if asOf, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid asOf")
+			return
+		}
+	}
+	end = time.Date(asOf.Year(), asOf.Month(), asOf.Day()+1, 0, 0, 0, 0, time.Local)
+	for _, e := range tx.FetchEvents() {
+		events[e.ID] = e
+	}
+	for _, tv := range tx.FetchTicketValues(end) {
+		var tl = byProd[tv.Product]
+
+		if tl == nil {
+			tl = &ticketLiability{product: tx.FetchProduct(tv.Product)}
+			byProd[tv.Product] = tl
+			sl := bySeries[tl.product.Series]
+			if sl == nil {
+				sl = &seriesLiability{series: tl.product.Series}
+				bySeries[sl.series] = sl
+				series = append(series, sl)
+			}
+			sl.products = append(sl.products, tl)
+		}
+		switch {
+		case !tv.Used.IsZero() && tv.Used.Before(end):
+			tl.usedTickets++
+			tl.used += tv.Value
+		case ticketStillValid(tv, tl.product, events, end):
+			tl.deferredTickets++
+			tl.deferred += tv.Value
+		default:
+			tl.expiredTickets++
+			tl.expired += tv.Value
+		}
+	}
+	api.Commit(tx)
+	sort.Slice(series, func(i, j int) bool { return series[i].series < series[j].series })
+	for _, sl := range series {
+		sort.Slice(sl.products, func(i, j int) bool { return sl.products[i].product.ID < sl.products[j].product.ID })
+		for _, tl := range sl.products {
+			sl.total.add(tl)
+		}
+		total.add(&sl.total)
+	}
+	if r.FormValue("format") == "csv" {
+		emitDeferredRevenueCSV(w, series, asOf)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("asOf", asOf.Format("2006-01-02"))
+		writeTicketLiability(jw, &total)
+		jw.Prop("series", func() {
+			jw.Array(func() {
+				for _, sl := range series {
+					jw.Object(func() {
+						jw.Prop("series", sl.series)
+						writeTicketLiability(jw, &sl.total)
+						jw.Prop("products", func() {
+							jw.Array(func() {
+								for _, tl := range sl.products {
+									jw.Object(func() {
+										jw.Prop("id", string(tl.product.ID))
+										jw.Prop("name", tl.product.Name)
+										jw.Prop("ticketCount", tl.product.TicketCount)
+										writeTicketLiability(jw, tl)
+									})
+								}
+							})
+						})
+					})
+				}
+			})
+		})
+	})
+	jw.Close()
+}
+
+// ticketStillValid returns whether an unused ticket can still be used at an
+// event after the end time.  A ticket labeled for an event can only be used
+// at that event (or, if it was cancelled, exchanged or refunded).  An
+// unlabeled ticket can be used at any of its product's uncancelled events.
+func ticketStillValid(tv db.TicketValue, product *model.Product, events map[model.EventID]*model.Event, end time.Time) bool {
+	if tv.Event != "" {
+		if e := events[tv.Event]; e != nil {
+			return !e.Cancelled.IsZero() || !e.Start.Before(end)
+		}
+		return false
+	}
+	for _, pe := range product.Events {
+		if pe.Event.Cancelled.IsZero() && !pe.Event.Start.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTicketLiability writes the figures of a ticketLiability as properties
+// of the current JSON object.
+func writeTicketLiability(jw json.Writer, tl *ticketLiability) {
+	jw.Prop("usedTickets", tl.usedTickets)
+	jw.Prop("used", tl.used)
+	jw.Prop("expiredTickets", tl.expiredTickets)
+	jw.Prop("expired", tl.expired)
+	jw.Prop("earned", tl.used+tl.expired)
+	jw.Prop("deferredTickets", tl.deferredTickets)
+	jw.Prop("deferred", tl.deferred)
+}
+
+// emitDeferredRevenueCSV writes the deferred revenue report in CSV format, with
+// one row per product.
+func emitDeferredRevenueCSV(w http.ResponseWriter, series []*seriesLiability, asOf time.Time) {
+	var cw *csv.Writer
+
+	dollars := func(cents int) string { return fmt.Sprintf("%.2f", float64(cents)/100.0) }
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="deferred-%s.csv"`, asOf.Format("20060102")))
+	cw = csv.NewWriter(w)
+	cw.Write([]string{"Series", "Product", "Name", "Tickets Per Unit", "Used Tickets", "Used", "Expired Tickets", "Expired",
+		"Earned", "Deferred Tickets", "Deferred"})
+	for _, sl := range series {
+		for _, tl := range sl.products {
+			cw.Write([]string{
+				sl.series, string(tl.product.ID), tl.product.Name, strconv.Itoa(tl.product.TicketCount),
+				strconv.Itoa(tl.usedTickets), dollars(tl.used), strconv.Itoa(tl.expiredTickets), dollars(tl.expired),
+				dollars(tl.used + tl.expired), strconv.Itoa(tl.deferredTickets), dollars(tl.deferred),
+			})
+		}
+	}
+	cw.Flush()
+}
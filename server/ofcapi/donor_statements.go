@@ -0,0 +1,124 @@
+package ofcapi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// GetDonorStatements handles GET /ofcapi/donorStatement requests.  The year
+// parameter selects the calendar year.  By default, it returns a JSON list of
+// the donors and their totals.  With format=pdf, it returns the printable
+// statements for the donors without email addresses, or for the single donor
+// selected by the donor parameter (a key from the JSON list).  With
+// format=csv, it returns a mail-merge file for the donors without email
+// addresses.
+func GetDonorStatements(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		year int
+		list []*api.DonorStatement
+		jw   json.Writer
+		err  error
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if year, err = strconv.Atoi(r.FormValue("year")); err != nil || year < 2000 || year > time.Now().Year() {
+		api.BadRequestError(tx, w, "invalid year")
+		return
+	}
+	list = api.DonorStatements(tx, year)
+	api.Commit(tx)
+	switch r.FormValue("format") {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="donor-statements-%d.csv"`, year))
+		api.WriteDonorStatementsCSV(w, list, year)
+	case "pdf":
+		var (
+			selected []*api.DonorStatement
+			pdf      []byte
+		)
+		for _, ds := range list {
+			if donor := r.FormValue("donor"); donor != "" && ds.Key == donor {
+				selected = append(selected, ds)
+			} else if donor == "" && ds.Email == "" {
+				selected = append(selected, ds)
+			}
+		}
+		if len(selected) == 0 {
+			http.Error(w, "404 Not Found: no matching donors", http.StatusNotFound)
+			return
+		}
+		if pdf, err = api.DonorStatementPDF(selected, year); err != nil {
+			panic(err)
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="donor-statements-%d.pdf"`, year))
+		w.Write(pdf)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		jw = json.NewWriter(w)
+		jw.Array(func() {
+			for _, ds := range list {
+				jw.Object(func() {
+					jw.Prop("key", ds.Key)
+					jw.Prop("name", ds.Name)
+					jw.Prop("email", ds.Email)
+					jw.Prop("address", ds.Address)
+					jw.Prop("city", ds.City)
+					jw.Prop("state", ds.State)
+					jw.Prop("zip", ds.Zip)
+					jw.Prop("gifts", len(ds.Gifts))
+					jw.Prop("total", ds.Total)
+					jw.Prop("deductible", ds.Deductible)
+					if !ds.Sent.IsZero() {
+						jw.Prop("sent", ds.Sent.Format(time.RFC3339))
+					}
+				})
+			}
+		})
+		jw.Close()
+	}
+}
+
+// SendDonorStatements handles POST /ofcapi/donorStatement requests.  It emails
+// the statements for the year given in the year parameter to all donors with
+// email addresses who haven't already been sent them.
+func SendDonorStatements(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		session *model.Session
+		year    int
+		count   int
+		jw      json.Writer
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if year, err = strconv.Atoi(r.FormValue("year")); err != nil || year < 2000 || year > time.Now().Year() {
+		api.BadRequestError(tx, w, "invalid year")
+		return
+	}
+	count = api.SendDonorStatements(tx, api.DonorStatements(tx, year), year)
+	api.Commit(tx)
+	log.Printf("%s SEND DONOR STATEMENTS %d sent=%d", session.Username, year, count)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("sent", count)
+	})
+	jw.Close()
+	if count != 0 {
+		api.SendQueuedEmail()
+	}
+}
@@ -0,0 +1,260 @@
+package ofcapi
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// SendEventMessage handles POST /ofcapi/event/${id}/message requests.  It
+// sends an ad hoc message to each distinct email address holding valid
+// tickets for the event.  The request body is a JSON object with subject and
+// body (plain text) properties, an optional classes property listing the
+// ticket classes whose holders should get the message (as named on the door
+// list; default all), and an optional testTo property.  If testTo is given,
+// the message is sent only to that address (normally the sender's own, so
+// they can check it before sending it for real), and the response gives the
+// number of recipients it would have had.  Otherwise, the message and its
+// recipients are recorded.
+func SendEventMessage(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
+	var (
+		session    *model.Session
+		event      *model.Event
+		em         model.EventMessage
+		testTo     string
+		recipients []*model.EventMessageRecipient
+		names      []string
+		jw         json.Writer
+		err        error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if event = tx.FetchEvent(eventID); event == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	err = json.NewReader(r.Body).Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "subject":
+			return json.StringHandler(func(s string) { em.Subject = strings.TrimSpace(s) })
+		case "body":
+			return json.StringHandler(func(s string) { em.Body = s })
+		case "classes":
+			return json.ArrayHandler(func() json.Handlers {
+				return json.StringHandler(func(s string) { em.Classes = append(em.Classes, s) })
+			})
+		case "testTo":
+			return json.StringHandler(func(s string) { testTo = strings.TrimSpace(s) })
+		default:
+			return json.RejectHandler()
+		}
+	}))
+	if err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	if em.Subject == "" || strings.TrimSpace(em.Body) == "" {
+		api.BadRequestError(tx, w, "missing subject or body")
+		return
+	}
+	em.Event = event.ID
+	em.Created = time.Now()
+	em.Username = session.Username
+	recipients, names = eventMessageRecipients(tx, event, em.Classes)
+	if testTo != "" {
+		if !api.ValidEmail(testTo) {
+			api.BadRequestError(tx, w, "invalid testTo address")
+			return
+		}
+		if api.EmitEventMessage(tx, &em, event, "", testTo, 0) == nil {
+			api.BadRequestError(tx, w, "invalid message")
+			return
+		}
+		api.Commit(tx)
+		log.Printf("%s TEST EVENT MESSAGE %s to %s %q", session.Username, event.ID, testTo, em.Subject)
+	} else {
+		if len(recipients) == 0 {
+			api.BadRequestError(tx, w, "no ticket holders to send to")
+			return
+		}
+		for i, emr := range recipients {
+			var email = api.EmitEventMessage(tx, &em, event, names[i], emr.Email, emr.Order)
+
+			if email == nil {
+				api.BadRequestError(tx, w, "invalid message")
+				return
+			}
+			emr.Outbox = email.ID
+		}
+		em.Recipients = recipients
+		tx.SaveEventMessage(&em)
+		api.Commit(tx)
+		log.Printf("%s SEND EVENT MESSAGE %d %s classes=%q recipients=%d %q",
+			session.Username, em.ID, event.ID, em.Classes, len(recipients), em.Subject)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		if testTo == "" {
+			jw.Prop("id", int(em.ID))
+		}
+		jw.Prop("recipients", len(recipients))
+	})
+	jw.Close()
+	api.SendQueuedEmail()
+}
+
+// eventMessageRecipients returns the distinct email addresses holding valid
+// tickets for the event, in the specified ticket classes (or all classes if
+// classes is empty).  Each is paired with the lowest numbered order through
+// which it holds them, and the customer name on that order.
+func eventMessageRecipients(tx db.Tx, event *model.Event, classes []string) (
+	recipients []*model.EventMessageRecipient, names []string,
+) {
+	var (
+		list = tx.FetchEventDoorList(event)
+		seen = make(map[string]bool)
+	)
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	for _, de := range list {
+		var lower = strings.ToLower(de.Email)
+
+		if de.Email == "" || seen[lower] {
+			continue
+		}
+		if len(classes) != 0 {
+			var match bool
+
+			for _, class := range classes {
+				for tc, count := range de.Tickets {
+					if count != 0 && doorListClassName(tc) == class {
+						match = true
+					}
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+		seen[lower] = true
+		recipients = append(recipients, &model.EventMessageRecipient{Email: de.Email, Order: de.ID})
+		names = append(names, de.Name)
+	}
+	return recipients, names
+}
+
+// ListEventMessages handles GET /ofcapi/event/${id}/message requests.  It
+// returns the messages sent to the event's ticket holders, with counts of
+// their delivery status.
+func ListEventMessages(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
+	var (
+		messages []*model.EventMessage
+		counts   [][3]int
+		jw       json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if tx.FetchEvent(eventID) == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	messages = tx.FetchEventMessages(eventID)
+	for _, em := range messages {
+		var c [3]int
+		c[0], c[1], c[2] = tx.FetchEventMessageStatus(em.ID)
+		counts = append(counts, c)
+	}
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for i, em := range messages {
+			jw.Object(func() {
+				emitEventMessageHeader(jw, em)
+				jw.Prop("recipients", counts[i][0]+counts[i][1]+counts[i][2])
+				jw.Prop("sent", counts[i][0])
+				jw.Prop("queued", counts[i][1])
+				jw.Prop("failed", counts[i][2])
+			})
+		}
+	})
+	jw.Close()
+}
+
+// GetEventMessage handles GET /ofcapi/event/${id}/message/${mid} requests.  It
+// returns the message, with its list of recipients and the delivery status of
+// each.
+func GetEventMessage(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID, messageID model.EventMessageID) {
+	var (
+		em     *model.EventMessage
+		emails []*model.Email
+		jw     json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if em = tx.FetchEventMessage(messageID); em == nil || em.Event != eventID {
+		api.NotFoundError(tx, w)
+		return
+	}
+	for _, emr := range em.Recipients {
+		emails = append(emails, tx.FetchEmail(emr.Outbox))
+	}
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		emitEventMessageHeader(jw, em)
+		jw.Prop("body", em.Body)
+		jw.Prop("recipients", func() {
+			jw.Array(func() {
+				for i, emr := range em.Recipients {
+					jw.Object(func() {
+						jw.Prop("email", emr.Email)
+						jw.Prop("order", int(emr.Order))
+						if emails[i] == nil {
+							return
+						}
+						jw.Prop("status", emails[i].Status())
+						if !emails[i].Sent.IsZero() {
+							jw.Prop("sent", emails[i].Sent.Format(time.RFC3339))
+						}
+						if emails[i].Error != "" && emails[i].Sent.IsZero() {
+							jw.Prop("error", emails[i].Error)
+						}
+					})
+				}
+			})
+		})
+	})
+	jw.Close()
+}
+
+// emitEventMessageHeader emits the JSON properties describing an event
+// message, other than its body and recipients.
+func emitEventMessageHeader(jw json.Writer, em *model.EventMessage) {
+	jw.Prop("id", int(em.ID))
+	jw.Prop("created", em.Created.Format(time.RFC3339))
+	jw.Prop("username", em.Username)
+	jw.Prop("subject", em.Subject)
+	if len(em.Classes) != 0 {
+		jw.Prop("classes", func() {
+			jw.Array(func() {
+				for _, class := range em.Classes {
+					jw.String(class)
+				}
+			})
+		})
+	}
+}
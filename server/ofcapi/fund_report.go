@@ -0,0 +1,140 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// fundTotal is one fund's entry in the fund report.
+type fundTotal struct {
+	fund      string
+	count     int
+	tributes  int
+	total     int
+	donations []fundDonation
+}
+
+// fundDonation is one donation in the fund report.
+type fundDonation struct {
+	order *model.Order
+	line  *model.OrderLine
+}
+
+// GetFundReport handles GET /ofcapi/fundReport requests.  It returns the
+// donations made on valid orders between the from and to dates (inclusive,
+// YYYY-MM-DD; the default is the current year to date), grouped by the fund to
+// which they were designated.  The totals for each fund are returned as JSON
+// unless the format=csv parameter is given, in which case each donation is
+// listed.
+func GetFundReport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		from   time.Time
+		to     time.Time
+		funds  []*fundTotal
+		byFund = make(map[string]*fundTotal)
+		jw     json.Writer
+		err    error
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	from = time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	to = time.Now()
+	if s := r.FormValue("from"); s != "" {
+		if from, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid from")
+			return
+		}
+	}
+	if s := r.FormValue("to"); s != "" {
+		if to, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid to")
+			return
+		}
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, time.Local)
+	for _, oid := range tx.FetchDonationOrders(from, to) {
+		var order = tx.FetchOrder(oid)
+
+		for _, ol := range order.Lines {
+			if ol.Product.Type != model.ProdDonation || ol.Price*ol.Quantity == 0 {
+				continue
+			}
+			ft := byFund[ol.Fund]
+			if ft == nil {
+				ft = &fundTotal{fund: ol.Fund}
+				byFund[ol.Fund] = ft
+				funds = append(funds, ft)
+			}
+			ft.count++
+			ft.total += ol.Price * ol.Quantity
+			if ol.Tribute != model.TributeNone {
+				ft.tributes++
+			}
+			ft.donations = append(ft.donations, fundDonation{order, ol})
+		}
+	}
+	api.Commit(tx)
+	sort.Slice(funds, func(i, j int) bool { return funds[i].fund < funds[j].fund })
+	if r.FormValue("format") == "csv" {
+		emitFundReportCSV(w, funds, from, to)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("from", from.Format("2006-01-02"))
+		jw.Prop("to", to.AddDate(0, 0, -1).Format("2006-01-02"))
+		jw.Prop("funds", func() {
+			jw.Array(func() {
+				for _, ft := range funds {
+					jw.Object(func() {
+						jw.Prop("fund", ft.fund)
+						jw.Prop("count", ft.count)
+						jw.Prop("tributes", ft.tributes)
+						jw.Prop("total", ft.total)
+					})
+				}
+			})
+		})
+	})
+	jw.Close()
+}
+
+// emitFundReportCSV writes the fund report in CSV format, with one row per
+// donation.
+func emitFundReportCSV(w http.ResponseWriter, funds []*fundTotal, from, to time.Time) {
+	var cw *csv.Writer
+
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="funds-%s-%s.csv"`,
+		from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")))
+	cw = csv.NewWriter(w)
+	cw.Write([]string{"Fund", "Order", "Date", "Name", "Email", "Amount", "Tribute", "Honoree", "Notify", "Notify Email"})
+	for _, ft := range funds {
+		var fund = ft.fund
+
+		if fund == "" {
+			fund = "(undesignated)"
+		}
+		for _, d := range ft.donations {
+			cw.Write([]string{
+				fund, strconv.Itoa(int(d.order.ID)), d.order.Created.Format("2006-01-02"), d.order.Name, d.order.Email,
+				fmt.Sprintf("%.2f", float64(d.line.Price*d.line.Quantity)/100.0), string(d.line.Tribute),
+				d.line.Honoree, d.line.NotifyName, d.line.NotifyEmail,
+			})
+		}
+	}
+	cw.Flush()
+}
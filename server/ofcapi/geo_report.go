@@ -0,0 +1,240 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// geoGroup gives the patron counts for one zip code, city, or county, or for
+// the orders without an address, or the totals of the report.
+type geoGroup struct {
+	zip        string
+	city       string
+	county     string
+	state      string
+	households map[string]bool
+	orders     int
+	tickets    int
+}
+
+// add counts an order in the group.
+func (gg *geoGroup) add(household string, tickets int) {
+	if gg.households == nil {
+		gg.households = make(map[string]bool)
+	}
+	gg.households[household] = true
+	gg.orders++
+	gg.tickets += tickets
+}
+
+// GetGeoReport handles GET /ofcapi/geoReport requests.  It reports the number
+// of households, orders, and tickets from each zip code (group=zip, the
+// default), city (group=city), or county (group=county), for the valid orders
+// placed between the from and to dates (inclusive, YYYY-MM-DD; the default is
+// no limit) that include products in the specified series (the default is all
+// series).  With a series, only the tickets in that series are counted.  A
+// household is a distinct street address; orders without a street address and
+// zip code are counted separately, with their households distinguished by
+// email address.  The counties come from the HUD ZIP-COUNTY crosswalk (see
+// api/zip_county.go).  The report is returned as JSON unless the format=csv
+// parameter is given.
+func GetGeoReport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		from      time.Time
+		to        time.Time
+		series    string
+		group     string
+		groups    []*geoGroup
+		byKey     = make(map[string]*geoGroup)
+		noAddress geoGroup
+		total     geoGroup
+		jw        json.Writer
+		err       error
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if s := r.FormValue("from"); s != "" {
+		if from, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid from")
+			return
+		}
+	}
+	if s := r.FormValue("to"); s != "" {
+		if to, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid to")
+			return
+		}
+		to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, time.Local)
+		if !from.Before(to) {
+			api.BadRequestError(tx, w, "invalid date range")
+			return
+		}
+	}
+	series = r.FormValue("series")
+	switch group = r.FormValue("group"); group {
+	case "":
+		group = "zip"
+	case "zip", "city", "county":
+		break
+	default:
+		api.BadRequestError(tx, w, "invalid group")
+		return
+	}
+	for _, g := range tx.FetchGeoOrders(from, to, series) {
+		var (
+			household = api.HouseholdKey(g.Address, g.Zip)
+			zip       = g.Zip
+			state     = strings.ToUpper(g.State)
+			key       string
+			gg        *geoGroup
+		)
+		if household == "" || zip == "" {
+			if household = strings.ToLower(g.Email); household == "" {
+				household = "#" + strconv.Itoa(int(g.Order))
+			}
+			noAddress.add(household, g.Tickets)
+			total.add(household, g.Tickets)
+			continue
+		}
+		if len(zip) > 5 {
+			zip = zip[:5]
+		}
+		switch group {
+		case "zip":
+			key = zip
+		case "city":
+			key = strings.ToLower(strings.Join(strings.Fields(g.City), " ")) + "|" + state
+		case "county":
+			key = api.ZipCounty(zip) + "|" + state
+		}
+		if gg = byKey[key]; gg == nil {
+			gg = &geoGroup{state: state}
+			switch group {
+			case "zip":
+				gg.zip, gg.county = zip, api.ZipCounty(zip)
+			case "city":
+				gg.city, gg.county = strings.Join(strings.Fields(g.City), " "), api.ZipCounty(zip)
+			case "county":
+				gg.county = api.ZipCounty(zip)
+			}
+			byKey[key] = gg
+			groups = append(groups, gg)
+		} else if gg.county != api.ZipCounty(zip) {
+			gg.county = "" // a city in more than one county
+		}
+		gg.add(household, g.Tickets)
+		total.add(household, g.Tickets)
+	}
+	api.Commit(tx)
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].state != groups[j].state {
+			return groups[i].state < groups[j].state
+		}
+		if groups[i].county != groups[j].county {
+			return groups[i].county < groups[j].county
+		}
+		if strings.ToLower(groups[i].city) != strings.ToLower(groups[j].city) {
+			return strings.ToLower(groups[i].city) < strings.ToLower(groups[j].city)
+		}
+		return groups[i].zip < groups[j].zip
+	})
+	if r.FormValue("format") == "csv" {
+		emitGeoReportCSV(w, group, groups, &noAddress, &total)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("group", group)
+		jw.Prop("groups", func() {
+			jw.Array(func() {
+				for _, gg := range groups {
+					jw.Object(func() {
+						switch group {
+						case "zip":
+							jw.Prop("zip", gg.zip)
+						case "city":
+							jw.Prop("city", gg.city)
+						}
+						jw.Prop("county", gg.county)
+						jw.Prop("state", gg.state)
+						writeGeoCounts(jw, gg)
+					})
+				}
+			})
+		})
+		jw.Prop("noAddress", func() {
+			jw.Object(func() { writeGeoCounts(jw, &noAddress) })
+		})
+		jw.Prop("total", func() {
+			jw.Object(func() { writeGeoCounts(jw, &total) })
+		})
+	})
+	jw.Close()
+}
+
+// writeGeoCounts writes the counts of a geoGroup as properties of the current
+// JSON object.
+func writeGeoCounts(jw json.Writer, gg *geoGroup) {
+	jw.Prop("households", len(gg.households))
+	jw.Prop("orders", gg.orders)
+	jw.Prop("tickets", gg.tickets)
+}
+
+// emitGeoReportCSV writes the geographic report in CSV format, with one row
+// per group, followed by rows for the orders without an address and for the
+// totals.
+func emitGeoReportCSV(w http.ResponseWriter, group string, groups []*geoGroup, noAddress, total *geoGroup) {
+	var (
+		cw     *csv.Writer
+		header []string
+		blanks []string
+	)
+	counts := func(gg *geoGroup) []string {
+		return []string{strconv.Itoa(len(gg.households)), strconv.Itoa(gg.orders), strconv.Itoa(gg.tickets)}
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="patrons-by-%s.csv"`, group))
+	cw = csv.NewWriter(w)
+	switch group {
+	case "zip":
+		header = []string{"Zip", "County", "State"}
+	case "city":
+		header = []string{"City", "County", "State"}
+	case "county":
+		header = []string{"County", "State"}
+	}
+	blanks = make([]string, len(header)-1)
+	cw.Write(append(header, "Households", "Orders", "Tickets"))
+	for _, gg := range groups {
+		var row []string
+
+		switch group {
+		case "zip":
+			row = []string{gg.zip, gg.county, gg.state}
+		case "city":
+			row = []string{gg.city, gg.county, gg.state}
+		case "county":
+			if row = []string{gg.county, gg.state}; gg.county == "" {
+				row[0] = "(other)"
+			}
+		}
+		cw.Write(append(row, counts(gg)...))
+	}
+	cw.Write(append(append([]string{"(no address)"}, blanks...), counts(noAddress)...))
+	cw.Write(append(append([]string{"Total"}, blanks...), counts(total)...))
+	cw.Flush()
+}
@@ -0,0 +1,222 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// GetDoorList handles GET /ofcapi/event/${id}/doorlist requests.  It returns
+// an alphabetized list of the orders expected at the event, for use at the
+// door when the ticket scanners are unavailable.  The list is returned as a
+// PDF unless the format=csv parameter is given.
+func GetDoorList(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
+	var (
+		event   *model.Event
+		list    []*db.DoorListEntry
+		classes []string
+		seen    = map[string]bool{}
+	)
+	// Verify permissions.
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	// Get the event and its door list.
+	if event = tx.FetchEvent(eventID); event == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	list = tx.FetchEventDoorList(event)
+	api.Commit(tx)
+	// Sort the list by last name, and collect the set of ticket classes
+	// that appear on it.
+	for _, de := range list {
+		if de.Name != "" {
+			de.Name = api.LastNameFirst(de.Name)
+		}
+		for tc := range de.Tickets {
+			if !seen[tc] {
+				seen[tc] = true
+				classes = append(classes, tc)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if strings.ToLower(list[i].Name) != strings.ToLower(list[j].Name) {
+			return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+		}
+		return list[i].ID < list[j].ID
+	})
+	sort.Strings(classes)
+	// Emit the list in the requested format.
+	if r.FormValue("format") == "csv" {
+		emitDoorListCSV(w, event, list, classes)
+	} else {
+		emitDoorListPDF(w, event, list, classes)
+	}
+}
+
+// doorListClassName returns the column heading for a ticket class.
+func doorListClassName(class string) string {
+	if class == "" {
+		return "General"
+	}
+	return class
+}
+
+// doorListName returns the name to show for a door list entry.
+func doorListName(de *db.DoorListEntry) string {
+	if de.Name != "" {
+		return de.Name
+	}
+	if de.Email != "" {
+		return de.Email
+	}
+	return "(no name)"
+}
+
+// emitDoorListCSV writes the door list in CSV format.
+func emitDoorListCSV(w http.ResponseWriter, event *model.Event, list []*db.DoorListEntry, classes []string) {
+	var (
+		cw  *csv.Writer
+		row []string
+	)
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="doorlist-%s.csv"`, event.ID))
+	cw = csv.NewWriter(w)
+	row = []string{"Checked In", "Name", "Order", "Email"}
+	for _, tc := range classes {
+		row = append(row, doorListClassName(tc))
+	}
+	row = append(row, "Customer Note", "Office Note", "Guests")
+	cw.Write(row)
+	for _, de := range list {
+		row = []string{"", doorListName(de), strconv.Itoa(int(de.ID)), de.Email}
+		for _, tc := range classes {
+			if c := de.Tickets[tc]; c != 0 {
+				row = append(row, strconv.Itoa(c))
+			} else {
+				row = append(row, "")
+			}
+		}
+		row = append(row, de.CNote, de.ONote, strings.Join(de.Guests, "; "))
+		cw.Write(row)
+	}
+	cw.Flush()
+}
+
+// emitDoorListPDF writes the door list in PDF format.
+func emitDoorListPDF(w http.ResponseWriter, event *model.Event, list []*db.DoorListEntry, classes []string) {
+	const (
+		lineHeight  = 5.0
+		checkWidth  = 10.0
+		nameWidth   = 60.0
+		orderWidth  = 16.0
+		classWidth  = 18.0
+		pageMargin  = 10.0
+		headerColor = 220
+	)
+	var (
+		pdf        *gofpdf.Fpdf
+		tr         func(string) string
+		pageWidth  float64
+		notesWidth float64
+		total      = map[string]int{}
+	)
+	pdf = gofpdf.New("L", "mm", "Letter", "")
+	tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(false, pageMargin)
+	pageWidth, _ = pdf.GetPageSize()
+	notesWidth = pageWidth - 2*pageMargin - checkWidth - nameWidth - orderWidth - classWidth*float64(len(classes))
+	header := func() {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, tr(fmt.Sprintf("Door List: %s, %s", event.Name,
+			event.Start.Format("Monday, January 2, 2006, 3:04pm"))), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(headerColor, headerColor, headerColor)
+		pdf.CellFormat(checkWidth, lineHeight+1, "", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(nameWidth, lineHeight+1, "Name", "1", 0, "L", true, 0, "")
+		pdf.CellFormat(orderWidth, lineHeight+1, "Order", "1", 0, "R", true, 0, "")
+		for _, tc := range classes {
+			pdf.CellFormat(classWidth, lineHeight+1, tr(doorListClassName(tc)), "1", 0, "C", true, 0, "")
+		}
+		pdf.CellFormat(notesWidth, lineHeight+1, "Notes", "1", 1, "L", true, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	header()
+	for _, de := range list {
+		var (
+			notes  []string
+			lines  [][]byte
+			height float64
+			x, y   float64
+		)
+		if de.CNote != "" {
+			notes = append(notes, "Customer: "+de.CNote)
+		}
+		if de.ONote != "" {
+			notes = append(notes, "Office: "+de.ONote)
+		}
+		if len(de.Guests) != 0 {
+			notes = append(notes, "Guests: "+strings.Join(de.Guests, ", "))
+		}
+		lines = pdf.SplitLines([]byte(tr(strings.Join(notes, "\n"))), notesWidth-2)
+		if len(lines) == 0 {
+			lines = [][]byte{nil}
+		}
+		height = lineHeight * float64(len(lines))
+		if _, pageHeight := pdf.GetPageSize(); pdf.GetY()+height > pageHeight-pageMargin {
+			header()
+		}
+		x, y = pdf.GetXY()
+		cell := func(width float64, text, align string) {
+			pdf.Rect(x, y, width, height, "D")
+			pdf.SetXY(x, y)
+			pdf.CellFormat(width, lineHeight, text, "", 0, align, false, 0, "")
+			x += width
+		}
+		pdf.Rect(x+checkWidth/2-2, y+lineHeight/2-2, 4, 4, "D")
+		cell(checkWidth, "", "C")
+		cell(nameWidth, tr(doorListName(de)), "L")
+		cell(orderWidth, strconv.Itoa(int(de.ID)), "R")
+		for _, tc := range classes {
+			var count string
+			if c := de.Tickets[tc]; c != 0 {
+				count = strconv.Itoa(c)
+				total[tc] += c
+			}
+			cell(classWidth, count, "C")
+		}
+		pdf.Rect(x, y, notesWidth, height, "D")
+		for i, line := range lines {
+			pdf.SetXY(x+1, y+float64(i)*lineHeight)
+			pdf.CellFormat(notesWidth-2, lineHeight, string(line), "", 0, "L", false, 0, "")
+		}
+		pdf.SetXY(pageMargin, y+height)
+	}
+	// Add a totals line.
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(checkWidth, lineHeight+1, "", "", 0, "C", false, 0, "")
+	pdf.CellFormat(nameWidth, lineHeight+1, fmt.Sprintf("%d orders", len(list)), "", 0, "L", false, 0, "")
+	pdf.CellFormat(orderWidth, lineHeight+1, "", "", 0, "R", false, 0, "")
+	for _, tc := range classes {
+		pdf.CellFormat(classWidth, lineHeight+1, strconv.Itoa(total[tc]), "", 0, "C", false, 0, "")
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="doorlist-%s.pdf"`, event.ID))
+	if err := pdf.Output(w); err != nil {
+		panic(err)
+	}
+}
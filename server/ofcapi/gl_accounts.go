@@ -0,0 +1,221 @@
+package ofcapi
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ListGLAccounts handles GET /ofcapi/glAccount requests.
+func ListGLAccounts(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		list []*model.GLAccount
+		jw   json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivSetupOrders) == nil {
+		return
+	}
+	list = tx.FetchGLAccounts()
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for _, gla := range list {
+			writeGLAccount(jw, gla)
+		}
+	})
+	jw.Close()
+}
+
+// GetGLAccount handles GET /ofcapi/glAccount/${id} requests.
+func GetGLAccount(tx db.Tx, w http.ResponseWriter, r *http.Request, glaID model.GLAccountID) {
+	var gla *model.GLAccount
+
+	if auth.GetSession(tx, w, r, model.PrivSetupOrders) == nil {
+		return
+	}
+	if gla = tx.FetchGLAccount(glaID); gla == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(emitGLAccount(gla))
+}
+
+// CreateGLAccount handles POST /ofcapi/glAccount requests.
+func CreateGLAccount(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		session *model.Session
+		gla     *model.GLAccount
+		out     []byte
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if gla, err = parseGLAccount(r.Body); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	if reason := validateGLAccount(tx, gla); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	tx.SaveGLAccount(gla)
+	api.Commit(tx)
+	out = emitGLAccount(gla)
+	log.Printf("%s CREATE GL ACCOUNT %s", session.Username, out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// UpdateGLAccount handles PUT /ofcapi/glAccount/${id} requests.
+func UpdateGLAccount(tx db.Tx, w http.ResponseWriter, r *http.Request, glaID model.GLAccountID) {
+	var (
+		session *model.Session
+		gla     *model.GLAccount
+		out     []byte
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if tx.FetchGLAccount(glaID) == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if gla, err = parseGLAccount(r.Body); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	gla.ID = glaID
+	if reason := validateGLAccount(tx, gla); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	tx.SaveGLAccount(gla)
+	api.Commit(tx)
+	out = emitGLAccount(gla)
+	log.Printf("%s UPDATE GL ACCOUNT %s", session.Username, out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// DeleteGLAccount handles DELETE /ofcapi/glAccount/${id} requests.
+func DeleteGLAccount(tx db.Tx, w http.ResponseWriter, r *http.Request, glaID model.GLAccountID) {
+	var (
+		session *model.Session
+		gla     *model.GLAccount
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if gla = tx.FetchGLAccount(glaID); gla == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.DeleteGLAccount(gla)
+	api.Commit(tx)
+	log.Printf("%s DELETE GL ACCOUNT %d", session.Username, glaID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseGLAccount reads the general ledger account mapping details from the
+// request body.
+func parseGLAccount(r io.Reader) (gla *model.GLAccount, err error) {
+	var jr = json.NewReader(r)
+
+	gla = new(model.GLAccount)
+	err = jr.Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "kind":
+			return json.StringHandler(func(s string) { gla.Kind = model.GLAccountKind(s) })
+		case "productType":
+			return json.StringHandler(func(s string) { gla.ProductType = model.ProductType(s) })
+		case "series":
+			return json.StringHandler(func(s string) { gla.Series = strings.TrimSpace(s) })
+		case "paymentType":
+			return json.StringHandler(func(s string) { gla.PaymentType = strings.TrimSpace(s) })
+		case "account":
+			return json.StringHandler(func(s string) { gla.Account = strings.TrimSpace(s) })
+		default:
+			return json.RejectHandler()
+		}
+	}))
+	return gla, err
+}
+
+// validateGLAccount returns the reason the general ledger account mapping is
+// invalid, or an empty string if it is valid.
+func validateGLAccount(tx db.Tx, gla *model.GLAccount) string {
+	if gla.Account == "" {
+		return "account is required"
+	}
+	switch gla.Kind {
+	case model.GLRevenue, model.GLDeferred:
+		if gla.PaymentType != "" {
+			return "paymentType is not allowed for revenue mappings"
+		}
+		switch gla.ProductType {
+		case "", model.ProdTicket, model.ProdRecording, model.ProdDonation, model.ProdSheetMusic,
+			model.ProdAuctionItem, model.ProdWardrobe, model.ProdOther, model.ProdRegistration, model.ProdFee:
+			break
+		default:
+			return "invalid productType"
+		}
+	case model.GLPayment:
+		if gla.ProductType != "" || gla.Series != "" {
+			return "productType and series are not allowed for payment mappings"
+		}
+	default:
+		return "invalid kind"
+	}
+	for _, other := range tx.FetchGLAccounts() {
+		if other.ID != gla.ID && other.Kind == gla.Kind && other.ProductType == gla.ProductType &&
+			other.Series == gla.Series && other.PaymentType == gla.PaymentType {
+			return "another mapping has the same selection"
+		}
+	}
+	return ""
+}
+
+// emitGLAccount generates the JSON representation of a general ledger account
+// mapping.
+func emitGLAccount(gla *model.GLAccount) []byte {
+	var (
+		buf bytes.Buffer
+		jw  = json.NewWriter(&buf)
+	)
+	writeGLAccount(jw, gla)
+	jw.Close()
+	return buf.Bytes()
+}
+
+// writeGLAccount writes the JSON representation of a general ledger account
+// mapping to the JSON writer.
+func writeGLAccount(jw json.Writer, gla *model.GLAccount) {
+	jw.Object(func() {
+		jw.Prop("id", int(gla.ID))
+		jw.Prop("kind", string(gla.Kind))
+		if gla.ProductType != "" {
+			jw.Prop("productType", string(gla.ProductType))
+		}
+		if gla.Series != "" {
+			jw.Prop("series", gla.Series)
+		}
+		if gla.PaymentType != "" {
+			jw.Prop("paymentType", gla.PaymentType)
+		}
+		jw.Prop("account", gla.Account)
+	})
+}
@@ -0,0 +1,135 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// GetGLExport handles GET /ofcapi/glExport requests.  It returns the general
+// ledger journal entries, one per day, for the days between the from and to
+// dates (inclusive, YYYY-MM-DD; the default is the previous month).  They are
+// returned in CSV format, or with format=iif, in QuickBooks IIF format.  The
+// entries for days that are over are recorded when first exported, and don't
+// change afterward; later changes to them are posted as adjustments in a later
+// export (see api.BuildJournal).
+func GetGLExport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		from    time.Time
+		to      time.Time
+		now     = time.Now()
+		entries []*api.JournalEntry
+		err     error
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	from = time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local)
+	to = time.Date(now.Year(), now.Month(), 0, 0, 0, 0, 0, time.Local)
+	if s := r.FormValue("from"); s != "" {
+		if from, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid from")
+			return
+		}
+	}
+	if s := r.FormValue("to"); s != "" {
+		if to, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			api.BadRequestError(tx, w, "invalid to")
+			return
+		}
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, time.Local)
+	if !from.Before(to) {
+		api.BadRequestError(tx, w, "invalid date range")
+		return
+	}
+	switch r.FormValue("format") {
+	case "", "csv", "iif":
+		break
+	default:
+		api.BadRequestError(tx, w, "invalid format")
+		return
+	}
+	entries = api.BuildJournal(tx, from, to)
+	api.Commit(tx)
+	if r.FormValue("format") == "iif" {
+		emitGLExportIIF(w, entries, from, to)
+	} else {
+		emitGLExportCSV(w, entries, from, to)
+	}
+}
+
+// emitGLExportCSV writes the journal entries in CSV format, with one row per
+// journal line.
+func emitGLExportCSV(w http.ResponseWriter, entries []*api.JournalEntry, from, to time.Time) {
+	var cw *csv.Writer
+
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="journal-%s-%s.csv"`,
+		from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")))
+	cw = csv.NewWriter(w)
+	cw.Write([]string{"Date", "Account", "Debit", "Credit", "Memo"})
+	for _, je := range entries {
+		var memo = glExportMemo(je)
+
+		for _, jl := range je.Lines {
+			var debit, credit string
+
+			if jl.Amount > 0 {
+				debit = fmt.Sprintf("%.2f", float64(jl.Amount)/100.0)
+			} else {
+				credit = fmt.Sprintf("%.2f", float64(-jl.Amount)/100.0)
+			}
+			cw.Write([]string{je.Date.Format("2006-01-02"), jl.Account, debit, credit, memo})
+		}
+	}
+	cw.Flush()
+}
+
+// emitGLExportIIF writes the journal entries in QuickBooks IIF format, as
+// general journal transactions.
+func emitGLExportIIF(w http.ResponseWriter, entries []*api.JournalEntry, from, to time.Time) {
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="journal-%s-%s.iif"`,
+		from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")))
+	fmt.Fprint(w, "!TRNS\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tMEMO\r\n")
+	fmt.Fprint(w, "!SPL\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tMEMO\r\n")
+	fmt.Fprint(w, "!ENDTRNS\r\n")
+	for _, je := range entries {
+		var memo = glExportMemo(je)
+
+		for i, jl := range je.Lines {
+			var kind = "SPL"
+
+			if i == 0 {
+				kind = "TRNS"
+			}
+			fmt.Fprintf(w, "%s\tGENERAL JOURNAL\t%s\t%s\t%.2f\t%s\r\n", kind, je.Date.Format("01/02/2006"),
+				iifField(jl.Account), float64(jl.Amount)/100.0, memo)
+		}
+		fmt.Fprint(w, "ENDTRNS\r\n")
+	}
+}
+
+// glExportMemo returns the memo for a journal entry.
+func glExportMemo(je *api.JournalEntry) string {
+	return "Schola Cantorum orders " + je.Date.Format("2006-01-02")
+}
+
+// iifField removes the characters that can't appear in an IIF field.
+func iifField(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\t', '\r', '\n', '"':
+			return ' '
+		}
+		return r
+	}, s)
+}
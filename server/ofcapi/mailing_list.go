@@ -0,0 +1,63 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// GetMailingList handles GET /ofcapi/mailingList requests.  It returns, in CSV
+// format, the contacts who have consented to marketing email and haven't since
+// unsubscribed, with the date of their last purchase and the series for which
+// they have bought tickets.  The column names are those expected by mailing
+// list services (e.g. Mailchimp), so the file can be imported directly.
+func GetMailingList(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		list []*db.MailingContact
+		cw   *csv.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivManageOrders) == nil {
+		return
+	}
+	list = tx.FetchMailingList()
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mailing-list-%s.csv"`, time.Now().Format("20060102")))
+	cw = csv.NewWriter(w)
+	cw.Write([]string{"Email Address", "First Name", "Last Name", "Last Purchase", "Series"})
+	for _, mc := range list {
+		var first, last = splitName(mc.Name)
+
+		cw.Write([]string{mc.Email, first, last, mc.LastPurchase.Format("2006-01-02"), strings.Join(mc.Series, ", ")})
+	}
+	cw.Flush()
+}
+
+// splitName splits a customer name into first and last names.  The last word
+// is taken as the last name, except that a trailing suffix such as "Jr." stays
+// with it.
+func splitName(name string) (first, last string) {
+	var words = strings.Fields(name)
+
+	switch len(words) {
+	case 0:
+		return "", ""
+	case 1:
+		return words[0], ""
+	}
+	idx := len(words) - 1
+	if nameSuffixes[strings.ToLower(strings.TrimRight(words[idx], ".,"))] && idx > 1 {
+		idx--
+	}
+	return strings.TrimRight(strings.Join(words[:idx], " "), ","), strings.Join(words[idx:], " ")
+}
+
+// nameSuffixes are the suffixes that splitName keeps with the last name.
+var nameSuffixes = map[string]bool{"jr": true, "sr": true, "ii": true, "iii": true, "iv": true, "md": true, "phd": true}
@@ -0,0 +1,102 @@
+package ofcapi
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ListOrderEmails handles GET /ofcapi/order/${id}/emails requests.  It returns
+// the list of email messages about the order, and their delivery status.
+func ListOrderEmails(tx db.Tx, w http.ResponseWriter, r *http.Request, orderID model.OrderID) {
+	var (
+		emails []*model.Email
+		jw     json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if tx.FetchOrder(orderID) == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	emails = tx.FetchOrderEmails(orderID)
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for _, e := range emails {
+			emitOrderEmail(jw, e)
+		}
+	})
+	jw.Close()
+}
+
+// ResendOrderReceipt handles POST /ofcapi/order/${id}/emails requests.  It
+// queues a new receipt for the order, reflecting its current state, and
+// returns the queued message's status.
+func ResendOrderReceipt(tx db.Tx, w http.ResponseWriter, r *http.Request, orderID model.OrderID) {
+	var (
+		session *model.Session
+		order   *model.Order
+		email   *model.Email
+		jw      json.Writer
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivManageOrders); session == nil {
+		return
+	}
+	if order = tx.FetchOrder(orderID); order == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if !order.Valid {
+		api.BadRequestError(tx, w, "order not complete")
+		return
+	}
+	if email = api.EmitReceipt(tx, order); email == nil {
+		api.BadRequestError(tx, w, "order has no receipt to send")
+		return
+	}
+	api.Commit(tx)
+	log.Printf("%s RESEND RECEIPT for order %d to %s", session.Username, orderID, order.Email)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	emitOrderEmail(jw, email)
+	jw.Close()
+	api.SendQueuedEmail()
+}
+
+// emitOrderEmail emits the JSON description of an email message, without its
+// content.
+func emitOrderEmail(jw json.Writer, e *model.Email) {
+	jw.Object(func() {
+		jw.Prop("id", int(e.ID))
+		jw.Prop("created", e.Created.Format(time.RFC3339))
+		jw.Prop("to", func() {
+			jw.Array(func() {
+				for _, to := range e.To {
+					jw.String(to)
+				}
+			})
+		})
+		jw.Prop("subject", e.Subject)
+		jw.Prop("status", e.Status())
+		jw.Prop("attempts", e.Attempts)
+		if !e.Sent.IsZero() {
+			jw.Prop("sent", e.Sent.Format(time.RFC3339))
+		}
+		if !e.NextAttempt.IsZero() {
+			jw.Prop("nextAttempt", e.NextAttempt.Format(time.RFC3339))
+		}
+		if e.Error != "" {
+			jw.Prop("error", e.Error)
+		}
+	})
+}
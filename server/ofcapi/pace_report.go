@@ -0,0 +1,260 @@
+package ofcapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// eventPace is the ticket sales pace of a single event.
+type eventPace struct {
+	event   *model.Event
+	tickets []int // cumulative tickets sold, indexed by day from T-days
+	amounts []int // cumulative ticket revenue in cents, likewise
+}
+
+// paceComparison pairs the pace of a requested event with that of the
+// comparable event in the prior series, if any.
+type paceComparison struct {
+	current *eventPace
+	prior   *eventPace
+}
+
+// GetPaceReport handles GET /ofcapi/paceReport requests.  For each of the
+// events named by the event parameters, it returns the cumulative number of
+// tickets sold, and the revenue from them, as of each day from T-days to T-0
+// relative to the event start (days defaults to 60).  Sales made before T-days
+// are counted on T-days.  The same figures are returned for the comparable
+// event in the prior series, if there is one.  The results are returned as
+// JSON unless the format=csv parameter is given.
+func GetPaceReport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		days        = 60
+		now         = time.Now()
+		events      []*model.Event
+		comparisons []*paceComparison
+		jw          json.Writer
+		err         error
+	)
+	if auth.GetSession(tx, w, r, model.PrivViewOrders) == nil {
+		return
+	}
+	if s := r.FormValue("days"); s != "" {
+		if days, err = strconv.Atoi(s); err != nil || days < 1 || days > 366 {
+			api.BadRequestError(tx, w, "invalid days")
+			return
+		}
+	}
+	r.ParseForm()
+	if len(r.Form["event"]) == 0 {
+		api.BadRequestError(tx, w, "missing event")
+		return
+	}
+	events = tx.FetchEvents()
+	for _, eid := range r.Form["event"] {
+		var (
+			event *model.Event
+			pc    paceComparison
+		)
+		if event = tx.FetchEvent(model.EventID(eid)); event == nil {
+			api.BadRequestError(tx, w, "invalid event "+eid)
+			return
+		}
+		pc.current = computeEventPace(tx, event, days, now)
+		if prior := comparableEvent(events, event); prior != nil {
+			pc.prior = computeEventPace(tx, prior, days, now)
+		}
+		comparisons = append(comparisons, &pc)
+	}
+	api.Commit(tx)
+	if r.FormValue("format") == "csv" {
+		emitPaceReportCSV(w, comparisons, days, now)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("days", days)
+		jw.Prop("events", func() {
+			jw.Array(func() {
+				for _, pc := range comparisons {
+					jw.Object(func() {
+						writeEventPace(jw, pc.current)
+						if pc.prior != nil {
+							jw.Prop("prior", func() {
+								jw.Object(func() {
+									writeEventPace(jw, pc.prior)
+								})
+							})
+						}
+					})
+				}
+			})
+		})
+	})
+	jw.Close()
+}
+
+// computeEventPace computes the ticket sales pace of an event.  The figures
+// stop at the current day for events that haven't happened yet.
+func computeEventPace(tx db.Tx, event *model.Event, days int, now time.Time) (ep *eventPace) {
+	var (
+		start = dayNumber(event.Start)
+		count = days + 1
+	)
+	ep = &eventPace{event: event, tickets: make([]int, days+1), amounts: make([]int, days+1)}
+	for _, sale := range tx.FetchEventTicketSales(event) {
+		var before = start - dayNumber(sale.Created)
+
+		if before < 0 {
+			continue // sold after the day of the event
+		}
+		if before > days {
+			before = days
+		}
+		ep.tickets[days-before] += sale.Tickets
+		ep.amounts[days-before] += sale.Amount
+	}
+	for i := 1; i <= days; i++ {
+		ep.tickets[i] += ep.tickets[i-1]
+		ep.amounts[i] += ep.amounts[i-1]
+	}
+	if future := start - dayNumber(now); future > 0 {
+		if count -= future; count < 0 {
+			count = 0
+		}
+		ep.tickets, ep.amounts = ep.tickets[:count], ep.amounts[:count]
+	}
+	return ep
+}
+
+// dayNumber returns the number of the local calendar day containing the
+// specified time, such that consecutive days have consecutive numbers.
+func dayNumber(t time.Time) int {
+	t = t.In(time.Local)
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+var academicSeriesRE = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+var yearSeriesRE = regexp.MustCompile(`^(\d{4})( .*)$`)
+
+// priorSeries returns the name of the series a year before the specified
+// series, or an empty string if it can't be determined.  Series names are
+// generally "20XX-YY" or "20YY Summer".
+func priorSeries(series string) string {
+	if match := academicSeriesRE.FindStringSubmatch(series); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		return fmt.Sprintf("%04d-%02d", year-1, year%100)
+	}
+	if match := yearSeriesRE.FindStringSubmatch(series); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		return fmt.Sprintf("%04d%s", year-1, match[2])
+	}
+	return ""
+}
+
+// comparableEvent returns the event in the prior series that is comparable to
+// the specified event, or nil if there is none.  That is the uncancelled event
+// with the same name, if there is one, or otherwise the one at the same
+// position in the chronological order of the series.  The events list must be
+// in chronological order.
+func comparableEvent(events []*model.Event, event *model.Event) *model.Event {
+	var (
+		prior  = priorSeries(event.Series)
+		ours   []*model.Event
+		theirs []*model.Event
+	)
+	if prior == "" {
+		return nil
+	}
+	for _, e := range events {
+		if !e.Cancelled.IsZero() && e.ID != event.ID {
+			continue
+		}
+		if e.Series == event.Series {
+			ours = append(ours, e)
+		}
+		if e.Series == prior {
+			if strings.EqualFold(e.Name, event.Name) {
+				return e
+			}
+			theirs = append(theirs, e)
+		}
+	}
+	for i, e := range ours {
+		if e.ID == event.ID && i < len(theirs) {
+			return theirs[i]
+		}
+	}
+	return nil
+}
+
+// writeEventPace writes the properties of an eventPace to the JSON writer.
+func writeEventPace(jw json.Writer, ep *eventPace) {
+	jw.Prop("id", string(ep.event.ID))
+	jw.Prop("name", ep.event.Name)
+	jw.Prop("series", ep.event.Series)
+	jw.Prop("start", ep.event.Start.Format(time.RFC3339))
+	jw.Prop("tickets", func() {
+		jw.Array(func() {
+			for _, t := range ep.tickets {
+				jw.Int(t)
+			}
+		})
+	})
+	jw.Prop("amounts", func() {
+		jw.Array(func() {
+			for _, a := range ep.amounts {
+				jw.Int(a)
+			}
+		})
+	})
+}
+
+// emitPaceReportCSV writes the pace report in CSV format, with one row per day
+// and a pair of columns (tickets and revenue) for each event.
+func emitPaceReportCSV(w http.ResponseWriter, comparisons []*paceComparison, days int, now time.Time) {
+	var (
+		cw     *csv.Writer
+		paces  []*eventPace
+		header = []string{"Day"}
+	)
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pace-%s.csv"`, now.Format("20060102")))
+	for _, pc := range comparisons {
+		paces = append(paces, pc.current)
+		if pc.prior != nil {
+			paces = append(paces, pc.prior)
+		}
+	}
+	for _, ep := range paces {
+		name := fmt.Sprintf("%s %s", ep.event.Start.Format("2006-01-02"), ep.event.Name)
+		header = append(header, name+" Tickets", name+" Amount")
+	}
+	cw = csv.NewWriter(w)
+	cw.Write(header)
+	for i := 0; i <= days; i++ {
+		var row = []string{strconv.Itoa(i - days)}
+
+		for _, ep := range paces {
+			if i < len(ep.tickets) {
+				row = append(row, strconv.Itoa(ep.tickets[i]), fmt.Sprintf("%.2f", float64(ep.amounts[i])/100.0))
+			} else {
+				row = append(row, "", "")
+			}
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+}
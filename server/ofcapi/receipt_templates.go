@@ -0,0 +1,280 @@
+package ofcapi
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ListReceiptTemplates handles GET /ofcapi/receiptTemplate requests.
+func ListReceiptTemplates(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		rts []*model.ReceiptTemplate
+		jw  json.Writer
+	)
+	if auth.GetSession(tx, w, r, model.PrivSetupOrders) == nil {
+		return
+	}
+	rts = tx.FetchReceiptTemplates()
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for _, rt := range rts {
+			writeReceiptTemplate(jw, rt)
+		}
+	})
+	jw.Close()
+}
+
+// GetReceiptTemplate handles GET /ofcapi/receiptTemplate/${id} requests.
+func GetReceiptTemplate(tx db.Tx, w http.ResponseWriter, r *http.Request, rtID model.ReceiptTemplateID) {
+	var rt *model.ReceiptTemplate
+
+	if auth.GetSession(tx, w, r, model.PrivSetupOrders) == nil {
+		return
+	}
+	if rt = tx.FetchReceiptTemplate(rtID); rt == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(emitReceiptTemplate(rt))
+}
+
+// CreateReceiptTemplate handles POST /ofcapi/receiptTemplate requests.
+func CreateReceiptTemplate(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		session *model.Session
+		rt      *model.ReceiptTemplate
+		out     []byte
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if rt, err = parseReceiptTemplate(r.Body); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	if reason := validateReceiptTemplate(tx, rt); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	tx.SaveReceiptTemplate(rt)
+	api.Commit(tx)
+	out = emitReceiptTemplate(rt)
+	log.Printf("%s CREATE RECEIPT TEMPLATE %s", session.Username, out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// UpdateReceiptTemplate handles PUT /ofcapi/receiptTemplate/${id} requests.
+func UpdateReceiptTemplate(tx db.Tx, w http.ResponseWriter, r *http.Request, rtID model.ReceiptTemplateID) {
+	var (
+		session *model.Session
+		rt      *model.ReceiptTemplate
+		out     []byte
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if tx.FetchReceiptTemplate(rtID) == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if rt, err = parseReceiptTemplate(r.Body); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	rt.ID = rtID
+	if reason := validateReceiptTemplate(tx, rt); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	tx.SaveReceiptTemplate(rt)
+	api.Commit(tx)
+	out = emitReceiptTemplate(rt)
+	log.Printf("%s UPDATE RECEIPT TEMPLATE %s", session.Username, out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// DeleteReceiptTemplate handles DELETE /ofcapi/receiptTemplate/${id}
+// requests.
+func DeleteReceiptTemplate(tx db.Tx, w http.ResponseWriter, r *http.Request, rtID model.ReceiptTemplateID) {
+	var (
+		session *model.Session
+		rt      *model.ReceiptTemplate
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivSetupOrders); session == nil {
+		return
+	}
+	if rt = tx.FetchReceiptTemplate(rtID); rt == nil {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.DeleteReceiptTemplate(rt)
+	api.Commit(tx)
+	log.Printf("%s DELETE RECEIPT TEMPLATE %d", session.Username, rtID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewReceiptTemplate handles GET /ofcapi/receiptTemplate/${id}/preview,
+// GET /ofcapi/receiptTemplate/preview, and POST
+// /ofcapi/receiptTemplate/preview requests.  It renders a receipt template for
+// the order given in the order= parameter, and returns the rendered subject,
+// HTML body, and plain text body, without sending anything.  The template is
+// the one with the given ID; or for POST, the one in the request body; or
+// otherwise, the one that would be used for the order's receipt.
+func PreviewReceiptTemplate(tx db.Tx, w http.ResponseWriter, r *http.Request, rtID model.ReceiptTemplateID) {
+	var (
+		rt       *model.ReceiptTemplate
+		order    *model.Order
+		oid      int
+		subject  string
+		htmlBody string
+		textBody string
+		jw       json.Writer
+		err      error
+	)
+	if auth.GetSession(tx, w, r, model.PrivSetupOrders) == nil {
+		return
+	}
+	if oid, err = strconv.Atoi(r.FormValue("order")); err != nil || oid < 1 {
+		api.BadRequestError(tx, w, `invalid "order"`)
+		return
+	}
+	if order = tx.FetchOrder(model.OrderID(oid)); order == nil {
+		api.BadRequestError(tx, w, "no such order")
+		return
+	}
+	switch {
+	case rtID != 0:
+		if rt = tx.FetchReceiptTemplate(rtID); rt == nil {
+			api.NotFoundError(tx, w)
+			return
+		}
+	case r.Method == http.MethodPost:
+		if rt, err = parseReceiptTemplate(r.Body); err != nil {
+			api.BadRequestError(tx, w, err.Error())
+			return
+		}
+	default:
+		if rt = api.SelectReceiptTemplate(tx, order); rt == nil {
+			api.BadRequestError(tx, w, "no receipt is sent for this order")
+			return
+		}
+	}
+	api.Commit(tx)
+	if subject, htmlBody, textBody, err = api.PreviewReceipt(rt, order); err != nil {
+		api.SendError(tx, w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Object(func() {
+		jw.Prop("template", rt.Name)
+		jw.Prop("subject", subject)
+		jw.Prop("html", htmlBody)
+		jw.Prop("text", textBody)
+	})
+	jw.Close()
+}
+
+// parseReceiptTemplate reads the receipt template details from the request
+// body.
+func parseReceiptTemplate(r io.Reader) (rt *model.ReceiptTemplate, err error) {
+	var jr = json.NewReader(r)
+
+	rt = new(model.ReceiptTemplate)
+	err = jr.Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "name":
+			return json.StringHandler(func(s string) { rt.Name = s })
+		case "productType":
+			return json.StringHandler(func(s string) { rt.ProductType = model.ProductType(s) })
+		case "product":
+			return json.StringHandler(func(s string) { rt.Product = model.ProductID(s) })
+		case "subject":
+			return json.StringHandler(func(s string) { rt.Subject = s })
+		case "html":
+			return json.StringHandler(func(s string) { rt.HTML = s })
+		case "text":
+			return json.StringHandler(func(s string) { rt.Text = s })
+		default:
+			return json.RejectHandler()
+		}
+	}))
+	return rt, err
+}
+
+// validateReceiptTemplate returns the reason the receipt template is invalid,
+// or an empty string if it is valid.
+func validateReceiptTemplate(tx db.Tx, rt *model.ReceiptTemplate) string {
+	if rt.Subject == "" || rt.HTML == "" {
+		return "subject and html are required"
+	}
+	if rt.ProductType != "" && rt.Product != "" {
+		return "specify at most one of productType and product"
+	}
+	switch rt.ProductType {
+	case "", model.ProdTicket, model.ProdRecording, model.ProdDonation, model.ProdSheetMusic,
+		model.ProdAuctionItem, model.ProdWardrobe, model.ProdRegistration, model.ProdFee:
+		break
+	default:
+		return "invalid productType"
+	}
+	if rt.Product != "" && tx.FetchProduct(rt.Product) == nil {
+		return "nonexistent product"
+	}
+	for _, other := range tx.FetchReceiptTemplates() {
+		if other.ID != rt.ID && other.ProductType == rt.ProductType && other.Product == rt.Product {
+			return "another template has the same selection"
+		}
+	}
+	if err := api.CheckReceiptTemplate(rt); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// emitReceiptTemplate generates the JSON representation of a receipt template.
+func emitReceiptTemplate(rt *model.ReceiptTemplate) []byte {
+	var (
+		buf bytes.Buffer
+		jw  = json.NewWriter(&buf)
+	)
+	writeReceiptTemplate(jw, rt)
+	jw.Close()
+	return buf.Bytes()
+}
+
+// writeReceiptTemplate writes the JSON representation of a receipt template to
+// the JSON writer.
+func writeReceiptTemplate(jw json.Writer, rt *model.ReceiptTemplate) {
+	jw.Object(func() {
+		jw.Prop("id", int(rt.ID))
+		jw.Prop("name", rt.Name)
+		if rt.ProductType != "" {
+			jw.Prop("productType", string(rt.ProductType))
+		}
+		if rt.Product != "" {
+			jw.Prop("product", string(rt.Product))
+		}
+		jw.Prop("subject", rt.Subject)
+		jw.Prop("html", rt.HTML)
+		jw.Prop("text", rt.Text)
+	})
+}
@@ -2,7 +2,7 @@ package ofcapi
 
 import (
 	"net/http"
-	"strings"
+	"sort"
 	"time"
 
 	"github.com/rothskeller/json"
@@ -13,7 +13,8 @@ import (
 	"scholacantorum.org/orders/model"
 )
 
-// RunReport runs a report, handling GET /ofcapi/report requests.
+// RunReport runs a report, handling GET /ofcapi/report requests.  The results
+// are returned as JSON, or with format=csv, the report lines as CSV.
 func RunReport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 	var (
 		def    *model.ReportDefinition
@@ -24,65 +25,24 @@ func RunReport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Get the report definition.
-	if def = parseReportDef(tx, r); def == nil {
+	r.ParseForm()
+	if def = api.ParseReportDefinition(tx, r.Form); def == nil {
 		api.BadRequestError(tx, w, "invalid report definition")
 		return
 	}
 	result = tx.RunReport(def)
 	// Send back the results.
 	api.Commit(tx)
+	if r.FormValue("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+		api.WriteReportCSV(w, result)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	emitReport(w, result)
 }
 
-func parseReportDef(tx db.Tx, r *http.Request) (def *model.ReportDefinition) {
-	def = new(model.ReportDefinition)
-	r.ParseForm()
-	for _, os := range r.Form["orderSource"] {
-		switch os := model.OrderSource(os); os {
-		case model.OrderFromPublic, model.OrderFromMembers, model.OrderFromGala, model.OrderFromOffice, model.OrderInPerson:
-			def.OrderSources = append(def.OrderSources, os)
-		default:
-			return nil
-		}
-	}
-	def.Customer = strings.ToLower(strings.TrimSpace(r.FormValue("customer")))
-	if before := r.FormValue("createdBefore"); before != "" {
-		if t, err := time.ParseInLocation("2006-01-02T15:04:05", before, time.Local); err != nil {
-			return nil
-		} else {
-			def.CreatedBefore = t
-		}
-	}
-	if after := r.FormValue("createdAfter"); after != "" {
-		if t, err := time.ParseInLocation("2006-01-02T15:04:05", after, time.Local); err != nil {
-			return nil
-		} else {
-			def.CreatedAfter = t
-		}
-	}
-	for _, v := range r.Form["orderCoupon"] {
-		if v := strings.TrimSpace(v); v != "" {
-			def.OrderCoupons = append(def.OrderCoupons, strings.ToUpper(v))
-		}
-	}
-	for _, pid := range r.Form["product"] {
-		if tx.FetchProduct(model.ProductID(pid)) == nil {
-			return nil
-		}
-		def.Products = append(def.Products, model.ProductID(pid))
-	}
-	def.PaymentTypes = r.Form["paymentType"]
-	def.TicketClasses = r.Form["ticketClass"]
-	for _, eid := range r.Form["usedAtEvent"] {
-		if eid != "" && tx.FetchEvent(model.EventID(eid)) == nil {
-			return nil
-		}
-		def.UsedAtEvents = append(def.UsedAtEvents, model.EventID(eid))
-	}
-	return def
-}
-
 func emitReport(w http.ResponseWriter, result *model.ReportResults) {
 	var jw = json.NewWriter(w)
 	jw.Object(func() {
@@ -101,6 +61,9 @@ func emitReport(w http.ResponseWriter, result *model.ReportResults) {
 							jw.Prop("orderSource", string(r.OrderSource))
 							jw.Prop("paymentType", r.PaymentType)
 							jw.Prop("amount", r.Amount)
+							if r.Deductible != 0 {
+								jw.Prop("deductible", r.Deductible)
+							}
 						})
 					}
 				})
@@ -109,6 +72,8 @@ func emitReport(w http.ResponseWriter, result *model.ReportResults) {
 		jw.Prop("orderCount", result.OrderCount)
 		jw.Prop("itemCount", result.ItemCount)
 		jw.Prop("totalAmount", result.TotalAmount)
+		jw.Prop("totalDeductible", result.TotalDeductible)
+		jw.Prop("totalFees", result.TotalFees)
 		jw.Prop("orderSources", func() {
 			jw.Array(func() {
 				for os, c := range result.OrderSources {
@@ -158,6 +123,71 @@ func emitReport(w http.ResponseWriter, result *model.ReportResults) {
 				}
 			})
 		})
+		jw.Prop("amounts", func() {
+			jw.Array(func() {
+				for _, ac := range result.Amounts {
+					jw.Object(func() {
+						if ac.HasMin {
+							jw.Prop("min", float64(ac.Min)/100.0)
+						}
+						if ac.HasMax {
+							jw.Prop("max", float64(ac.Max)/100.0)
+						}
+						jw.Prop("c", ac.Count)
+					})
+				}
+			})
+		})
+		jw.Prop("orderValid", func() {
+			jw.Array(func() {
+				for _, v := range []bool{true, false} {
+					if c, ok := result.OrderValid[v]; ok {
+						jw.Object(func() {
+							jw.Prop("v", v)
+							jw.Prop("c", c)
+						})
+					}
+				}
+			})
+		})
+		jw.Prop("members", func() {
+			var members = make([]int, 0, len(result.Members))
+
+			for m := range result.Members {
+				members = append(members, m)
+			}
+			sort.Ints(members)
+			jw.Array(func() {
+				for _, m := range members {
+					jw.Object(func() {
+						jw.Prop("m", m)
+						jw.Prop("c", result.Members[m])
+					})
+				}
+			})
+		})
+		jw.Prop("productTypes", func() {
+			var ptypes = make([]string, 0, len(result.ProductTypes))
+
+			for pt := range result.ProductTypes {
+				ptypes = append(ptypes, string(pt))
+			}
+			sort.Strings(ptypes)
+			jw.Array(func() {
+				for _, pt := range ptypes {
+					jw.Object(func() {
+						jw.Prop("pt", pt)
+						jw.Prop("c", result.ProductTypes[model.ProductType(pt)])
+					})
+				}
+			})
+		})
+		jw.Prop("series", func() {
+			emitStringCounts(jw, result.Series)
+		})
+		jw.Prop("options", func() {
+			emitStringCounts(jw, result.Options)
+		})
 	})
 	jw.Close()
 }
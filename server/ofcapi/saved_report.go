@@ -0,0 +1,236 @@
+package ofcapi
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/auth"
+	"scholacantorum.org/orders/db"
+	"scholacantorum.org/orders/model"
+)
+
+// ListSavedReports handles GET /ofcapi/savedReport requests.  It returns the
+// saved reports owned by the caller.
+func ListSavedReports(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		session *model.Session
+		list    []*model.SavedReport
+		jw      json.Writer
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivViewOrders); session == nil {
+		return
+	}
+	list = tx.FetchSavedReports(session.Username)
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	jw = json.NewWriter(w)
+	jw.Array(func() {
+		for _, sr := range list {
+			writeSavedReport(jw, sr)
+		}
+	})
+	jw.Close()
+}
+
+// GetSavedReport handles GET /ofcapi/savedReport/${id} requests.
+func GetSavedReport(tx db.Tx, w http.ResponseWriter, r *http.Request, srID model.SavedReportID) {
+	var (
+		session *model.Session
+		sr      *model.SavedReport
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivViewOrders); session == nil {
+		return
+	}
+	if sr = tx.FetchSavedReport(srID); sr == nil || sr.Owner != session.Username {
+		api.NotFoundError(tx, w)
+		return
+	}
+	api.Commit(tx)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(emitSavedReport(sr))
+}
+
+// CreateSavedReport handles POST /ofcapi/savedReport requests.  The new report
+// is owned by the caller.  Scheduling email delivery of the report (i.e.,
+// giving it recipients) requires PrivManageOrders, since the delivered report
+// includes customer names and email addresses.
+func CreateSavedReport(tx db.Tx, w http.ResponseWriter, r *http.Request) {
+	var (
+		session *model.Session
+		sr      *model.SavedReport
+		out     []byte
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivViewOrders); session == nil {
+		return
+	}
+	if sr, err = parseSavedReport(r.Body); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	if len(sr.Recipients) != 0 && session.Privileges&model.PrivManageOrders == 0 {
+		api.ForbiddenError(tx, w)
+		return
+	}
+	if reason := validateSavedReport(tx, sr); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	sr.Owner = session.Username
+	sr.Created = time.Now()
+	tx.SaveSavedReport(sr)
+	api.Commit(tx)
+	out = emitSavedReport(sr)
+	log.Printf("%s CREATE SAVED REPORT %s", session.Username, out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// UpdateSavedReport handles PUT /ofcapi/savedReport/${id} requests.  As with
+// CreateSavedReport, giving the report recipients requires PrivManageOrders.
+func UpdateSavedReport(tx db.Tx, w http.ResponseWriter, r *http.Request, srID model.SavedReportID) {
+	var (
+		session *model.Session
+		old     *model.SavedReport
+		sr      *model.SavedReport
+		out     []byte
+		err     error
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivViewOrders); session == nil {
+		return
+	}
+	if old = tx.FetchSavedReport(srID); old == nil || old.Owner != session.Username {
+		api.NotFoundError(tx, w)
+		return
+	}
+	if sr, err = parseSavedReport(r.Body); err != nil {
+		api.BadRequestError(tx, w, err.Error())
+		return
+	}
+	if len(sr.Recipients) != 0 && session.Privileges&model.PrivManageOrders == 0 {
+		api.ForbiddenError(tx, w)
+		return
+	}
+	if reason := validateSavedReport(tx, sr); reason != "" {
+		api.BadRequestError(tx, w, reason)
+		return
+	}
+	sr.ID, sr.Owner, sr.Created, sr.LastRun = old.ID, old.Owner, old.Created, old.LastRun
+	tx.SaveSavedReport(sr)
+	api.Commit(tx)
+	out = emitSavedReport(sr)
+	log.Printf("%s UPDATE SAVED REPORT %s", session.Username, out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// DeleteSavedReport handles DELETE /ofcapi/savedReport/${id} requests.
+func DeleteSavedReport(tx db.Tx, w http.ResponseWriter, r *http.Request, srID model.SavedReportID) {
+	var (
+		session *model.Session
+		sr      *model.SavedReport
+	)
+	if session = auth.GetSession(tx, w, r, model.PrivViewOrders); session == nil {
+		return
+	}
+	if sr = tx.FetchSavedReport(srID); sr == nil || sr.Owner != session.Username {
+		api.NotFoundError(tx, w)
+		return
+	}
+	tx.DeleteSavedReport(sr)
+	api.Commit(tx)
+	log.Printf("%s DELETE SAVED REPORT %d", session.Username, srID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseSavedReport reads the saved report details from the request body.
+func parseSavedReport(r io.Reader) (sr *model.SavedReport, err error) {
+	var jr = json.NewReader(r)
+
+	sr = new(model.SavedReport)
+	err = jr.Read(json.ObjectHandler(func(key string) json.Handlers {
+		switch key {
+		case "name":
+			return json.StringHandler(func(s string) { sr.Name = strings.TrimSpace(s) })
+		case "query":
+			return json.StringHandler(func(s string) { sr.Query = s })
+		case "schedule":
+			return json.StringHandler(func(s string) { sr.Schedule = model.ReportSchedule(s) })
+		case "recipients":
+			return json.ArrayHandler(func() json.Handlers {
+				return json.StringHandler(func(s string) { sr.Recipients = append(sr.Recipients, strings.TrimSpace(s)) })
+			})
+		default:
+			return json.RejectHandler()
+		}
+	}))
+	return sr, err
+}
+
+// validateSavedReport returns the reason the saved report is invalid, or an
+// empty string if it is valid.
+func validateSavedReport(tx db.Tx, sr *model.SavedReport) string {
+	if sr.Name == "" {
+		return "name is required"
+	}
+	if form, err := url.ParseQuery(sr.Query); err != nil || api.ParseReportDefinition(tx, form) == nil {
+		return "invalid report definition"
+	}
+	switch sr.Schedule {
+	case model.ScheduleNone, model.ScheduleDaily, model.ScheduleWeekly, model.ScheduleEvent:
+		break
+	default:
+		return "invalid schedule"
+	}
+	for _, addr := range sr.Recipients {
+		if !api.ValidEmail(addr) {
+			return "invalid recipient " + addr
+		}
+	}
+	if sr.Schedule != model.ScheduleNone && len(sr.Recipients) == 0 {
+		return "scheduled reports must have recipients"
+	}
+	return ""
+}
+
+// emitSavedReport generates the JSON representation of a saved report.
+func emitSavedReport(sr *model.SavedReport) []byte {
+	var (
+		buf bytes.Buffer
+		jw  = json.NewWriter(&buf)
+	)
+	writeSavedReport(jw, sr)
+	jw.Close()
+	return buf.Bytes()
+}
+
+// writeSavedReport writes the JSON representation of a saved report to the
+// JSON writer.
+func writeSavedReport(jw json.Writer, sr *model.SavedReport) {
+	jw.Object(func() {
+		jw.Prop("id", int(sr.ID))
+		jw.Prop("name", sr.Name)
+		jw.Prop("owner", sr.Owner)
+		jw.Prop("query", sr.Query)
+		jw.Prop("schedule", string(sr.Schedule))
+		jw.Prop("recipients", func() {
+			jw.Array(func() {
+				for _, addr := range sr.Recipients {
+					jw.String(addr)
+				}
+			})
+		})
+		jw.Prop("created", sr.Created.Format(time.RFC3339))
+		if !sr.LastRun.IsZero() {
+			jw.Prop("lastRun", sr.LastRun.Format(time.RFC3339))
+		}
+	})
+}
@@ -3,6 +3,7 @@ package payapi
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rothskeller/json"
@@ -20,10 +21,13 @@ type getPricesData struct {
 	message string
 	price   int
 	options []string
+	funds   []string
 }
 
 // GetPrices returns the prices and availability of one or more products.  It is
-// used to drive payment forms.
+// used to drive payment forms.  If the total parameter is given (an order total
+// in cents), it also returns the processing fee the customer can choose to
+// cover on an order of that total.
 func GetPrices(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 	var (
 		source      model.OrderSource
@@ -34,6 +38,9 @@ func GetPrices(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 		pdata       []*getPricesData
 		product     *model.Product
 		masterSKU   *model.SKU
+		total       int
+		fee         int
+		err         error
 		jw          json.Writer
 	)
 	// Get the request source and authorization.
@@ -55,19 +62,28 @@ func GetPrices(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 	if coupon = r.FormValue("coupon"); coupon == "" {
 		couponMatch = true
 	}
+	if r.FormValue("total") != "" {
+		if total, err = strconv.Atoi(r.FormValue("total")); err != nil || total < 0 {
+			api.BadRequestError(tx, w, "invalid total")
+			return
+		}
+		fee = api.ProcessingFee(total)
+	}
 	// Look up the prices for each product.
 	for _, pid := range productIDs {
 		var (
 			sku *model.SKU
 			pd  getPricesData
 		)
-		// Get the product.  Skip nonexistent ones.
-		if product = tx.FetchProduct(model.ProductID(pid)); product == nil {
+		// Get the product.  Skip nonexistent ones, and tickets to
+		// cancelled events.
+		if product = tx.FetchProduct(model.ProductID(pid)); product == nil || api.ProductCancelled(product) {
 			continue
 		}
 		pd.id = product.ID
 		pd.name = product.ShortName
 		pd.options = product.Options
+		pd.funds = product.Funds
 		// Find the best SKU for this product.
 		for _, s := range product.SKUs {
 			if !api.MatchingSKU(s, coupon, source, true) {
@@ -107,7 +123,7 @@ func GetPrices(tx db.Tx, w http.ResponseWriter, r *http.Request) {
 		jw.String(message)
 	} else {
 		// Return the product data.
-		emitGetPrices(jw, session, couponMatch, pdata)
+		emitGetPrices(jw, session, couponMatch, fee, pdata)
 	}
 	jw.Close()
 }
@@ -122,7 +138,7 @@ func noSalesMessage(sku *model.SKU) string {
 }
 
 // emitGetPrices writes the JSON response.
-func emitGetPrices(jw json.Writer, session *model.Session, couponMatch bool, pdata []*getPricesData) {
+func emitGetPrices(jw json.Writer, session *model.Session, couponMatch bool, fee int, pdata []*getPricesData) {
 	jw.Object(func() {
 		if session != nil && session.Name != "" {
 			// If there's a name in the session, it came from
@@ -145,6 +161,9 @@ func emitGetPrices(jw json.Writer, session *model.Session, couponMatch bool, pda
 			jw.Prop("stripePublicKey", config.Get("stripePublicKey"))
 		}
 		jw.Prop("coupon", couponMatch)
+		if fee != 0 {
+			jw.Prop("fee", fee)
+		}
 		jw.Prop("products", func() {
 			jw.Array(func() {
 				for _, pd := range pdata {
@@ -165,6 +184,15 @@ func emitGetPrices(jw json.Writer, session *model.Session, couponMatch bool, pda
 								})
 							})
 						}
+						if len(pd.funds) != 0 {
+							jw.Prop("funds", func() {
+								jw.Array(func() {
+									for _, f := range pd.funds {
+										jw.String(f)
+									}
+								})
+							})
+						}
 					})
 				}
 			})
@@ -19,6 +19,7 @@ func CaptureOrderPayment(tx db.Tx, w http.ResponseWriter, r *http.Request, order
 		order          *model.Order
 		card           string
 		tentativeEmail string
+		receipt        bool
 		err            error
 	)
 	// Get current session data, if any.
@@ -45,12 +46,15 @@ func CaptureOrderPayment(tx db.Tx, w http.ResponseWriter, r *http.Request, order
 		return
 	}
 	order.Valid = true
+	order.Payments[0].Card = card
 	tx.SaveOrder(order)
 	_, tentativeEmail = tx.FetchCard(card)
+	receipt = api.EmitReceipt(tx, order) != nil
+	receipt = api.EmitTributeNotices(tx, order) != 0 || receipt
 	api.Commit(tx)
 	log.Printf("- CAPTURE ORDER %s", order.ToJSON(true))
-	if order.Email != "" {
-		api.EmitReceipt(order, false)
+	if receipt {
+		api.SendQueuedEmail()
 	}
 	api.UpdateGoogleSheet(order)
 	if order.Email == "" {
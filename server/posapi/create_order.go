@@ -33,6 +33,7 @@ var tokenRE = regexp.MustCompile("^tok_[A-Za-z0-9_]*$")
 //     oNote:  order note from office
 //     inAccess:  flag whether order is in office Access database
 //     coupon:  coupon code used for order
+//     marketingOptIn:  flag whether customer consents to marketing email
 //     [line# begins at 1]
 //     line#.product:  product ID for line #
 //     line#.quantity:  quantity for line #
@@ -46,9 +46,10 @@ func GetEventPrices(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID mo
 			sku     *model.SKU
 			pd      getPricesData
 		)
-		// Get the product.  Make sure it has capacity.
+		// Get the product.  Make sure it isn't for a cancelled event and
+		// has capacity.
 		product = tx.FetchProduct(model.ProductID(pid))
-		if !api.ProductHasCapacity(tx, product) {
+		if !event.Cancelled.IsZero() || api.ProductCancelled(product) || !api.ProductHasCapacity(tx, product) {
 			continue
 		}
 		pd.id = product.ID
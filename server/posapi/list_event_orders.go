@@ -11,16 +11,25 @@ import (
 	"scholacantorum.org/orders/auth"
 	"scholacantorum.org/orders/db"
 	"scholacantorum.org/orders/model"
+	"scholacantorum.org/orders/stripe"
 )
 
 // ListEventOrders returns a list of orders that include tickets valid at the
 // specified event.  It is used to support the Will Call feature of the
-// at-the-door sales app.
+// at-the-door sales app.  With no parameters, it lists all orders with a
+// customer name.  With a card=pm_XXX parameter (a payment method from the
+// Stripe Terminal SDK's readReusableCard call, which charges nothing), it lists
+// orders whose customer matches the one on file for that card.  With a
+// search=XXX parameter, it lists orders whose customer email contains XXX, or
+// whose customer phone number contains the digits of XXX.
 func ListEventOrders(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID model.EventID) {
 	var (
 		session *model.Session
 		event   *model.Event
 		list    []db.EventOrder
+		card    string
+		search  string
+		digits  string
 		jw      json.Writer
 	)
 	// Get current session data, if any.
@@ -32,10 +41,49 @@ func ListEventOrders(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID m
 		api.NotFoundError(tx, w)
 		return
 	}
-	list = tx.FetchEventOrders(event)
+	// If we were given a card, find the customer associated with it.  We
+	// don't hold the transaction open while we're talking to Stripe.
+	if pm := r.FormValue("card"); pm != "" {
+		var name, email string
+
+		api.Commit(tx)
+		card = stripe.GetPaymentMethodFingerprint(pm)
+		tx = db.Begin()
+		if card != "" {
+			name, email = tx.FetchCard(card)
+		}
+		if name == "" && email == "" {
+			api.Commit(tx)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("[]"))
+			return
+		}
+		list = filterEventOrders(tx.FetchEventOrders(event), func(eo db.EventOrder) bool {
+			if email != "" {
+				return strings.EqualFold(eo.Email, email)
+			}
+			return strings.EqualFold(eo.Name, name)
+		})
+	} else if search = strings.ToLower(strings.TrimSpace(r.FormValue("search"))); search != "" {
+		digits = phoneDigits(search)
+		list = filterEventOrders(tx.FetchEventOrders(event), func(eo db.EventOrder) bool {
+			if strings.Contains(strings.ToLower(eo.Email), search) {
+				return true
+			}
+			return len(digits) >= 4 && strings.Contains(phoneDigits(eo.Phone), digits)
+		})
+	} else {
+		list = filterEventOrders(tx.FetchEventOrders(event), func(eo db.EventOrder) bool {
+			return eo.Name != ""
+		})
+	}
 	api.Commit(tx)
 	for i := range list {
-		list[i].Name = lastNameFirst(list[i].Name)
+		if list[i].Name != "" {
+			list[i].Name = api.LastNameFirst(list[i].Name)
+		} else {
+			list[i].Name = list[i].Email
+		}
 	}
 	sort.Slice(list, func(i, j int) bool {
 		if list[i].Name < list[j].Name {
@@ -53,28 +101,34 @@ func ListEventOrders(tx db.Tx, w http.ResponseWriter, r *http.Request, eventID m
 			jw.Object(func() {
 				jw.Prop("id", int(eo.ID))
 				jw.Prop("name", eo.Name)
+				if card != "" || search != "" {
+					jw.Prop("email", eo.Email)
+					jw.Prop("phone", eo.Phone)
+				}
 			})
 		}
 	})
 	jw.Close()
 }
 
-func lastNameFirst(name string) string {
-	var comma, space int
-	var suffix string
-
-	name = strings.TrimSpace(name)
-	if comma = strings.LastIndexByte(name, ','); comma >= 0 {
-		name, suffix = strings.TrimSpace(name[:comma]), strings.TrimSpace(name[comma+1:])
-	}
-	if space = strings.LastIndexByte(name, ' '); space >= 0 {
-		if suffix != "" {
-			return name[space+1:] + ", " + name[:space] + ", " + suffix
+// filterEventOrders returns the subset of the list for which the match function
+// returns true.
+func filterEventOrders(list []db.EventOrder, match func(db.EventOrder) bool) (out []db.EventOrder) {
+	for _, eo := range list {
+		if match(eo) {
+			out = append(out, eo)
 		}
-		return name[space+1:] + ", " + name[:space]
-	}
-	if suffix != "" {
-		return name + ", " + suffix
 	}
-	return name
+	return out
+}
+
+// phoneDigits returns the digits of a phone number, with any other characters
+// removed.
+func phoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
 }
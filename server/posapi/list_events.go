@@ -71,6 +71,9 @@ func emitListedEvent(jw json.Writer, e *model.Event, freeEntries []string) {
 		jw.Prop("id", string(e.ID))
 		jw.Prop("name", e.Name)
 		jw.Prop("start", e.Start.Format(time.RFC3339))
+		if !e.Cancelled.IsZero() {
+			jw.Prop("cancelled", true)
+		}
 		if freeEntries != nil {
 			jw.Prop("freeEntries", func() {
 				jw.Array(func() {
@@ -49,8 +49,9 @@ func SendOrderReceipt(tx db.Tx, w http.ResponseWriter, r *http.Request, orderID
 		}
 		tx.SaveOrder(order)
 	}
+	api.EmitReceipt(tx, order)
 	api.Commit(tx)
 	log.Printf("- RESEND RECEIPT for order %d to %s", orderID, order.Email)
 	w.WriteHeader(http.StatusNoContent)
-	api.EmitReceipt(order, false)
+	api.SendQueuedEmail()
 }
@@ -249,14 +249,19 @@ func useTicketPost(
 		log.Printf("%s USE TICKETS order:%d event:%s class:%q used:%d want:%d allow:%d-%d",
 			session.Username, order.ID, event.ID, cname, used, wanted, min, max)
 	}
-	// Clean up and return success.
+	// Clean up and return success.  If the order has a wallet pass, update
+	// it to reflect the new usage.
 	tx.SaveOrder(order)
+	walletPass := order.ID != 0 && !tx.FetchWalletPassUpdated(order.ID).IsZero()
 	api.Commit(tx)
 	jw = json.NewWriter(w)
 	jw.Object(func() {
 		jw.Prop("id", int(order.ID))
 	})
 	jw.Close()
+	if walletPass {
+		api.UpdateWalletPasses(order)
+	}
 }
 
 // useTicketError sends an error for a UseTicket request with an invalid order.
@@ -10,6 +10,7 @@ import (
 	"github.com/stripe/stripe-go/charge"
 	"github.com/stripe/stripe-go/paymentintent"
 	"github.com/stripe/stripe-go/paymentmethod"
+	"github.com/stripe/stripe-go/refund"
 	"github.com/stripe/stripe-go/terminal/connectiontoken"
 
 	"scholacantorum.org/orders/config"
@@ -207,3 +208,40 @@ func GetCardFingerprint(chargeID string) string {
 		return ""
 	}
 }
+
+// GetPaymentMethodFingerprint returns the fingerprint of the card in the
+// specified payment method.  This is used with payment methods created by the
+// Stripe Terminal SDK's readReusableCard call, which reads a card without
+// charging it.  It returns an empty string for any error.
+func GetPaymentMethodFingerprint(methodID string) string {
+	var (
+		pm  *stripe.PaymentMethod
+		err error
+	)
+	stripe.LogLevel = 1 // log only errors
+	stripe.Key = config.Get("stripeSecretKey")
+	if pm, err = paymentmethod.Get(methodID, nil); err != nil {
+		log.Printf("ERROR retrieving Stripe payment method %s: %s", methodID, err)
+		return ""
+	}
+	if pm.Type != stripe.PaymentMethodTypeCard || pm.Card == nil {
+		return ""
+	}
+	return pm.Card.Fingerprint
+}
+
+// RefundCharge refunds the specified amount (in cents) of a Stripe charge.  It
+// returns the ID of the Stripe refund.
+func RefundCharge(chargeID string, amount int) (refundID string, err error) {
+	var rf *stripe.Refund
+
+	stripe.LogLevel = 1 // log only errors
+	stripe.Key = config.Get("stripeSecretKey")
+	if rf, err = refund.New(&stripe.RefundParams{
+		Charge: stripe.String(chargeID),
+		Amount: stripe.Int64(int64(amount)),
+	}); err != nil {
+		return "", err
+	}
+	return rf.ID, nil
+}
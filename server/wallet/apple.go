@@ -0,0 +1,292 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+	"go.mozilla.org/pkcs7"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/model"
+)
+
+// AppleEnabled returns whether Apple Wallet passes are configured.
+func AppleEnabled() bool {
+	return config.Get("applePassTypeID") != "" && config.Get("applePassCert") != "" && config.Get("applePassKey") != ""
+}
+
+// ApplePassTypeID returns the pass type identifier for our Apple Wallet passes.
+func ApplePassTypeID() string { return config.Get("applePassTypeID") }
+
+// AppleAuthToken returns the authentication token that Apple Wallet devices
+// present when asking for updates to the pass for an order.  It is derived
+// from the order token, since the pass barcode gives away the order token
+// anyway.
+func AppleAuthToken(order *model.Order) string { return "order-" + order.Token }
+
+// ApplePass returns a signed Apple Wallet pass (a .pkpass file) for the order.
+func ApplePass(order *model.Order) (pkpass []byte, err error) {
+	var (
+		files    = map[string][]byte{}
+		names    []string
+		manifest bytes.Buffer
+		sig      []byte
+		buf      bytes.Buffer
+		zw       *zip.Writer
+	)
+	files["pass.json"] = applePassJSON(getPassContent(order))
+	for name, data := range map[string][]byte{
+		"icon.png": passIcon, "icon@2x.png": passIcon2x, "logo.png": passLogo, "logo@2x.png": passLogo2x,
+	} {
+		if files[name], err = base64.StdEncoding.DecodeString(string(bytes.ReplaceAll(data, []byte("\n"), nil))); err != nil {
+			return nil, err
+		}
+	}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	jw := json.NewWriter(&manifest)
+	jw.Object(func() {
+		for _, name := range names {
+			sum := sha1.Sum(files[name])
+			jw.Prop(name, hex.EncodeToString(sum[:]))
+		}
+	})
+	jw.Close()
+	if sig, err = signManifest(manifest.Bytes()); err != nil {
+		return nil, err
+	}
+	zw = zip.NewWriter(&buf)
+	for _, name := range names {
+		if err = addZipFile(zw, name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err = addZipFile(zw, "manifest.json", manifest.Bytes()); err != nil {
+		return nil, err
+	}
+	if err = addZipFile(zw, "signature", sig); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// applePassJSON returns the pass.json file for an Apple Wallet pass.
+func applePassJSON(pc *passContent) []byte {
+	var (
+		buf bytes.Buffer
+		jw  = json.NewWriter(&buf)
+	)
+	field := func(key, label, value string) {
+		jw.Object(func() {
+			jw.Prop("key", key)
+			jw.Prop("label", label)
+			jw.Prop("value", value)
+		})
+	}
+	jw.Object(func() {
+		jw.Prop("formatVersion", 1)
+		jw.Prop("passTypeIdentifier", ApplePassTypeID())
+		jw.Prop("teamIdentifier", config.Get("appleTeamID"))
+		jw.Prop("serialNumber", pc.order.Token)
+		jw.Prop("webServiceURL", config.Get("ordersURL")+"/ticket/wallet")
+		jw.Prop("authenticationToken", AppleAuthToken(pc.order))
+		jw.Prop("organizationName", "Schola Cantorum")
+		jw.Prop("description", fmt.Sprintf("Schola Cantorum Order #%d", pc.order.ID))
+		jw.Prop("foregroundColor", "rgb(255, 255, 255)")
+		jw.Prop("backgroundColor", "rgb(1, 83, 165)")
+		jw.Prop("labelColor", "rgb(191, 212, 233)")
+		jw.Prop("barcode", func() { appleBarcode(jw, pc) })
+		jw.Prop("barcodes", func() {
+			jw.Array(func() { appleBarcode(jw, pc) })
+		})
+		if pc.event != nil {
+			jw.Prop("relevantDate", pc.event.Start.Format(time.RFC3339))
+		}
+		if pc.voided {
+			jw.Prop("voided", true)
+		}
+		jw.Prop("eventTicket", func() {
+			jw.Object(func() {
+				jw.Prop("headerFields", func() {
+					jw.Array(func() { field("order", "ORDER", fmt.Sprintf("#%d", pc.order.ID)) })
+				})
+				jw.Prop("primaryFields", func() {
+					jw.Array(func() {
+						if pc.event != nil {
+							field("event", "EVENT", pc.event.Name)
+						} else {
+							field("event", "EVENT", "No upcoming events")
+						}
+					})
+				})
+				jw.Prop("secondaryFields", func() {
+					jw.Array(func() {
+						if pc.event == nil {
+							return
+						}
+						jw.Object(func() {
+							jw.Prop("key", "date")
+							jw.Prop("label", "DATE")
+							jw.Prop("value", pc.event.Start.Format(time.RFC3339))
+							jw.Prop("dateStyle", "PKDateStyleMedium")
+							jw.Prop("timeStyle", "PKDateStyleShort")
+							jw.Prop("changeMessage", "Your event is now at %@.")
+						})
+						if pc.event.Venue != "" {
+							field("venue", "VENUE", pc.event.Venue)
+						}
+					})
+				})
+				jw.Prop("auxiliaryFields", func() {
+					jw.Array(func() {
+						if pc.tickets == "" {
+							return
+						}
+						jw.Object(func() {
+							jw.Prop("key", "tickets")
+							jw.Prop("label", "TICKETS")
+							jw.Prop("value", pc.tickets)
+							jw.Prop("changeMessage", "Your tickets: %@")
+						})
+					})
+				})
+				jw.Prop("backFields", func() {
+					jw.Array(func() {
+						if pc.order.Name != "" {
+							field("name", "Name", pc.order.Name)
+						}
+						if pc.details != "" {
+							field("details", "Unused Tickets", pc.details)
+						}
+						field("status", "Ticket Status", pc.barcode)
+						field("contact", "Questions?", "info@scholacantorum.org\n(650) 254-1700")
+					})
+				})
+			})
+		})
+	})
+	jw.Close()
+	return buf.Bytes()
+}
+
+// appleBarcode emits the barcode dictionary for an Apple Wallet pass.
+func appleBarcode(jw json.Writer, pc *passContent) {
+	jw.Object(func() {
+		jw.Prop("format", "PKBarcodeFormatQR")
+		jw.Prop("message", pc.barcode)
+		jw.Prop("messageEncoding", "iso-8859-1")
+		jw.Prop("altText", fmt.Sprintf("Order #%d", pc.order.ID))
+	})
+}
+
+// signManifest returns the detached PKCS#7 signature of the pass manifest,
+// signed with our pass type certificate.  The Apple WWDR intermediate
+// certificate is included in the signature if configured; it is not configured
+// when testing with a self-signed certificate.
+func signManifest(manifest []byte) (sig []byte, err error) {
+	var (
+		cert *x509.Certificate
+		wwdr *x509.Certificate
+		key  interface{}
+		sd   *pkcs7.SignedData
+	)
+	if !AppleEnabled() {
+		return nil, errors.New("Apple Wallet passes are not configured")
+	}
+	if cert, err = parseCertificate(config.Get("applePassCert")); err != nil {
+		return nil, fmt.Errorf("applePassCert: %s", err)
+	}
+	if key, err = parsePrivateKey(config.Get("applePassKey")); err != nil {
+		return nil, fmt.Errorf("applePassKey: %s", err)
+	}
+	if sd, err = pkcs7.NewSignedData(manifest); err != nil {
+		return nil, err
+	}
+	if wwdrPEM := config.Get("appleWWDRCert"); wwdrPEM != "" {
+		if wwdr, err = parseCertificate(wwdrPEM); err != nil {
+			return nil, fmt.Errorf("appleWWDRCert: %s", err)
+		}
+		err = sd.AddSignerChain(cert, key, []*x509.Certificate{wwdr}, pkcs7.SignerInfoConfig{})
+	} else {
+		err = sd.AddSigner(cert, key, pkcs7.SignerInfoConfig{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	sd.Detach()
+	return sd.Finish()
+}
+
+// addZipFile adds a file to a zip archive.
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// PushAppleUpdate notifies the devices with the specified push tokens that the
+// pass for an order has changed, using the Apple Push Notification service.
+// The devices will then fetch the new pass from our web service.  Errors for
+// individual devices are collected and returned together.
+func PushAppleUpdate(pushTokens []string) error {
+	var (
+		cert   tls.Certificate
+		client *http.Client
+		errs   []string
+		err    error
+	)
+	if len(pushTokens) == 0 {
+		return nil
+	}
+	if cert, err = tls.X509KeyPair([]byte(config.Get("applePassCert")), []byte(config.Get("applePassKey"))); err != nil {
+		return err
+	}
+	client = &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{Certificates: []tls.Certificate{cert}},
+			ForceAttemptHTTP2: true,
+		},
+	}
+	for _, token := range pushTokens {
+		var (
+			req  *http.Request
+			resp *http.Response
+		)
+		req, _ = http.NewRequest(http.MethodPost, "https://api.push.apple.com/3/device/"+token, strings.NewReader("{}"))
+		req.Header.Set("apns-topic", ApplePassTypeID())
+		req.Header.Set("apns-push-type", "background")
+		if resp, err = client.Do(req); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			errs = append(errs, fmt.Sprintf("push to %s: %s", token, resp.Status))
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
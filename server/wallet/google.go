@@ -0,0 +1,232 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rsa"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rothskeller/json"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jws"
+	"golang.org/x/oauth2/jwt"
+
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/model"
+)
+
+// googleAPI is the base URL of the Google Wallet REST API.
+const googleAPI = "https://walletobjects.googleapis.com/walletobjects/v1"
+
+// GoogleEnabled returns whether Google Wallet passes are configured.
+func GoogleEnabled() bool {
+	return config.Get("googleWalletIssuerID") != "" && config.Get("googleWalletEmail") != "" &&
+		config.Get("googleWalletPrivateKey") != ""
+}
+
+// GoogleSaveURL returns the "Save to Google Wallet" link for the order.  The
+// link carries the entire pass, in a JWT signed with our service account key.
+func GoogleSaveURL(order *model.Order) (url string, err error) {
+	var (
+		pc      = getPassContent(order)
+		payload bytes.Buffer
+		jw      = json.NewWriter(&payload)
+		key     *rsa.PrivateKey
+		claims  *jws.ClaimSet
+		token   string
+	)
+	if !GoogleEnabled() {
+		return "", errors.New("Google Wallet passes are not configured")
+	}
+	if key, err = parseRSAPrivateKey(config.Get("googleWalletPrivateKey")); err != nil {
+		return "", fmt.Errorf("googleWalletPrivateKey: %s", err)
+	}
+	jw.Object(func() {
+		jw.Prop("eventTicketClasses", func() {
+			jw.Array(func() { googleClass(jw, pc) })
+		})
+		jw.Prop("eventTicketObjects", func() {
+			jw.Array(func() { googleObject(jw, pc) })
+		})
+	})
+	jw.Close()
+	claims = &jws.ClaimSet{
+		Iss: config.Get("googleWalletEmail"),
+		Aud: "google",
+		Typ: "savetowallet",
+		PrivateClaims: map[string]interface{}{
+			"origins": []string{config.Get("ordersURL")},
+			"payload": stdjson.RawMessage(payload.Bytes()),
+		},
+	}
+	if token, err = jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT"}, claims, key); err != nil {
+		return "", err
+	}
+	return "https://pay.google.com/gp/v/save/" + token, nil
+}
+
+// UpdateGooglePass updates the Google Wallet pass for the order, if it has been
+// saved to anyone's wallet.  Google pushes the change to the wallets that hold
+// it.
+func UpdateGooglePass(order *model.Order) (err error) {
+	var (
+		pc     = getPassContent(order)
+		conf   *jwt.Config
+		client *http.Client
+	)
+	if !GoogleEnabled() {
+		return nil
+	}
+	conf = &jwt.Config{
+		Email:      config.Get("googleWalletEmail"),
+		PrivateKey: []byte(config.Get("googleWalletPrivateKey")),
+		Scopes:     []string{"https://www.googleapis.com/auth/wallet_object.issuer"},
+		TokenURL:   google.JWTTokenURL,
+	}
+	client = conf.Client(oauth2.NoContext)
+	client.Timeout = 30 * time.Second
+	if err = googlePut(client, "eventTicketClass", googleID(pc), func(jw json.Writer) { googleClass(jw, pc) }); err != nil {
+		return err
+	}
+	return googlePut(client, "eventTicketObject", googleID(pc), func(jw json.Writer) { googleObject(jw, pc) })
+}
+
+// googlePut replaces a class or object through the Google Wallet REST API.  It
+// is not an error for the class or object not to exist; that means the pass
+// has not been saved to any wallet yet.
+func googlePut(client *http.Client, kind, id string, emit func(json.Writer)) (err error) {
+	var (
+		body bytes.Buffer
+		jw   = json.NewWriter(&body)
+		req  *http.Request
+		resp *http.Response
+	)
+	emit(jw)
+	jw.Close()
+	req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("%s/%s/%s", googleAPI, kind, id), &body)
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err = client.Do(req); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("PUT %s %s: %s: %s", kind, id, resp.Status, msg)
+	}
+}
+
+// googleID returns the ID of the Google Wallet class and object for the
+// order's pass.  Each pass has its own class, because the class carries the
+// event name and date, and those change for flex passes as their tickets are
+// used.  Classes and objects are in separate namespaces, so they can share the
+// ID.
+func googleID(pc *passContent) string {
+	return fmt.Sprintf("%s.order-%d", config.Get("googleWalletIssuerID"), pc.order.ID)
+}
+
+// googleClass emits the Google Wallet event ticket class for the order's pass.
+func googleClass(jw json.Writer, pc *passContent) {
+	jw.Object(func() {
+		jw.Prop("id", googleID(pc))
+		jw.Prop("issuerName", "Schola Cantorum")
+		jw.Prop("reviewStatus", "UNDER_REVIEW")
+		jw.Prop("hexBackgroundColor", "#0153a5")
+		if pc.event == nil {
+			jw.Prop("eventName", localized(jw, "Schola Cantorum"))
+			return
+		}
+		jw.Prop("eventName", localized(jw, pc.event.Name))
+		jw.Prop("dateTime", func() {
+			jw.Object(func() {
+				jw.Prop("start", pc.event.Start.Format(time.RFC3339))
+			})
+		})
+		if pc.event.Venue != "" {
+			jw.Prop("venue", func() {
+				jw.Object(func() {
+					jw.Prop("name", localized(jw, pc.event.Venue))
+					jw.Prop("address", localized(jw, pc.event.Venue))
+				})
+			})
+		}
+	})
+}
+
+// googleObject emits the Google Wallet event ticket object for the order's
+// pass.
+func googleObject(jw json.Writer, pc *passContent) {
+	jw.Object(func() {
+		jw.Prop("id", googleID(pc))
+		jw.Prop("classId", googleID(pc))
+		if pc.voided {
+			jw.Prop("state", "COMPLETED")
+		} else {
+			jw.Prop("state", "ACTIVE")
+		}
+		jw.Prop("barcode", func() {
+			jw.Object(func() {
+				jw.Prop("type", "QR_CODE")
+				jw.Prop("value", pc.barcode)
+				jw.Prop("alternateText", fmt.Sprintf("Order #%d", pc.order.ID))
+			})
+		})
+		if pc.order.Name != "" {
+			jw.Prop("ticketHolderName", pc.order.Name)
+		}
+		jw.Prop("reservationInfo", func() {
+			jw.Object(func() {
+				jw.Prop("confirmationCode", fmt.Sprintf("%d", pc.order.ID))
+			})
+		})
+		if pc.tickets != "" {
+			jw.Prop("ticketType", localized(jw, pc.tickets))
+		}
+		jw.Prop("textModulesData", func() {
+			jw.Array(func() {
+				if pc.details != "" {
+					jw.Object(func() {
+						jw.Prop("id", "details")
+						jw.Prop("header", "Unused Tickets")
+						jw.Prop("body", strings.Replace(pc.details, "\n", "; ", -1))
+					})
+				}
+			})
+		})
+		jw.Prop("linksModuleData", func() {
+			jw.Object(func() {
+				jw.Prop("uris", func() {
+					jw.Array(func() {
+						jw.Object(func() {
+							jw.Prop("id", "status")
+							jw.Prop("uri", pc.barcode)
+							jw.Prop("description", "Ticket Status")
+						})
+					})
+				})
+			})
+		})
+	})
+}
+
+// localized returns a function that emits a Google Wallet localized string.
+func localized(jw json.Writer, s string) func() {
+	return func() {
+		jw.Object(func() {
+			jw.Prop("defaultValue", func() {
+				jw.Object(func() {
+					jw.Prop("language", "en-US")
+					jw.Prop("value", s)
+				})
+			})
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package wallet
+
+// This is the base64-encoded rendering of pass-icon.png.
+var passIcon = []byte(`iVBORw0KGgoAAAANSUhEUgAAAB0AAAAdCAIAAADZ8fBYAAAC2klEQVR4nOyVXUiTbRjHr/v5
+2vf2qFM30Ve3Cb7Oj1f2uvnxiiDyBloryrI6qZOOLIhIK6tBJJQ7keggJCICiwyVBJVIOilN
+DYsKP0HXlqiJc+p8WroP98Q2p0IHsrWgwD/3wX3Bn99z3X+ui4fgVjTBLxAWvPwhXCJ4iYBE
+HLw8J16ropOkvMhwBRRWo0+tKlOJ+BTjcCMMRSAHnULytr7kYkW6iE91v/tSXtfD5+A/22+l
+Tn7vTC5J+PprfGaqfjgcIyC9LIsT6kNBT2iicGQ4mGY8mUUQGMuydc2jV1vGWQCHy2tnXGH2
+K+Tgbed1xZlxAMAC1DWPGDtM2YnCZCmfR2AfPtvD4dI8ov1Cfl5ajK8AaOycQAhN3P4/Wkw1
+90z3jVkRjoXMjROSHZcKspVR/grejFnLcuUpMuHQp+U913tNC6vhzG+ChOqqLfz7L0lgft9P
+Lgq4ZIpMOL+8tt/YP8e4wtm3eBHVbSjahH40LT3tn8lU0ABQ3za+HRoCV0DhrdU6VYLIX8GI
+xa439h8vTgIAr5dtGZgJGiGEHBSxvMdntf/4M2UBnryaOvdgyOH0pCX5el91uhccHgDQpkiu
+HU3XKCRLDvfO3AIV3VqTHy3mAIBn3WtoGr7VbQ68dN3LYhjicUgBiTnc3vtVmtREMQDQYu4O
+OVTkyjqv/BeALjKuAzdeB6C+5wMMmZd8H8BQSYYUAKR+W+D49k0qIHRK2mLbGJHAUcsFDSey
+LlemUyQOAJMzjP5m36B5ZdMAAHO21SNFiQihnGRJ+8Ds6DSjU9IEjkgCR4H/xenS5H9VdO+4
+7es3j1zK26eRFWbEYggBgGWOufPcfPeFxbnOBoFbqtWnGo6pEULzy2uPXk5ZGefhvIQoIbXB
+BQCZmNqrkWmVdCzNBWBtdufILNM3Zhu0rMCPvG0qVcecKlXoVBIOiVkW1npGrQ1dpi1uZIWF
+Zt/l7nJ/K+73AQD7I+ye7Wi7TQAAAABJRU5ErkJggg==
+`)
+
+// This is the base64-encoded rendering of pass-icon@2x.png.
+var passIcon2x = []byte(`iVBORw0KGgoAAAANSUhEUgAAADoAAAA6CAIAAABu2d1/AAAGLUlEQVR4nOyXe0xT7R3Hn3N6
+5ZRSCi2lIBURMbZcFKQUmHjXOedChm5eMqITmS5GozhCxmJwi24Z6IY6xRmNRheJEtB5mWZz
+DuKUWBVEUuQVxRaQqy290J5eTs+bhvNQCiW+JlD7Jv3++sfvfJ8nPZ+cPM/v+T10dt4V8P0R
+CpMAbgA3gDuTuHSY+K/YKCKXcKIEQQw6zX9x5THBeZkxP06NiIkKYdBR//26uSmC4tx5yQkR
+NARaAPgjbnIUpzxflpMshoYrSBJpbh/AnUimVOBHuIfXxx3cJGUxadBwhXbYsq+qqanboKxY
+5S9fN5bPPFewMGeRx0cFALR8o9tc2dips21ViDE23S9wE8WcB4eXhPFY0KD0zyc9BVUvjA4S
+AGAy2fyi7sYL2LXFWZNZrzx8v/U0xQoA+G+bVme0fk1cNg0U/yiu8U/LY0QY9FxhtRGHLjQV
+XnhNAIrV9XUJ8peVSp3BinyVBlLCY9YcVCTN40ODkp1w7ixvvNEyCA0PibmMr7B2pWLsVskP
+ZgmDoEGJIMGRSy1TsQIAeo12X+OmSULu/DYzNIQNDUpOgFRce3X8oRoAEIQia2T8qMiQ2Xws
+QcBiMpDhEYfGZNcMmH2KK58Teqc0k4sxoeFWZY2q7E6nQhK8a3XcBkU0l0PNsdnId11ao8Wg
+t5IhXJbvcJfFh90oyQj2xnr21pvqxp7/lGRkJkWi8OBV9xnK6zrqlB+1OEFZPqu7ubKwi0WK
+IDYDGm7Vv+rVm4mGY8tYTArG7iBP1rYdvf3OQjjhLB/ibk4VVe1Ln3C6jv6GDXjz++GSLbIx
+B8ft208ob7V633AzXnd3ZESe2y/3zmqy7T/fvHPtXGgAggQHzr6cinXGcffkRFfuTWcyvLzF
+gtsL//KsOG/++NVc16C+pOyDT77FLVolqShMY9C8vMJscfz8j0+zZOGyWPdJgVsdpdUq+ORb
+3EOrJWXbF6IoMnnQanXklz9V9Zl2r58HPVdU12s0ehsYb/kGd4tcXLZ9Ed0bq93hLDz1/O4b
+bcGKOWzPMnpX2Q3TKTX9lSExKriyIGXyvWW0fdlz6vn1l/0AgA2pAmi7wmpz3lcNwyef4CIA
+FC6PObotkePtLGhUDRVfalF2G0dnxs/26G/0RouDdLdgAAA+G92cLVkqDU+K4fI4DJvd2fRB
+P224dAS5+KvkTTmx0HDLCZAzN9t+c70dGiCSRRs7FEZ/NM8dKcLo/zuSExvNhYYrxBHB04Mb
+wkSv/jpttTwKGm4RBHnsWsuxe53QcIXO7iRIMH7BBGMeHfqBdfETWKdtMQgxes1BhVwaDg23
+9CP4npMv6l4PQYMS7iQNBpzPc/dlLCaaKGS3DuKuBwB+ohDBkWmtDHPDWf/+XbZX1na1bmlp
+w2TW0ejo0sOUUnZiBEwBF2NPP+7aBeENf1g+PzYUGpScANTUq1eU/b99wAK9ibr8uAemlAqW
+zoIp6P1kgukUuAlCtoD9XekjOYy/5ctulGaFhU68FQIAztxs+8W5Zq2VmDgwLq4/6enu82BK
+TBDuyqZWf9FlldnigCNu0ejSn1KbMZjxj/3pje3aQZMdjnpRHJ9VvD7+73sXK6RCGjKxuo6Y
+7b+/2nrk9jtoTCmbk2zp0P1siYQ2bsetXCQe+Ghq6jFqdPg9Za+AhYZidJuNwG320ebTjTs4
+4sBI8kqRQshG27oNBqtHr4kAsDFVVLUjqSw/OVsmnNxh2Qii+pF661+f3Vd9gt5npNbhao1+
+Xbp4rIrRaMgPM6JlIuzjgPllj7H2RX/l/c43au2aFBGXw3KS5MSbcF6KqGJnchif1dVj0PSb
+R8wEjQZixZhEzONgXpprAMCgzlzT0HX+kaZtwAy9L9DuZbOOF6RO6i4Qgwk3ma0cDpsHL0Kn
+a9u8XNyDaGhummijXLx4gUDAx9Bx9/2xsOCOrn79v5qGHrf231XpvMz4klgxn//nbVLpXCGC
+eP+nvkHjidq3p+q7vOCOj3geMyk2JJTLYjLoCEKaLHbTiLWj1zxWIKcxsiTc7CTRygReZDjG
+YKAW3N41iD/5YGh5O/SgnWonPoPrb4F+2fQAbgA3gBvADeAGcAO4AdwAbgA3gDtzuN8OAOFz
+NQv8ycatAAAAAElFTkSuQmCC
+`)
+
+// This is the base64-encoded rendering of pass-logo.png.
+var passLogo = []byte(`iVBORw0KGgoAAAANSUhEUgAAAJ0AAAAyCAIAAADJDk66AAAYTElEQVR4nOx8d3wU1fr+mZ3t
+my3ZzSab3tumV5LQDUgJEKSEi3AFFRDlCkpHil4QLiAKiiCCCIjSFUFAqoSWhEAq6WWTbPqW
+ZLO97+8zyU6S3SwQvHB/yjfPu3/svOfMZJlnznve87xnwBKnHgUDeOmAQb8M8DrA6wCvf3le
+seiXvzocSPDoUIdAN0aSD9XZgYTDwsBkUqj1Aom6uFFTIZRdzW2plWjQ7gO8/uV5HR/KnJ/s
+MzLKmYC3EV24AIyIRb7p9Kbr9xs++KmIL9GijQO8/vV4tSdgJsU7vT/aN8SPhfrM0BpBX4px
+WGjcYPdAD8bQDbfaNAbUjVgQA4+BMbx2ndpo4X+cHZ8X5sGxS9l6X6I3or5nwLhg+4+nBx5I
+bzp4m4/6Bnjt5HV6lONnb0c4Mcmow4yiirZ/ny5ZnOI3NJKD+izg406dNdR99/VaAIATCftx
+WuC0wR5UOzwyoE2QWCT/7kr15ks1aHfbCPW19/O0J2Ie0/w0ONAJEUFOnCIp6vj/AMx/d/rz
+Nxoes29u6OHliVakGo2m3WdLEz65C0Gmx5HaZaOC7AEATAJ8bcPgN8f44XCYkipROU+kVes5
+bMq62eFpMY5o34E4/D+Jw+GudieWxHq70VGHGSq1fsmenKM5LQAAqh0BdduGUocEz7kjPf09
+GbWN0rGbM+rQfOrTyX46o+lUjgDtO8Dri+d1Trzz5+9EUUg41GGGRK6d8p/MzBqJORRXS4wm
+gIHQ5j7IrJUh2VYYEwCw9zq/m1QAwLpfq9CvA7y+eF4hAHbODJw/IagvWxKpasyme4WNCtQB
+CoWquwUtwx4TiqUKza9ZDQAAmQpJkXzsiWiLbSS4U8L8WAQs3CyWXS1sk1kmShEcSlIIC4ax
+RdWi9Grr+TKFy/B2tTeZTOV1kuuVEos2S8AASglleDjTMRiosVV2rkCsN5nQxpeUVyoWOvpe
+9JgEN9TRA1G7asr2rN6kdtmC/fnnVyUGeFqH6yaRYuYXD/gdyDrn92Lx2CSPt1N81Eb9l5d4
+LXId2ssMDgX33cLIEdEuMPowidpU/9qXd65IiBwAsHSsz3vTuXBnsxFABy+WLv6pHI3n/vPH
++9J6zQh5ZeI39j6sEqlRRw9GB7H2Loxwc6SiDsBrlKRuu2+z88uTN309K8wmqTKFduKqPx7U
+2cgq6ySauI9uX75TozP1DPBH5eKRa29n13YgBwD8cKtRIlXjYMwHk4NKvxr91RRvtKMZm6YG
+Jse4wBBcWCXKzKlTa3QOTNKc4T2/5J0pQRKp/npGfaNYhQGmt8YFBTGQOWJGjOOyNC7NjqDS
+G+4+4GfmNWkMpqgg1qEFUeipFojzpLs5UvPKWyevuJG8/Fpdq9zHlbFsXADa/jKO1w0TfaeN
+9kKPemA0mtYcKsiX61GHNbQm42vfFPodLw92pWAwUGu7KqtBiTYipjYa3voqZ/+iaAd7EpGI
+mz8tfFYqN6NAuOxoYYVQ7ckgTBvhYTCYlu27/+29RgBACKdywyTfJT+VohcAFXzZxG0ZLQod
+m4z9Y0OSn4d9Yqhj5d2mtWnBAID7xcLF3xcUNiOxZIg3/eSKQdEhjuO5rEslYvQCZuy8XitW
+636426gyIEF+yrasrO3JI8KYPT1eJl4hAPa8wX1zbAAA1jON0QQ2H3t0MKMJdSBGxWL8WQQ8
+DpIoDI1yrUyHnFUl0VT1Sous7PcSUciH11+Lc56d5BIX4kTGY0fFOt/0Ywxbf2tirCuRiDt3
+p66LVABAcYtixv5C9FTENv9c3KJAordQqd//B3/7XHsmgzwhguXvziiuEo3YnIF2BHdrOtb9
+VLJ3UcyScT59eVUZjN/eqgcARHNIjgwCBsK0SVV0Kv4l5BUPYb5dEPaP4V59STWZoM9PP9py
+qQYLQcN9aXGB7ClxbFcOnUEldGdVGgMklalFYsXlAuH+G7za9scKh1Kt8ci9xiP3Gt1puGmJ
+bqumBDIZpDnDPP3YSD51tURs+7ROK+T1TAFyDUIwBgJerjQAwM+5IrTFjLuFrYiw1WfKR+QR
+MrwpLXhyknuXPGLOBztU6NeXhddEb8aPH8a4ONihjt6Adp8t3nW5ds0En3de9Xbq1aexVXE6
+o+lhpaCsXlosRogkYqBIF7KjHf4JvHZbvVS380oNhYBb+4/gCDc7qRrJliEIsu7Xy5QaW7OA
+EXkQqTgYPTYDi0MyFYPR+jEFAJxZmhDLZV/PbjiZ1SBoUynU+iPLEih46yv8vXkdH2h/dGUi
+uc8itevz/e/l2VXS/B2vsHuJTW0d2o3HH+2/3WB1z9RGU1aDdarcG4M97O7x5egRYo405N/L
+b9eWtUinjfSaHOV0MP3ZJNyyWmQ981q84ydnyrSmnkXRhFhnAEBuZTvqMGNuuH0sl11ULZq4
+66H5jkMQg4LX6fqlVP898uFpEexjq5MeR+qP16oAhDm6IrE3qRmPhHErrn3bh9QnGxaC9s4J
+/X3zyA0T/RxIyMgIcyJ/PTtozmhfowlcyGn49X6zTKlLjnX9bEYgvXPoDPWhp69O8GY+Rcy6
+UtaeWyr0cqU/+mz4axFsZNB3KiqrZ3C1BsPOi5VoRzMwGOTiZDxMRGeRja/5UsgvdnL9n47X
+WbFOe9+Px+NsP0k/3uAZAeatsT7dM65Ob/z6TPGmCzUqW8HtyTCZAIdOxMGYNTNDlk8PUWu0
+FDIeAwGTCTpwsexKKTLmFu7KPvBh/L9SgxaMC1RrdVQKAYJMi0Z5Lz9Vhl7GNmZ/nfPb6iRf
+d9qxVUlylRYDIDIJp9UZ1+7Pv1crs+r9fb7o7RJhNNeh/Mvk2kapI4tiTyPw6ttZDBLa5YUA
+xnKnoN9fIOYPdt69KBbXZ1rq+py+za9plv9rcmC3BwCw42Tx+t94+mfmFPmYADh5v6m2oYOB
+hykkGAtDrSJ5xiPhxpNFX1wzB95SgfJ8ZqNRb2AQMVqtvqBStPFU6RdXkUJQAIPQIlSdyWnR
+on+dSYTpECa9RFjWopCoDUfT6yr47XKFgU4AUrn2XFbT4gN554vNyRQVB1Eg0+0SUVkLMk1c
+fNCMNxldmUQKCZtV0THvm4ewxiiRaX7Je4EyNfQ/2Le2ZIT7pnmROIztkXo5q+Gby9Wn1w/B
+wz2sl9e0xa67+6LFtpcYLzwOLxvl8cncSCwGstUIrmY3zfw6984nFqQCAP5ztvw5khrnSkmO
+dA51JjnQCHqd8SFffrOw+VaNzHbvAV6fyuvaFJ81r4fBkK02AG4XtMza/XB0CDPUz0J8EbWp
+Tj58PjGKQ8HteTN0bII7pteDlZwIVqZxb+TUL9hX0Kx8rKQ1wKttXr+YEbBwEheCbA+77GLR
+zC8fyg2mucOtdcQrua1Wnj8HFzvsjY+HerlS5Qrtd5d5OTxRm1RLJuKGhzi+PdZ3VKzbR5OU
+758otT7tWcyXiU+O4FQ0SNMrJf0952/N6z/iOO+mBveVk7qsWaD451cP2jrFAa4HBXWbUdT8
+pFVp/235eH8vV6rBCGZ9du9qRY94dKG4raCufcWk4A9OPiX1fapFeVK/nB917Fr1/wlew10p
+O98OR4+scSO78Z1vCxpV5sKZI8taeFKrbUi+QWzyzCFuo0KYjvZEpcbwoFJyJrP+ctlj76Y9
+ATPzFU8AwFdny3qTal5W3W+98UhseHnzsufPa6wX7cKaJDrV9gL/+8tVi38oMaDjmApDfWUK
+XZ8F66pXPdbOjsBhezLqAC/mP0b5HLhQ8eFx24F0eqIrg0ZoaJGt/bncogFF75l1sBctOoBJ
+JuEVKh2/SXKtRNK1aB7PZVJJ2JM5ghG+9KgAFoyFS2pEl4rakRqOp50rmxLjicjF7g6kGbFO
+AIBL+cKuyryLHTYlhkNjkIABEogVF3NburdITgpl4vHYM7mCscHMEF9WbmVbbo1kbLgDAOBO
+mbgJLWE5kbEjuKzWNlU6T+pKxQ0JZObzpeUC1Qg/emyQo96gzywS3K9HAltqhIOfB1On12UU
+CB42KZ4/ryQYs2Ck17oZgXYUG3qKwQS+OF2y4VcLRYYE21j82JEsTh/PZW2YE9U3oYYhsGBC
+wO95rVfL2lBfD1Ijkc1pR9LrrZ8RS3DscIcXRg6JcoEhoNMZcZ2yCa9ROmFLRk275vO5oV5u
+DnFnH707mdv9A364UvXOkeIVKX6vDkHiAaJVRbkMjXJBwvJ7l8okxiUj3dfPCqOQe55XsUTz
+/jcPzz5CFrhfLIhi00hDr1bNTwmCINOWM+XSNsXhpQlIZXfL7fNFZiUyzNXu8NKE9Af16Ttz
+B/naH16acCK9JtKDGuDt0PVLdAbjB3tyJsU7d1evta8bVn2bu+9u0/PklUmETy6JGxLhZOU3
+V2C0+rXf5++53WjhBUCgNah1RqKlCMWhWfC6bII/BnqMCgqB8THONnn1cEIEnermDgtvH3So
+DAYDOHKlateF6so29Ug/xldvR/h5Mj6dHjjLXLYzvDUuYPOxoqK69nBPxoq0kFmv+h2/U7/r
+ctWZ3JYoT+q7qdx7BS1HbtUhxQm5fnY8Z8u8aIVKu/HYo6LaDgIOxPs7vZPie3hZguDft+51
+Lq6wOPjNcQG/3au/XyfJrpKAJ/9EFNOH+yiVms3HHxXVSpIjOQvG++96L1anN6w5lF/dJE0M
+dFgylbv+jbDDGc1qo+n58OpCxf6yLCkiwN7Kb454YuXrn2dl9dHYzNQKZR4uFuWtkUEW1/F1
+79lB0hchzrYFORiHDBet7snDFagMxpSdD9AjcLNKMmz9naoD46L8ezajbz1Vuv0yDwBwvqjN
+iUmcN84/1Jf19bUawJOrNLp3AagTKI5mtZi3YfwzFACQti2zO5M6kyfmC2WfzY/eOjts6Cak
+aouBwPYTxf++WN3ZDmLYT9mEZS7tydWjV/9R2lm8Ol/UlhLNceVQt/9ctusa8kj9Vtz+SqhD
+RJDjEB+761Wy58BrmIvdL8sT3DjWaW2X3S9q/efXOfVSHbDZDMDlfOECS165fmx3Gq77lCdn
+N3yx3tplXgQrvV1oLCbZuqEP6Hh4eqLLaK6DD4dM7KwB4GAY6rXoPp/dgH4FRZ0VJALBOifo
+shE+NA6LUlUvsUqP99ys//j1sEh/JrUzRTCawP70OrSxv/aoVFjaqyLZJFK6cqiFvJ4KUlmD
+LCLIkUEjASCzMb09ky1+xS390+E2SdUaDDtOlyRvuf8EUgEAJ27zDQYL5nBY6INxPduRLty3
+jt69cavctoLxay6y/Wx67JN2kAMAfFmEh9tG7H4nOj6I1SFTF/E6MsslBn3vZwUqb+u5m6Ze
+tbm+xqQifHdIrfN5EwBypRqLhd1oSAe93tissP04dqNv4tEi7fkZyJ7qzkRMoeq5jlKD3Ea4
+c9KyOHv/6/7O5P6O4DHBjKyPh26bF0Mm2jilSaCY9O87689Wdqe+j7NMvuzIVettvfMmBKRF
+mSlZfaL8+I1aK+7NpVmJ6mx2i6XPbCfu8ZUqXWIEZ1Kog3Vb5yeycwJeMynAzcnus1NF3kuu
+j9qRM/NAwYLDhZblcYyNP2xp3UOb16IwmSBXR+uVmwMRtqdTlBpDRdcjYhmBum8R8pIgCiaj
+X8H5cWbB68Wijjv/GTk1io06bIBDwS1M9sj7dMjZdSMiAm1svtJoDft+K49bdfNW1VNylm5b
+fbwsu1hksVEGhr/7MO7UwqhwF4rKYHzrYEHC0quLvslZ+l1eUaV5gBoMpqUHCqx2/HZbk0y/
+9UQxDIFDH8bPirHO5lYke97eMfrL14MiOhcqv2T0hITPp/pTyE+pwnab3oBw6sY0z/H5reri
+qjYOm7JmjIWItjEtkIDHXM1usPmUV0u0us53FIYEm+88BMCc4R5o+5+BxVA7Vyjw+qXs4JJB
+a/jtB2/X8+plIolGbzDZ07BubLITyy41kh0RyEZrqNY/sbZR+sPdxl8y6suFz7Z5R6Y3Ttie
+cWhhZEpizz8Gh8VMHOYxNsntVp7gaolArtRQKPiZ8U6h/sgCRqc3bjiYdzLvSYrjZ9fqnOzJ
+76UGHliauKpOfLe4jSfT0vHYyTFsP097ACAMBsquloQGOGx6I/yn9DoTAGlJbmPi3ZQqLQxM
+j71uL2tpUwIABoVzts4IxsPQoWu8hQfzr348ZN0bEckRTpdLxTgcdmokK8TPQdCm+ehECXqe
+BWQ60+GzJfOmhy1I8WfRSYVNsrRoR193uo2uf45XAMCXt+pvVLSvSw34dGY4mWRRY3kcBGLl
+xZzmcxn1Vyr6O0D7QqYzTdudt7xMvHJGCLXXdgIcFjMqjjMqzmKabGiRzd+bk96PeLD8VOnd
+csGWWWH+Xkx/r57o0tAi23iq7GhWk4sdNsKTPiraeVQ0spGlsFI07dNb/5kdxqD3a8hmNSjP
+3atLHey5JDUAAOj07dpMvnT0J3f2vhWWGMEZ3PlSgsFgupnb/P6hgprH78Na/Gu1DAL/msyd
+Psx9OgANAtnWE0Ub37S9Ifm/qr+6kDA7ZnNHDvJmkDE2mgHQG0zltcKffq/emWE7c/lzGOxO
+PrZymCPrsbc1v6R52ud53TJkP231MM4rMc4MGlGh1d/Mbtp4oyfwAgDeT3CM9Wdll4v2ZAtB
+74b+YVE8OyGYnVsh2pnZcytmBtHGxDor1MafspoynrgPq9tiOcRFyR7NYtX6Kw3/pcb5lLo6
+EQMleFFd2WQSAYZhLAwDpUavVmlFEvUDvrJD+0I2XzGJcEqkY5K/Q4gbiUYh4HAYlVpXL1Rn
+1EoLK0VXyiU2zhmAJZ7C64D9TQ3zpMYBXgd4fYl5peKgIHtrgWkClz4+xLZu+nzh70gcx2UA
+AF5PdHQmwAO8Ph9e1090v7Qm+qv53D8+ivFlEtLiHT+fimhhK6f6RfvQIznES8si0L7PjMXJ
+rmtTzEUhpFbBwN38KMqTbvEMDecyI33oTiTskvGezZb/H8ozrHMGrLcN9aSMjXZM3Z4vUhkS
+PSjVbRpDpeRRnTzCAe/AIFQJlFVCza7zPKuz+g+BXDfYv6eqsXqi96WHgroOi1R/kDvlerkk
+3IMsFKsH4vDzicOI/o/FMjorqZl8ZK0yI5JFgIGXK6IUNgqUg3xoVBIi+I3wpx2eG3B8fvCr
+QfYwgOYMdj61MHjfbP+UMHuo8/2DLaneHyS7nloYPCumR9dsEanYqFY1zJcW6Gr3Y3rzwmEu
+p94N3j3T16vzdVtvZ3KjQMlxoJR2LpacKNj1E71OvRu0YowbFoJWjnCJ90CeDA86fkuqFzTA
+a394TefJf73XeOyDsD2z/DgU5C6nDHHRaQ0cFkWrM+Y3KMaEO5Co2BH+9A3T/H643fTtzQZH
+O+y6ie5DAmjbztUW1kq3zQmZEc+OdCaNT3ISS7THbjUum+aHXh4UN6poFAIEAB2GPnrNe+tZ
+nlBnUuhNn57hieT6ecMR5hh0Un69MsKZXNCkwEOYfW9zRe3qdcd5oe7UyVFMrB0uwgeRQheP
+82yU6kwDvPaHVwDA1ssN47fk4vHYDame9gQMAQ8XizSDPEhlNRKZzhTsTmwSyheMdN5zjf8H
+T55eJTv+UDQhwWX9KV5Os2rv3VaZQufLtvPgkIur2o/mCLNqlfpegrZQrdfo9Ekc0uFF3GqB
+6lq5xJ2BD+aQ1qf5DQtltaoMMS7EDrlGbjAFuZGbRaqPxzl7OJFifOgrJ3mz6XiqHb60UZ7o
+RYl1JYe7kw/fagQDvPaH12gnJMaKVIYH1RKWHS7KnSISI4KwB4eSVYAUKthMSiFfaUfGajol
+GiwEUbAQjAEaPXI4zJdGt4PLmmWuDpQ7PEQzD3WnCMQW4rlQrFo+3ZttT9p2DnmFZO+bgaV1
+HWl7iutaFQ0ihTuHwu98GYTNIhbwFfYMYoNAvvV8zdbzNTuOV57NbC2tlXo6U5ZP8tt2rkZh
+HMib+pE3OZHhnQvCK/gSqUI3OJy18kiFmyO5okFJwQAalXTogTjInqDTG0Rqw+l7LStTfWM9
+hfEBjE0nq27kCQ4v5BbWKYaFMvZfrDmXJz4yj3XqAaJQOjmQSi01xbIG5ZBw1twvC7v+uxON
+HoT5M3YFOIT60A9c5SdxWff5ai4Lr9UZRWrDjgt137wbvnSip1ZrdGUS0r4pkbQbsVhYrlTc
+qJAO6E391ZvsCZihgXQsFvOwSsqX6jzoSEGiTa4LcyVl8pVMAuzDwj9sQsZfBJvg5UIuq1d0
+FeGH+djRKLgH1dJWJTJw41xJ1SJtm8bgycCZTFAXhea1DR1PwkLlYnMpnkmAB/nZ8VrUNCKm
+tEXtaY9v1xjUOpMP0/yHqDhoiB9dZzDdqezQmIADEf55eeSbex/xehX/AQD/bwDilu7f6HUN
+fAAAAABJRU5ErkJggg==
+`)
+
+// This is the base64-encoded rendering of pass-logo@2x.png.
+var passLogo2x = []byte(`iVBORw0KGgoAAAANSUhEUgAAAToAAABkCAIAAACZ02s4AAAz20lEQVR4nOxdd3xTVfs/GTer
+GW3T0E33phTooLS0ZZehgOwfU0FZyp4CiiIgKogKivAiKOgrMhRFXqZsOiilpaWlLd17N22S
+Zjb5fW6TezOaTYGC9/vwx73n3oTkNt/zPOdZh0iZfBxgwIDhZQAeOcDoitEVoytGV4yuGF0x
+umJ0xeiK0RWjK0ZXjK4YXTG6YnTF6IrR9enoSkQOMDwT+FChxGBGgDvTiU1h2ZDwBELnMJB3
+dAhE8pY2YUOrrKKedymXBwAoEkqR12F0xeiK0fW50HWyL+O1gS5BXrYerkwWk4XDmSbhbgUE
+AKhv4uVVNd+5V/PFzWqxXI5cxIBBBRwWd+2uuOtADvm9sT6DwpxcndgASJBha9DS1nEtpWTv
+70/S257qfTC6YnTF6KpL16Xh9tPH+vYJdKXhulMl8gXSr355uONGFTKAGcOYMYwZw09hDC+L
+4iyZFOjV277zrJvNV7oNtGF+JA6H2369Ehl7MZgf5gAAGOBrCzqx5a9yrvR5q/1lUZxQTzoA
+QKaAlp4qQIYxumJ0NUXXke60TXODAADRIW7ImAnwpAoCkWCp+oWIijVz+t8v5l4s4yNj+hHj
+RAtlk6VEQjVXlFIt6V46vT7IBQAwOt5LSafDl0ozn7tT7PVYt7jI3gAABUZXjK5m0tUWIh2c
+6z1sSACdoDVuCAoFlPW44j9/F5c18g+tjaX1skGumAsKBb92SsDFPenIgBY+HeMxPMrF34tD
+JuGQMdCuwDfXcgEAeSWtP10qPP2kDbmC4eUGRlcL6LpkgN26ueHOZlOusqZt95HMgzktZDw+
+bXuC+S/UwYBgZzqByO+QIQOwzA2w/2BhmJszExlQg4aT0zrH3ZyZCVG9p97Mm/5DHnIRoytG
+138BXY/OCpicGAQRFciACVy8VTLnP7lKjn0wwtnPk2nwVlOgUgmT/Vg/5TUhA2B9LGfTO4M0
+NaohQETF+OEBl5nUUXszkDGMrhhdX126xjjRvlnSP8TPAQCzuCqWKPb/N33LZbVHN9SXgxxa
+CScHtfE92oO+cf5ATa6WVXFzC5sbW/h4PN6tl01wgCvHjoRchKWqvh05xOiK0fXVpeu2Ec4L
+p4SymFRkwAQaWiRbvkk9lt+MDMBCZkDIoZWiUKhnivXTgqhUFXtFIvme4xldXMdZh6bBbqEZ
+r4VJCbifT2WsOFuKXMLoitH1VaQrGY8/ubjvqMF+5uc8FJbx5u++ndak6zYV857WkXqnSKB0
+pHpTiKF9XVBH6r7/3t9+vQY5U2PhyRIAQBNPLpQotv1TjgxjdMXo+irSNZINfb9iYLCvBflJ
+2fn1o3amc6V6mJlb0jJqsAdyZjGEwo6kWpU1OzXCHnVKt7R1fHhVD1dRef9CGXKIVeRgFTmv
+aEXOgn6cP3ckdnLVXLmdXTtse5qhaOfpuzVSmWm3kCGUVHGRQ+BoR0YOQUOTiWAsVkCHFdC9
+4gV0X0/03Ls61o5J0B42JjdTSkZ9mqoTaNGU9DZJUo71uYSnrxYih6ChVa29WQw1dTH8G4AZ
+w2pjmIzH//len4RoLzM9wChXR3+ThZ4akj3/zYkOcjYn9KKD1NyqT2/VImegqE6EHIJebMai
+ELuDOS3IgMUY6U6bHuvs7+9gR7WxoeL57R2N/Nbs3Oafb9SmcUW6d2uLvw155ViPiGC2HZMC
+QSSeQFpU3nTsctHvRSZ0/vpY2E8+LNzZxYnNsIE6OmQCoby4ovlccvWRh43IXdaATiCuH8qJ
+Cua4OzOpVAoeTxCJRdX17Q/z6z6+UNu9mV5Yiv+LTPH3tyH/uiHSIgMYAPAguzL203Rjd2jI
+h8N7r503wPzILezhfVyXuOuB5u+MjMeXHB5tR1G5mpvaxZ8dSt93rwG5bi7iXBhb/8+//4De
+evMixRLFteSSjT8XFAjEAIA/FvfVTEIctPD8a5FOq2f3Qx3UqIhx0OV/cqYdfowMaGFTgvP8
+CUGuTgxkQBd5xS37TuXqJe3lVf3RJETarNPIsApkPP77//MbnuDBoev34Te1i4+ceGh8qd/z
+QSAGT0KO/72IZEO/vT/Q38sCruYVtzS2iEZ9+kAk70DGTOBmSSuuuTXYh2NDJRi7D8kx/uNi
+/mv7H+q8f4dCEeNI9vO0VyUwQcT4Qd6JgVQiT5aBuKNMYn6Yw771UYGebAinf+4gEnB+nnav
+R/bKfNRUzpPMiHAEAPh62Kk+Wwt/3VvREKTntUQgD/Bih7LA6Qx1UodSjs8LXjwtnMkwZtA5
+2FFHRXs4KUQX89TLdaXMGeTs4cqCjwBhx++5yDCIcaK506FzmwbHR7rakAwGzGgQMba/a68O
+cdd3xozhl8kYnhfI3rF8INsWMnGfBmrqBW/tSgIAcKUyE7dqY+fNmm/uNGwb6zw82sfN006v
+Zmtp60jJKNt7rvR2NU/nksoTdrQgxY/t4aqqjyErpNEhbtEhbttb2x8+rjufUmNc2Y7xZOxe
+Hq2hGEmVNY1F9XwgFDNsIB8PNhpk5rAZ/dwoXT/GO5NDcTipQgEVljWVVbcCAJw5VC/fXujX
+GTskaOL1irMl6unj90WhYxK8AVAvvFtE0pIndY2tHXg83pFN9vTmMCB4pQARFQunRcrl8lV/
+mRV/quVKNOPSTe3iu8ml11LqbpQJq4Sy2X3pk0YHxIU6KePSb74Rdvpek6EHixnDPd0Yfj/e
+acP8KIuWlE3t4rc/uWOyRMak+NuQE0OYvi429iyaTAar0OZWQVJe65lC0z+mOBfGf1YNQBmr
+I03t4vQH8HL3s98L0AgQKo8/i/d0V+nJlraOT4+katKbTiAemO0/Ybh/e3v7pgMPlHapjjGs
+fOGWAymaVuv0QPuvV0agVL94q+SN71VL+m0jnNfNj0Kt5YtXsgEAi4+XaBr5A+1tdrwVGBuu
+qnCSynDLdt7WzLs0bgwH0ClnP4iqqhe8ezAnn6+76v75zZDJo3yVq+4zlwtn/5iDXME8wy+5
+ZxgTTHqs/Ku16/7Jvv83KVSvRWpIxBLF6r1Jen0hz1lsIdKR+QHDY7wNr9cAvwP8cS5LmeGk
+qiwf6rb5nXA0+2LWJzcvlOpR5hsG9yqqE6GVdzratavqQ1/10dJB8BEApRUtQRtuwUX2BGLG
+NyPckHDxoZMPjWRE/rG4L/q/5DxpjNh610ztqvyPDMXSYP/cd2OVwbns/Pqoj5ORK5h2fUm0
+68m3gxZM7m8RV9sV+C9+TO8JXO1cNksmHcwes+HK6UtPqmr12890Apgzsa+SbEoZH++OHILT
+l3L0chW2ou/UG6mSvZ9d1ZWryldxW1W2N4NBV9reG4c7olzNzq83nr0849CjukaVKRvi5zDZ
+16APuasYiXuL5fKiGtVfjU4lYq6ml8nVRMbjz60I65yqJbrXDItUhvv2WMqOG6YjAQPtbQJ7
+Ueh0YEPGC6WgrU1exW/nieWpDeJujwQk1bYn/ZQLQO60ANspcc4RfVy7VtWOjvfaU9m85u9K
+Mh7v5+GgrKoVSxS7/qpAbrEMyVkGH0J1g9CWRYMfMqLzo5DlKKw8r6v1vF6I5fLrqaUzxgV2
+noGJse5nCnN1bzJDJnrRQjzsHJgECFJVJrHIqkU1Dmdx6Buj6wujKxmPv7AmfFB/F90LRkWM
+gw7/lqw3audvQ54b6xARyHZgM1gODEcGxVBktV2BF7aKm7nC2gZuYaXgXFqNIeVmBU7mc0/m
+cwF4vD6W886kPjpl65NGBq/5uzLelUKhqOypsqq2YpFBdWRcHhS16oygaO+i4rw5qulDKsMd
+SNYqVNIrKZn1KF29Xaha14yCjMfvGOM8JMrTx90e/ZqvmPyL6EonwF/2wpaIiABHi76sQgEd
+PXFv7Xl1FqEtRNowwhkAMDzKJdDHUZuf+rkqxkE4oQiQ5L1dGJ5urLhIxVtvhFXWNP56Ia97
+Y/ef3234Ovnm+VX9AQCoo9WJQw1nknwc1EWwdXwhcmgxKpvl5hT6qcJEdNVvrE0i0nQFG0Jq
+mXoKo9PNNYbnhrA+eifS6n4dGF17Fl3pBOKFLREAAEu5CgD48WwWGgMcyCGvmeAdH+GORiwM
+8JNU29D6pLQhv6z1cRm3oE54tVKLHt4UYqQnM9KHGhXM8b7TYLWi0ytiuXzhtw8BAJmH3NGp
+JMyLKZFb/FZ6IZZYYNVDHaoPQMOZ9WMja+SQyAwvR1FMD4QzRr5aHYNGknlSRUlhfWk1t4Er
+4/KEfKFMLpdPGeEbGtALeRFG1x5MV1uIdHlTuFV/LdLxs/eXnoJXXGM8Getm+kUE9tbWpbqo
+bxLfTq/45Ua5cUO3WCQrzmv+De6gZH3qvxEo+d/YzEcVDoeBTypWTxnOzOekiFr5EuVqlkKh
+DLS3SW0WaF3ugigvtRnfyjM9L2yaAzvSEK6Sbqbkb/4xr2s79RHx3sghRtceTFcfKnR6c3Sg
+t53WqHn48yocBbGFSIfm+YyOD+gkqkGuNrWLz/ydt/6v8p6zXwaVrHarFNeLb1fzWtuESrvA
+w5UZyYa6VtJ3O56U8ZTpHDicdGY8J/WsCboOD1e7FXKLTVQvjPag+3txOqsXYH7ezi43VG7h
+yjQc73p58GquyFH0oxPPbbWSqzdTSmYcyV/Qj3P/s8GvD/M3olQVCuhqUsXwlTdWnC19Plzd
+85qbslW3ISyL4iyL4ijVWqf5qrhSAqvWojJVAAYiKrZMD0Fuf4a4mKIukX99iK/Sg2AIUQ6k
++AhVqEkqwx27aSIPsV9vm06iqnTp/Yxq5IoWbCGScy/9GWAYXXsKXSPZ0KmP45AW+5ZBWRZ3
+fF7wvnUJRipIlOl4q3dffX3/g665b88Iv7zVZ+nM8G3LIuYG6P9qI9yo6+ZFrJsXgX61zMJq
+pZvnxD/lAKgcTiNjfXckqqMsmtg/2XfnaHfdUavk2/tNeYiSdO5lc3ZlGBmPJ+P1/PB8qNDB
+lepk5qT7xfcadW1aHRCJWjrThkIAmucI9k7zQt8Wo2tPpGucC+O3DxL0tuE1KTdTSibsf3R1
+TfiURD/j+8dV1rRN33bz+4znVORhC5GurYucNNIHAMCmkfd+mHB2SZhmLoE3hfjtFL8fP0rg
+2JHQZohSGe7wmXxVb6d7DffzVeFWHE767pvRF5f3jXNRv0OcC+Pyqv4LJoe8NzO8uxi799eH
+EuQpxoa7pW1PSNue8G6Euv6JjMdvGep2dddQ1A5q4As//K/p3sjpJa2ds4/qmybGenhTdLX3
+7nGu48cGI2cvN4imb3kJZYQb9fD6GEcHCjJgAW6nweufa+sijcdmSytgjTFhW6qyIvT5QCaX
+O9ipsoU6fa3yxDjPxDj/fW1CgUBAIpHtWHqivn/+U/DzY7Xfa+XeB2e2D1U+HLJCmhDtFT/Q
+v76JJxKLKGRKLzZDOUNBRMV7M8NrmkXG63vMwbGc1oD/pq+aG618Z2XL5d2rB7/fLha2wk+P
+bWujqf3EEsVH3z4wqVrhsFwpr7DTvPf1gGccD1fbpK+GJWfWllZzRVKckz1lQLCzvxcLAHlZ
+FVe1hMZjaRI9KU1ijCfj4LrBOo12zZTkjOpRezP+XNrPOFebuNJ5X6QAAAoEpn9S3Qh+h2zq
+zuSf1w7o46/ZuFhixyTYMZmdjYu1uKpQQH9fz5nzk1axeHqbZOrH1w6tGoSqMhxO2slemMCa
+NW4ZT6qPZxjMiLBINl+q5LXfXv9mpCYt2TQyoKnyE1FpaJF8sO+e3iRHvfLFj5lwIGe9KpDD
+YlLRrGNU7qZXZj9pWDwDjkUTCQSta5gx/AKN4an+rKMbB1nH1dvZtWP2pH831d9418J2Bf6T
+b5PvNUrMmf67Hfl8UeRHSd+fyKipN+ZiLavillVxP/rupt7GDmlN0ritSUd+z21o0f8VauoF
+Xx3PTPjknjmJDWZi1+26xPWXbqaUiCUKsUTXBIAnI4H0zOXCIWuumM9VAMCx/OZj+c0rP09S
+2js6EInkx89mjdiTLhCpqvzN6Q2AVeQ8j4qcuSGsnasGsWlkYzcZkJwnjUO2pb4VbvfRisHG
+8/7/d6No8qFHyNmLxLQA29ejXTw8GLYUGp1GkMlkXJ64pLLlcnrTD5nmWrBzQ1hD+znZs6hU
+Mk4oVtQ1tl7JbD5VoKVUY5xgD7OfrSoz5ERBiyEH+GgPuiMVfv5CRUdnUqQese3M410e7xTs
+QbWlwzc3tnWk5tYfut9kxK8eyYbcbeGb5XK5ZuG7JhaF2A2OcOawiHgCoaVNmFPa/s0tVYum
+ADol2s3G+AfD6Pr86Do/zGH7iii0iZFFUlzW9Mb2NDsaOLUjwVCzH9S3FLnxbjfqHEwwMV9e
+kbXrsijOtsXR1iV25xW3TN6eUiySXVsxwDhXAQBH/nyMcRXj6gvh6itC1/WxnLULB1Egazx+
+BSWtU3YkF4tkS8Pto0O0mgl1lYrqVs0eoj0T3hRiP1eKKxued5ztKQKRvLGhPbWMl8mXmfsW
+GF0xuj4jui6LgrnKsIqrZVXcqTtTioSwL3T5jFDjIVZ4zXYxX+u8JyHKgbQk0atvAMfP06Fr
+LEehgCprGjPz6k5crzDZBxijK0bXZ0LXjXGOaxZG0wmWv7JzFTrj47sFAljnLIviGGpTBoC5
+G9K8KPSjEz+YGTg8xhtpEKfH6YrDSd1dWO4urNeGhqRklm04kvkcsoUx6XZ5iQM5n43zWrc4
+xkqutohn70pC7cM5Y3y1LutDVq6J/NUXIvPDHP76ctjYIT5mNnPE4aSD+ruc+XToohA7rQuY
+dsW067PTroemec2eEG7SfNUrTVzp8i/uoq1Y4lwYffxdja9a4czEzHrksKfgk5Euy2dHarZW
+Uyigssr6h/l1NY1CAEA9V+LAJDpxbMOD2b1dOOjj4tCpn66Obf7stk7ABqMrRtfup+tvCwLH
+Dw8wSTC9Ut8kXrRbq0vwxAEsk7Q3s3HJ85QlA+xWzInSXKY+zK3d9Uu2vpgkvFnz3BDWutnh
+ymQ9AEBFLb+qzZoH2L3YPc516EBVEtLir1MxE924if7y0fXPpf2s2ytVmQY07/PU29VavpYQ
+b9MlOy2tZjUueW4S5UD6YGEsylWpDPefk/fX/F2p92ZV9k9O67H3r11c3jd+oP+Fm3kzD+ca
+yUl4buLmSEf3JWLBPQql2Nr11Vy7YoLJv01eMu16cXnfhGhrVGtlTdvsHffg/Re7NB9Bd6Aw
+IgKBAJi8SVsi2dCE8F4ezixHe7INBS8Qyavq2x88aT6f1fL0nZl2zgtFd6AV46DdR1O2X680
+/TIARn+TtSik4mn2mMToitHVLLp2ctVL/zWjklfc8paGH1hHetkzdIe6CF9oLsHoBOKuiZ6j
+Brm5OTt0XRLPAODDDlCQW3ngrFZRm0Uy0p0WFeaOBmz+uZ5nJleV/zCuvqRcfWnoSsbjz6/q
+j7bhtEjupldO/Oqhke0YNEu6DEFo3q6QSwbYbX57ELKZnf5lGJ0ABoS6fd2nd+Ll/Dk/WdPz
+es0bvuiqtaFFMvOwNW+C0RWj6zOhK51APLcxLDrEGq6evvTEOCWCaGat3m2IkMFriGwb4bxy
+7kCUSMaFhpNPSfTD4/Gzjlpc3BMc4Iocgsu3C7rLYzTGkzFrSO8QX7YTm0ylUhVyhVCiqKpt
+fphX9/k5WHvrFOJvGeoWHgB76RQKxaSD8KZyk3zo8xJ9Q3zt6TQihUJpE0gLq+r+uFqhWeD+
+frxTVLC6JaW3u9rPt3aS37sa7rxdJ4u6rlyWhtuPj3Xz9XSgMEkkiCQTyptaYE94Zl7tLzfK
+9W4LuHucq4877M0SiDqUe88tCrGbPN7f39mWRSe18iWJm27m80VxLozV49W22+V7FQce6DdD
+5gWyJw5R/QmOXylGs8SWRXGGDYC7T3N54rd+gRPgxnjChtuqN3wDvB1ZDJJIJOIJpOk51V/+
+VaJZfbl5iOvoBHcvR3uSDVEkFrW3itMeNew5U9DVHuzRdFVWWl3aOKBvkMXNgcU46OfTme+d
+KUQG9AMimvUE6DQTt80LZJvPVRSvD/Wdm1R9LN+CENEkHzpazSuV4b74uxv6nsa5MHbN8w/s
+o7OTOo5CAXZMTh9/zthRAQCAH37N/OCKunfZ4FB7ZG1CAgezj84KmJwYpPkEOCQSx859UB/3
+cZ3NdJTTSnQfJ0OOfZ2Vzk+XCoHGg5nqz9owq0+In3ZDOYhgx4Qp4evBmDDc/+a98pU/ZCuz
+SlFEhbtFBsL7u/IFUvBjzvF5wZNHBaPrFAqFUtW50gm2I2rWtTdxBcAAXYN9mOidKY9qAULX
+6BBH5XhrmxD8kn9ifsDYIUHKLnYqU45EZTGpbs7MwTFeW/en/5DZEGlL+XJFP83e1wyICuhU
+D1fbhFi3XQfvf5fe/HJ4hgPolOtbo69vjbaCq+0K/IFjaSa5Cm+pKjbLyqXbmOjK+96MAM1f
+qpkCERWzJ/khZ2ZheH9n5BBU17U8fT+3Bf04pz4cNCDUTZOrQmGHUKh+MnYUyI4CrXkzxlAr
+tkPTvGaMCzT0BBKivX5bFIqcWYPNQ1wPbYzX5ao2IKJiRIz7xe2DR3vQda8hcvLtIJ3+W1IZ
+zsheWFbLH4v7ThgBPxC9z4RNI+96NzLGiXZ4XbShPvUcOnX70hilfkZhlm55/hLjRPtp3UDr
+GqMJhR2fHUn6/K5Z9dlFQmm7Aq+tVSzWrvMC2Z15URLdC2ZIhC+HjMebb9CymeoZtrza4CZx
+5sudQh6XJ1Y2H84rbjl/q2j39QZlkHmMJ2PNZH/UZYDDSRe8EXzsbmOXOUIyfRzcm1ssUTzI
+qSmuaCYQiO5O9PAQF7SkMW6w9+QrxWcKeQ8L6jS7H/d2YaLZ2skZ1TKZmjloFse7EewN8yPR
+371YosgsrC4p4QklCnsGLsgHnr/8PFWOPTdn5oHVMW9svtbVkiSTScOHBgIAP2qpDCcQCKRy
+Ip6sh05PKTQabWiCP1BIeVL4zbOyqoormiGI5O3NigzsrfycdBvo9EfDlO79pnbxo6zq8loe
+Ho/3drePCHVVflkqlbBupt+FnQ96tDE8xpPx/ZrYXmxrmkJU1fKWfZNm0VZR/GYhzdT/RSbh
+ohxIhrq9jBvcyzquKtvPT/Bjmt/fQNl+QdXknq+1mLRO8vmiOTtSD6+LvpFWqrOb44VS3oU9
+6WeXyBLjPFWZDEzq4mG90C1IUCFBoKK69c3d9zQ3ax/p/vjw+njl35GGk88a7nmmMBsuk9Co
+lDgxPwCl685fH+lsTaLcMWzDwnCUq/fz69Z+l6G9lx+8SlzQj7P1nQjlMsGJQ927ImzojnTk
+BhVgXQcUQmHHiYsFX/2vTLkUN9732DrAn1YhrakXLNqTBAC4UqF+JnvH1ypbRgEAlFx9Uto2
+ZUeShl+gaGl40a4V8fCbABAa5O5NyUIjf+qpuodgeqD9DxsTrONq8qOKUZtuWcRVAEBtvVlU
+GdmH03VQ+c/R2XQoyAj8nC3Y/wJvoy6g57ebZcmblDSuKGzzDUM7r675MZffAe/s3HkGfHvr
+sUiFwo4FX6ZrclX5M919XM0ZX2SvSouwY6YP2jMg63Fd3Mcp2lxV4YfMhlk77jS1qy4NDPZa
+3N9W645OkUjBys+Tlp4qQOnxLCzhzrcFa/elXKlo1+Qq7Hb6q1yzpxS/A6z+LkWDq/C/79Kb
+k+4Xq8w6ApgaofbG9Sy6vhvBPrAuBk0AMF/aFfgT5/OG7XxgRQZCVb3AnFvj+rJ1hxCxpdC0
+zi0EBbLAHhPz1OsuFt2aDnKWokgo5da3cetVhjdko+evc+t+xe1qPbPkvnsNsN+l85hhY9q7
+3hWDBvZGF5mr/pNlrDNeNe/M36rWxDicdNLogK6tif9JKrbIsWe1pD+qNlRXnFuk/gCPn9R2
+NSjg5/lQvZTzcbHpicbwl6+7vz1VbfaYj9Y24ZfHM8xcrHaV6xm1YxJM73fUN7g3APp/LnKF
+uStPvShtkOoOGRYuT71upNla00jZOOJcGPH+TC8nCoVCQZvi25jytP2Trn+3C7imolmqXBiT
+yRZPLsuiOGijvMzCGh3t3VXW/1U+d3yocsHc14OFDKtx9s5zqoLMyDJYv1XTpP4L1hrwPpTX
+q6luY9PD6ErG408s7DM6PsCKFWBFdevyfff1BtzMlH33Gta1SEz2OrVjEtbHcvROCg0CcaDW
+gAVQKKA7hRZY7znFTVOAypkc6Gqve9laUUYdw0NdTT4HvZJabLAQT/IUpRFBHur80KIi02sW
+sVxeXsPr7AMOWExqpC0ljavmhlSG+83aTDJLUVqun4fwfpbt6tlZWefYFU80vCRUEq4HGcMj
+3Khp2xM6A1aW/V3FOOjirZIBG7Sq4ayTtIdmbfs/aah+JZySZv2cXV7dYFEw5vuUFrRJr6MD
+U8fRb518PdHz3M6ho+O9rOMqAOBh8zNZAWq6wbk8/b9sHfD5akIGuWp5QGRSWXellJhEGV9q
+TkIr2v1YR+RSNUVxOFxP0a57x/eePrGPFd1GG/jCLw4nPf1+EEr5/HTJ0IGeJrMRw4Kd9CrY
+D6/WjItvQgvBLJK0R5Z9Ba5UkldYFxbs1PkVJCvGe134Jsv0ywxjz2tuC6eFIWegpl6Qld9Q
+VNFU1yJu5crKBTK+BBxa0d9oLQTpGdGgXaReGZFIWtwzJBBJPeM0CLQ+VUc3fUgaGaf/wrOH
+Qboemub1v7Q6Q/2Xnx7zwxxWTOujtFssRdbjumUHzNpDxUxJbRb8cj737SmmQ/nvzeufXJLc
+1a0y8/P7J9+PtvTrSKRg/7ky3VFTcvxiIUJXEB7pNclHnQdnKWwh0qzXVNs98aSKH048fP+C
+ns9DILwYK6yJq/5ebsi+0sbhYKe6TYyDbleYpZD1CsFwgMeW0f0uAzNh8M+Ax+P3b45/Fh19
+Zgcxbn4QtX/9ECu4KpYojv7xeOAnKd3IVZVX4/fi7HzT7V04dOrxjTGaW7ah0cvYD+/8fqVI
+MxnIJJLTS7qmxVq0BSOdAHYtibAoeLgsijMtQBXkWJ3AVvqBAABnz2fr5SoAgEknMZ+9F7rr
+7jUnUxsUCpXlFepvOg40yYfuxFF9tdrKBouCNAK51s1Mw35sDw+GntEXS9eVp8pEbZLtG+OP
+zwvWuxunpULG4z8Z6XL/49hDWxKjgpw1E8HMRM6Txhlbry09VYAMdDPe/TqjrtH0MtLRgfLr
+lrhFIXY6cxm/Qzbr6KPI9y4d+PXB3fTKvOKW0oqW3MKmB9mVDXw903xdo2j5D1Z2Qv30eBa6
+gnV3Yd3+OCaAbtaUPy+QvW1x9LdbEvZNgtfh7o7qvLFLDxqRQy1MD7RnMVkspsVzqzkik6sn
+Gg+6LkPuNUqelKo+lXMvm8/GeQGdO7RlzbQA9HeVnGlZR+gC7XCun6f+MHuUAynIz0lrqCfQ
+ld8h23wokyDpmJLol/15/LIog0kCJmV+mMPF5X2LDo1a+1ZkiJ+eKlCTUlMv+OTgvYitd5/e
+q2QEaVzRki+TmrimPx7bFtq5acjOTUN+WxCorENAUSSUrj5XMWJPev8tt4I23Ar/8M69nAY0
+0I+KUNix/lvrN5s8mc89+nsmutlkoLfd+W0xS8PtAQAA/D97XwLV1J39/7K97AuErJAAgRBB
+KYuKomjdAFErrTjSaWvtaOtg25nWbmfsdGqX6bT2P//217H60043W7tYRdtxWq3WrS6IS1HZ
+IeyBJIRAyJ4XkvzONy+8xLCoSNU58+71HDl5j7w88j7fe793+VwIGuG/Nwti330+m0YjskjQ
+o8tTD60PlNeg/6SRwwP+yeKk0Anl4ytud9CmpaUM84x9+F01Nr51bdGkUZ7DTx5UZaYG6iWN
+dtdf9zZfdfhacq4HCfWMlHGcF2aGX4tKJP6jJGNs5JvjIqM5UbvqeoXby19/PFsm5b799IxV
+jT27DzduOnVtjxHNzaydwp8/WZqeLB7bnFU0ngRB0P5DLc/sbbo1Mb0DrZaijUd2vDAdq4wb
+SdEy46XzVTMnK46fa9t+qH3ohrYkg/fAPSq0HSRUXIjvpa1nrr/wcFhZ/692IZ+zLDfZjyUo
+Wsz++zPzHmnoLLus3XlcF5rAULFoJfOEc7LisAmRg7FWZ21rMGW/emlyGNOFgkbeti41rAYd
+HtfvITTeu2ROwv7zWrQobYWKp7V4TnZZNp8zFF5sRuuWaTTiG0/OXHC29fPDzXsaQaaESiQ+
+lQ0ifCvykrAGAJ+P8uk3F8L6cq5Ha9VaDPAQBG1YMz1aULnx+8Dg+XWZESVFaWPYwd0iuKI5
+SbP59MaSDImQOVEZNVEZ9cTDjo4WY0O7tUXn6LM4zObAt8fhEJNi2MoY0Awhl3IlQh5W3j0G
+7dRZDp5uf8lfm3qLOc3KDa7FL5/56Nm0aSnRVx0YQfg8SlFeYlFeSr+5X9vjtNtsA2QSj8bg
+i2hYij9UjSb361vKxoXS4cFPqt43O1cvS0cdFgLBnaoSpqqEa1ekodPK+10ONpk63BcB/3C8
+dsWHtVQi8fFiJ7qeTlBEnNqaV1Ot1xmdPp9PEkWfkAyqFNwDhKZ2gOoA2ik3sDm/pp6q6SsZ
+/FkQAZe+kd9jM7NIMJ1OemVrIKS3/B+VxzYy0atTyL68nNi8nNgPHB6rB6HT6UNsHVx6qPql
+Q2PpK/xgX+v/TgL+Nvr3pNGIa1ekrSqeYrVZmUQK9mesa+4LW/juFLiCtsM643fP/7z5gcTF
+sxV0OknAogtSYzJvqh1qREVbE/Yduc1kQk0O95y/XvhghXHFotTrpNuGIITLofvDNqN9kVUN
+hsc3nx9Hes4nS9VVzT1PP5AW5g7A/m2ggBLuhKNFYFt3V7x2BCyFLq/3rU/K33pqNnqbETRK
+GGWH3UfcubciRsjC4Eq+jk7969dSteWpOh3mgBAI7qEbB5Mbmfvq2W+enDhraqAgERQP0El0
+KPxMi9u3c8/ZZ/ZfVxZ9qO6oM87aXwHWwaWTBl+DqD43NWTZvVCvP3NBf+fCFbVvK3fUqEqb
+/7hQNiszJjGWP4b95yjabXTVNnYdr9C/e9pwa5ze65G137TsLTf8+ZFJI3Uk3pA4HJ7P91eN
+VEZ/M7KtwrSt4sR798Ytnq2IFo8WtNQZHMfPta3/piXUYdlWYbJuOvbsgxlDH8G2TtP/fHl5
+W4Xp81WBZA8o/b2+/Of1y2PvXN7+tGeILwPbB2Np6BOY927FuszWNYUpSfGCoZWqDofnQlXn
+67vUQ7ckNySPft0Eosq9zpX3pIb1mRisjiMn2n73Rf01I1531nzXqXzKw3fLVCqBUsyNEHKo
+vhuGrgvxGYyWDmO/Wt23t9zwqwaQbl5fmCkozlclJ4jHtkiZ+u0nzre/+61mDDmbG9XlSs7C
+yQK5hMNhgvAMBabYPQMuq7tDZz12SfdZ9Wic/QVx7IXpfD6P6XYPmCyOHy/33srvJVfGWDJZ
+GMllEAgEo8l2orJnpGRyOou8bLpAGsWg0+kmi6uuHYQARh/lPDYpyeBlKiOYTGa/FWnt6nuv
+bPwvcSvgGioJdMqiVN4EOU8aReeyqQwq8O8pMIVMIlNhnwshOJ3O/gGf1+YwWV1mK6LutJa1
+OG9yCbwtWhDHXjFTOi1VGCPlX7MPwT1A6NL31Tb1Hf1FO16lV7jgQhzpAK644nqn6c1a1/9C
+YZHID05gqxQg+iKIZLPoZDoMuQaIiBvp63e0G9x1GvPBZvuv1PeMwxWHKw7XG4ArrrjeLsWd
+YdwZxp3h/xhnGIcrDlccrjhccbjicMXhisMVhysO1/9auJJv4FxccBlZpgmoiXxKt80bRtUZ
+ptuWxyYlgaL80kNtWy4YB1/+z5Ytv1Ukx/N2/dC0/RIoRNnzRHIkl/78+1UXzQgOVxyudxZc
+X1somj1FJhzkebLY3D9fMrz0bTtaA/T3onhlDOtvXzag/MDx8VwxH3QUNPsJBrJF9D/dn9is
+6VtfqrnhC9+cpLPI7/wRDB9Y9v+qRmoj2f34BD6P8f2xtv9fNtrKoorliPm0Jv8dsUjk+Bie
+1+sdd6zizjDuDN+sM/zJI4nLcxPYTEplY8/R89oqtZFKoy2eKX05V4o6w3OniJVy9twJgQ4E
+fgTDgYDZdkfbQUnmfZk8pZw9NVU2+H63Ti5ZBzgsWMynZUdThz3pxfmiiQmAa3L7udGKN6lE
+YgSX7kB8KGNwfjydDhN6em24M4w7w3eWM/zwRO60VKHd5X3z4+rSQfrVlSnaVFXEn38MWMvv
+j7RGCRnbzoKGwUViUKbaZQDeMmp7d502Svj0ypbbU5RqMjsZAkaKBD4wpPMikQEvnS3z+Xw7
+/tU8esXLXBmDDhM0g+TyqXLAEa8zOoc/G4crDtfbBdcZycBmXq7rxrAKRp7WmKGaIMvuyyET
+cZLjQSlYd1/QS7xoRlbuvDHah3GU7j5EKmBECcIb8SAIem5ZLIcFn6sy7Ki6BotAuhyQuekG
+aYPQCSxa3Rh5QnC44nD9teAa8AZpwzuTgOqVSZIKGL02BN24xkkAFUOHPthqs0hM9dHgsk5X
+6O5xWQIrQ8EhkyntevNXlbbQQ9Ecxso0NptLcts9BypNQ+flFMSxBzyewx128CZJLIedcKSm
+d+hpgYmbPY70JJ4oIvzzL4xlTb9LbHMiW/YFWmcnc+AFd/F4kXCf2XnskjWUrCNOBLbinYaA
+dZVGAfA3dIa3E61Oi4qXkh2Ir67VhjUbTeXRoniUXrM3rFsLpY8+2m4L6wHC4YrDdexwrWy1
+zs6G0pKEbxbY3zjUOdRpXDVPunCO7Icz2vLSFgiCxGIQjtJ0BQlf/rQ2PYJDu/cvZ1CGrII4
+9lPL4+TBEWHRxX3Ipp31B1otLBL5rd/IstPEGKt90SLKyV80679uwVxrHgV++8nMLn3f/Xpr
+1l0ykr/hsWhh3Ie764Ydhd6tA7Dh88JHHD1+nwImef79kwYM+2KS1q9QpCeLYVKARqM437f/
+cPMrR/UBqlQR+PV2TQBvkTymz+c71RSE36pJvFVLFWiALUAD0mJas11tciOFWfxl+bG/1PeV
+f1A7eBDaMFu8slDR2G458F7l4Gs4XHG43jRct1wwZqbostPEhQui504XlFf0bD3WWd8f7AoW
+SoD3qx2cBIOSAGMTOhQ0cgSH1md2orxKBXHs1x5LoVFpFys6Tlb2ONxQfk70hDhuopAGtVr+
++ZgqTcnV9jj2nNaqdY60OFZujnTOZNFbLmA50cBynoJBIrijIpk0Gm3P97Xd/e45WYJUZdRv
+FsYPC9fKTmAkueygdX3Wz/yUKGNpum2v+934NoQUF82taTJeuKyr0SLTFIyleQn35Cp2nu1T
+2xHsps6pAT4TGXAkm9BvcaGHHkkFHf9/eGCC1+v99nDHsWojj055qECeHM97q1BSsqftdIN5
+WT4kCUEyjwIXzIlFPKRt3w3DZIDDFYfr2OEKBqt+1vyHLMuiWaJYKSd3lnRaRtQHe1s/vhxg
+G0WzO2eaQWyJR4EjuAyr3YVxROckMEkEd19/IE+7vljBpJH2/qjGmJY+rzFPE1DLDa6Nc4Vp
+Sm6Xwb7mnSttCDCkuxv66zWWF1Zn5GQCGgrqvi6X16uUgefeM+B5bvMV1F89U2/+8lV+JG94
+SvGTHQ4H4mMzKehE7FiYWJQXC97BR/nnvlbUYpvcyL2brmAO+cE2a+ZdIqWcrRRS1a0IjwLz
+eUyz1YambWYrOQQCAR1axSKRV9+X5P8laNOntSgXnN9PrtmxcUa8n6LtQKvlNaeHx6GxSGTU
+N3lxiSSKSzp+sWvYuac4XHG43hRcUf69zecMRYnsRwtjY6WckuWK6g4Huhnj8xh2l7dMD7zf
++bFUmOTp6A1uIxP96EK3sqsm8WKETE23LYwVDd12zpgCyJy+PKBBsYqB+fdWJJINuNXu4sDn
+TU6Zn/vm9KUubG/ZhpC8EIy4gu53qFg9A2YrIoqk5ghoR/T2ZwpjeBzwkcoud+xuuCp588QU
+vjyGyWZQ6DBBFEn1+ChXeoFvvCSRSiK4seCZ0j/cEQ0L/z6LG8UFn03dYeUw4NVpQVpzxOXC
+hn12dduVcnZ+PL1UbZnKo82bKrHY3NuHM604XHG4jgNcMZK0C5trdm7I4nPIi7NY5Qdtuf4M
+R7s2EFZRxYI9XrcxaDRiRMBVbteBV9JV4OfG9mFMCotElooiHAiCGW1MYZLH4wOhnUb/OBzU
+q7xYE0TaXBmFRHAbTSMWWhlNdlEkNcmfes3JjLbYgFv+8rdBSvE38mNyc6QsBogSWWxuj8fD
+ZDItFkunGbynKgYsEF2Dg+TEIsA71+nfnCcOcl8lyljPPRwws0Nnz3XorUo5G/xx1JZ1hdEM
+KnHX4ebLNs9VZ+NwxeE6vnD1mzKvzmDicyJZfqr0zFhW6HOJgjM0wyHkg2e9shW8QqUCwJht
+ATMVqiomkURwu5yuofFbFoOs77Vi7Lao3fu3Onhmipw3ehZU3+tKUUBSEXV+Nghi7TyoAXgz
+B+D9yjzRfXlybY/jo9L6T69YXF5vtoj+0QsZhkHueKkELBAdusAqg8aTKvwcV1hIbM/hprK6
+8PUCGaQgb27tnzdVIpdwCuLsU1Nj2rW9rx4bkcobhysO1zHC9aFkdgSbFspEpeJSZH5MNmnt
+2KNs0AdipNECYF3rNUH7KYyAsfKmfivAWKKfpxpTHgU2uZEq64Dd5WWz2Qti6KGzxtfdGx86
++Diaw2AymUbTVYkfqYQ6ehYU/XhTJwrkEnaTxva3E8EsMQRBGalCCIK27lZjieWH5klAcHuw
+KAJdcRraA58qKpKJ3VGPORAnj4pkHWgNhJHBpkDEsHgGsA380SrzmuU+CZ9WUhjnn1ow/Jgi
+HK44XMcO14cncp//XTriRmTRjD0ntXrzwKJJ3Pty5RwWrOm2fegPw0r9+KzrChgifgTL46P8
+rA6mHFkMcpfBjkZ0vi0z5GXLJihEm4udO49qdf3u4hmRRfPif/xZ8/JP2ooa3cwM6YaVEyTf
+Ne1vdM6Tw7/Nj1HK2R0662sHA0hYHE8mEdw9fQEghZq7oVlQTBq7wPlyCRvxkLbuCy/YQGer
+TknhlqotCXRKSUH07MkyCHJja5AwAvb4KIdbAFynCahMGknb40Dv6GC5bsE0QNo8KzPmTavr
+0xN6p8Nzf1bUPQsSXC7nok2X0dMuWQcMJneMmEWHCWevaLCIFA5XHK7jBtfPqvuzLrTfPUW+
+JCd6SU6QIljTbfvLx3XogyiIZPl8votqAN00JonLgo0mGxYrmp7EDq1wKje4vviu/oGlSfOz
+pPOzAvXGvRZfqwGg/fldXR9y6SmKiA1rJm0YvFZti+nFzxtN7sByECu7yvcOTAaIZIdlQcP0
+VLPd4wP729MVnUODsT+e6pSvSCm8W56XLYMpMOBJbdZPTIjsMoKrpLPIbCZF32tF7fkMBVie
+tIOO908ax5c/gGxqcb6y8G554d0BTnOT2bnrh6uGyGi6bZmqCJvTg1Vl4HDF4TqecAUDBL5u
+yT2tXzJZKBbDZDK53+KqbjC9X96HPYiHToIYLxo1IRAIXx+sN4WMC2vUO7440Iy5kRAEvXO2
+90TNL8Uz+QIR0+v1NnXavj5tRGf2mNzI8i21a9M4aSlRDCrR6hgov2LcWXsVumrUZruz+WLj
+VYb0yMkWt5eEZkGHlTbE+9X+BgLF99Wp8DgWmlhuMlTkT+FRqVSN3rrrTG92HO1Sg+lcE/D2
+SUTiFweasZuq0w+E3dHbx8F7nqi23DtFLBSQXAMETaf9izJ9aHwbHesOkjonW6/ZxINTq+HU
+aji12u2kVluWwHp13WR9T//it6+Emtxh5f8GAPzDEMcX80YOAAAAAElFTkSuQmCC
+`)
@@ -0,0 +1,138 @@
+// Package wallet generates Apple Wallet and Google Wallet passes for ticket
+// orders.  Each pass represents an entire order.  Its barcode is the same as
+// the QR code on the receipt (the URL of the order's ticket page, which carries
+// the order token), and its relevant date is the start of the next event at
+// which the order's tickets can be used.
+package wallet
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"scholacantorum.org/orders/api"
+	"scholacantorum.org/orders/config"
+	"scholacantorum.org/orders/model"
+)
+
+// passContent is the content of a wallet pass for an order.  It is common to
+// both Apple and Google passes.
+type passContent struct {
+	order *model.Order
+	// event is the next event at which the order's tickets can be used.
+	// It is nil if there are no such events.
+	event *model.Event
+	// tickets is a summary of the tickets usable at event, e.g.
+	// "2 × General Admission, 1 × Senior".
+	tickets string
+	// details lists all of the unused tickets on the order, one group
+	// per line.
+	details string
+	// barcode is the value encoded in the pass barcode.
+	barcode string
+	// voided is true if the order has no unused tickets.
+	voided bool
+}
+
+// getPassContent returns the content of the wallet pass for an order.
+func getPassContent(order *model.Order) (pc *passContent) {
+	var (
+		groups  []*api.TicketGroup
+		tickets []string
+		details []string
+		cutoff  = time.Now().Add(-3 * time.Hour)
+	)
+	pc = &passContent{order: order, barcode: fmt.Sprintf("%s/ticket/%s", config.Get("ordersURL"), order.Token)}
+	if groups = api.OrderTicketGroups(order); len(groups) == 0 {
+		pc.voided = true
+		return pc
+	}
+	// Find the next event at which any of the tickets can be used.
+	for _, g := range groups {
+		for _, e := range groupEvents(g) {
+			if e.Start.Before(cutoff) {
+				continue
+			}
+			if pc.event == nil || e.Start.Before(pc.event.Start) {
+				pc.event = e
+			}
+		}
+	}
+	// Summarize the tickets.
+	for _, g := range groups {
+		var desc = fmt.Sprintf("%d × %s", g.Count, api.TicketClassName(g.Product))
+
+		if pc.event != nil {
+			for _, e := range groupEvents(g) {
+				if e.ID == pc.event.ID {
+					tickets = append(tickets, desc)
+					break
+				}
+			}
+		}
+		if g.Event != nil {
+			details = append(details, fmt.Sprintf("%s (%s, %s)", desc, g.Event.Name,
+				g.Event.Start.Format("January 2, 2006 at 3:04pm")))
+		} else {
+			details = append(details, fmt.Sprintf("%s (%s)", desc, g.Product.Name))
+		}
+	}
+	pc.tickets = strings.Join(tickets, ", ")
+	pc.details = strings.Join(details, "\n")
+	return pc
+}
+
+// groupEvents returns the events at which the tickets in a group can be used.
+func groupEvents(g *api.TicketGroup) (events []*model.Event) {
+	if g.Event != nil {
+		return []*model.Event{g.Event}
+	}
+	for _, pe := range g.Product.Events {
+		events = append(events, pe.Event)
+	}
+	return events
+}
+
+// parsePrivateKey parses a PEM-encoded private key in either PKCS#1 or PKCS#8
+// format.
+func parsePrivateKey(keyPEM string) (key interface{}, err error) {
+	var block *pem.Block
+
+	if block, _ = pem.Decode([]byte(keyPEM)); block == nil {
+		return nil, errors.New("no PEM data in private key")
+	}
+	if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err = x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+// parseRSAPrivateKey parses a PEM-encoded RSA private key in either PKCS#1 or
+// PKCS#8 format.
+func parseRSAPrivateKey(keyPEM string) (*rsa.PrivateKey, error) {
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if rkey, ok := key.(*rsa.PrivateKey); ok {
+		return rkey, nil
+	}
+	return nil, errors.New("private key is not an RSA key")
+}
+
+// parseCertificate parses a PEM-encoded X.509 certificate.
+func parseCertificate(certPEM string) (*x509.Certificate, error) {
+	var block *pem.Block
+
+	if block, _ = pem.Decode([]byte(certPEM)); block == nil {
+		return nil, errors.New("no PEM data in certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}